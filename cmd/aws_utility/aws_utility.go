@@ -15,13 +15,13 @@ func main() {
 	menuContainer := container.NewVBox()
 	contentContainer := container.NewVBox()
 
-	mainContainer := container.NewVBox(menuContainer, contentContainer)
-
 	renderer, err := render.NewFyneRenderer(myWindow, menuContainer, contentContainer)
 	if err != nil {
 		log.Fatalf("Failed to create renderer: %v", err)
 	}
 
+	mainContainer := container.NewVBox(menuContainer, contentContainer, renderer.RetryStatus())
+
 	renderer.GenerateMenu()
 
 	myWindow.SetContent(mainContainer)
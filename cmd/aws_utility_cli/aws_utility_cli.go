@@ -1,9 +1,18 @@
 package main
 
 import (
+	awsinterface "aws_utility/pkg/awsInterface"
 	"aws_utility/pkg/clicommands"
+	"aws_utility/pkg/emulator"
+	"aws_utility/pkg/fleet"
+	"aws_utility/pkg/inventory"
 	"aws_utility/pkg/logger"
+	"aws_utility/pkg/output"
+	"aws_utility/pkg/payload"
+	"aws_utility/pkg/templates"
 	"os"
+	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/urfave/cli/v2"
@@ -19,27 +28,597 @@ func main() {
 			{
 				Name:    "lambda",
 				Aliases: []string{"l"},
-				Usage:   "Execute a Lambda function",
+				Usage:   "Execute a Lambda function (function or function:alias)",
 				Flags: []cli.Flag{
 					&cli.StringFlag{Name: "cluster", Usage: "Cluster name"},
 					&cli.StringFlag{Name: "service", Usage: "Service name"},
 					&cli.StringFlag{Name: "tag", Usage: "Tag name"},
+					&cli.StringFlag{Name: "payload", Usage: "Inline JSON payload (- reads stdin)"},
+					&cli.PathFlag{Name: "payload-file", Usage: "Read the JSON payload from a file (- reads stdin)", TakesFile: true},
+					&cli.GenericFlag{Name: "set", Usage: "Set a payload field, e.g. --set service.replicas=3 (repeatable)", Value: &payload.Assignments{}},
+					&cli.StringFlag{Name: "template", Aliases: []string{"t"}, Usage: "Payload template name"},
+					&cli.GenericFlag{Name: "var", Usage: "Set a template variable, e.g. --var tag=1.2.3 (repeatable)", Value: &payload.Assignments{}},
+					&cli.StringFlag{Name: "schema", Usage: "Validate the payload against this JSON Schema file or URL"},
+					&cli.BoolFlag{Name: "no-validate", Usage: "Skip JSON Schema validation of the payload"},
+					&cli.StringFlag{Name: "invocation-type", Usage: "RequestResponse, Event or DryRun", Value: "RequestResponse"},
+					&cli.BoolFlag{Name: "tail", Usage: "Print the last 4 KB of the function's execution log"},
+					&cli.StringFlag{Name: "qualifier", Aliases: []string{"q"}, Usage: "Version or alias to invoke"},
+					&cli.BoolFlag{Name: "stream", Usage: "Invoke with response streaming and print chunks as they arrive"},
+					&cli.StringFlag{Name: "region", Usage: "Region of the function (defaults to the one in its ARN)"},
+					&cli.PathFlag{Name: "local", Usage: "Run this locally built bootstrap (or directory containing one) instead of calling AWS", TakesFile: true},
+					&cli.StringFlag{Name: "local-handler", Usage: "Handler name passed to the local runtime as _HANDLER"},
+					&cli.GenericFlag{Name: "local-env", Usage: "Set an environment variable for the local runtime, e.g. --local-env LOG_LEVEL=debug (repeatable)", Value: &payload.Assignments{}},
+					&cli.PathFlag{Name: "local-env-file", Usage: "Read environment variables for the local runtime from a .env file", TakesFile: true},
+					&cli.DurationFlag{Name: "local-timeout", Usage: "Time the local handler may run", Value: emulator.DefaultTimeout},
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Print only the response, as json, yaml, table or raw"},
+					&cli.StringFlag{Name: "query", Usage: "JMESPath expression selecting part of the response, e.g. items[].id (implies --output json)"},
 				},
 				Action: func(c *cli.Context) error {
-					profile := c.String("profile")
 					lambdaName := c.Args().First()
-					cluster := c.String("cluster")
-					service := c.String("service")
-					tag := c.String("tag")
-					return clicommands.ExecuteLambda(profile, lambdaName, cluster, service, tag)
+					templateName := c.String("template")
+					vars, err := templates.ParseVars(*c.Generic("var").(*payload.Assignments))
+					if err != nil {
+						return err
+					}
+					for flagName, varName := range map[string]string{"cluster": "cluster", "service": "service", "tag": "ecr_tag"} {
+						if c.IsSet(flagName) {
+							if templateName == "" {
+								templateName = templates.ECSDeploy.Name
+							}
+							vars[varName] = c.String(flagName)
+						}
+					}
+					opts := clicommands.LambdaOptions{
+						Template: templateName,
+						Vars:     vars,
+						Payload: payload.Options{
+							Inline: c.String("payload"),
+							File:   c.Path("payload-file"),
+							Sets:   *c.Generic("set").(*payload.Assignments),
+						},
+						Schema:         c.String("schema"),
+						SkipValidation: c.Bool("no-validate"),
+						InvocationType: c.String("invocation-type"),
+						TailLogs:       c.Bool("tail"),
+						Qualifier:      c.String("qualifier"),
+						Stream:         c.Bool("stream"),
+						Region:         c.String("region"),
+						Response:       output.ResponseOptions{Format: c.String("output"), Query: c.String("query")},
+					}
+					if c.IsSet("local") {
+						env, err := clicommands.LocalEnvironment(c.Path("local-env-file"), *c.Generic("local-env").(*payload.Assignments))
+						if err != nil {
+							return err
+						}
+						opts.Local = &emulator.Options{
+							Bootstrap: c.Path("local"),
+							Handler:   c.String("local-handler"),
+							Env:       env,
+							Timeout:   c.Duration("local-timeout"),
+						}
+					}
+					return clicommands.ExecuteLambda(session(c), lambdaName, opts)
+				},
+			},
+			{
+				Name:      "describe",
+				Usage:     "Show the configuration of a Lambda function",
+				ArgsUsage: "<function[:qualifier]>",
+				Flags: []cli.Flag{
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+				},
+				Action: func(c *cli.Context) error {
+					return clicommands.DescribeLambda(session(c), c.Args().First(), c.String("output"))
+				},
+			},
+			{
+				Name:      "logs",
+				Usage:     "Print or follow a Lambda function's CloudWatch logs",
+				ArgsUsage: "[function]",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "follow", Aliases: []string{"f"}, Usage: "Keep printing new events as they arrive"},
+					&cli.StringFlag{Name: "since", Usage: "Start at a duration ago (15m) or a time (RFC 3339 or YYYY-MM-DD HH:MM); defaults to 10m, or now with --follow"},
+					&cli.StringFlag{Name: "until", Usage: "Stop at a duration ago or a time"},
+					&cli.StringFlag{Name: "filter", Usage: "CloudWatch Logs filter pattern"},
+					&cli.StringFlag{Name: "request-id", Usage: "Only show events of this invocation"},
+					&cli.StringFlag{Name: "invocation", Usage: "Show events of this history entry, or last for the latest invocation"},
+					&cli.StringFlag{Name: "log-group", Usage: "Read this log group instead of the function's"},
+					&cli.StringFlag{Name: "region", Usage: "Region of the function (defaults to the one in its ARN)"},
+					&cli.DurationFlag{Name: "interval", Usage: "Polling interval with --follow when live tail is unavailable", Value: 2 * time.Second},
+					&cli.IntFlag{Name: "limit", Usage: "Maximum number of events to print without --follow"},
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text or json (one event per line)", Value: "text"},
+				},
+				Action: func(c *cli.Context) error {
+					return clicommands.ShowLogs(session(c), c.Args().First(), clicommands.LogsOptions{
+						Region:        c.String("region"),
+						LogGroup:      c.String("log-group"),
+						Since:         c.String("since"),
+						Until:         c.String("until"),
+						FilterPattern: c.String("filter"),
+						RequestID:     c.String("request-id"),
+						Invocation:    c.String("invocation"),
+						Follow:        c.Bool("follow"),
+						Interval:      c.Duration("interval"),
+						Limit:         c.Int("limit"),
+						Format:        c.String("output"),
+					})
+				},
+			},
+			{
+				Name:      "list_versions",
+				Usage:     "List versions and aliases of a Lambda function",
+				ArgsUsage: "<function>",
+				Action: func(c *cli.Context) error {
+					return clicommands.ListVersions(session(c), c.Args().First())
+				},
+			},
+			{
+				Name:  "policy",
+				Usage: "Show the retry, timeout and rate limit policy (set in $" + awsinterface.PolicyFileEnvVariable + " or policy.yaml in the config directory)",
+				Action: func(c *cli.Context) error {
+					return clicommands.ShowPolicy()
+				},
+			},
+			{
+				Name:  "list_templates",
+				Usage: "List payload templates",
+				Action: func(c *cli.Context) error {
+					return clicommands.ListTemplates()
 				},
 			},
 			{
 				Name:  "list_lambdas",
 				Usage: "List available Lambda functions",
+				Flags: []cli.Flag{
+					&cli.GenericFlag{
+						Name:  "filter",
+						Usage: "Filter functions (repeatable): name=<glob>, regex=<regexp>, runtime=<runtime>, tag=<key>[=<value>], modified-after=<date>, modified-before=<date>",
+						Value: &payload.Assignments{},
+					},
+					&cli.BoolFlag{Name: "all-regions", Usage: "Search every enabled region (or those in $" + awsinterface.RegionsEnvVariable + ")"},
+					&cli.StringSliceFlag{Name: "region", Usage: "Search this region (repeatable)"},
+				},
+				Action: func(c *cli.Context) error {
+					return clicommands.ListLambdas(session(c), *c.Generic("filter").(*payload.Assignments), c.Bool("all-regions"), c.StringSlice("region"))
+				},
+			},
+			{
+				Name:      "deploy",
+				Usage:     "Upload new code to a Lambda function and optionally publish it",
+				ArgsUsage: "<function>",
+				Flags: []cli.Flag{
+					&cli.PathFlag{Name: "zip", Usage: "Zip file to upload", TakesFile: true},
+					&cli.StringFlag{Name: "image", Usage: "Container image URI to deploy"},
+					&cli.BoolFlag{Name: "publish", Usage: "Publish a version once the update succeeds"},
+					&cli.StringFlag{Name: "alias", Usage: "Move this alias to the published version (implies --publish)"},
+					&cli.StringFlag{Name: "description", Usage: "Description of the published version"},
+					&cli.DurationFlag{Name: "timeout", Usage: "How long to wait for the update to finish", Value: 5 * time.Minute},
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+				},
+				Action: func(c *cli.Context) error {
+					return clicommands.DeployLambda(session(c), c.Args().First(), awsinterface.DeployOptions{
+						ZipFile:     c.Path("zip"),
+						ImageURI:    c.String("image"),
+						Publish:     c.Bool("publish"),
+						Alias:       c.String("alias"),
+						Description: c.String("description"),
+						Timeout:     c.Duration("timeout"),
+					}, c.String("output"))
+				},
+			},
+			{
+				Name:  "canary",
+				Usage: "Shift an alias to a new version in weighted steps with automatic rollback",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "start",
+						Usage:     "Start a canary release",
+						ArgsUsage: "<function>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "alias", Usage: "Alias to shift", Required: true},
+							&cli.StringFlag{Name: "to", Usage: "Version to shift traffic to", Required: true},
+							&cli.StringFlag{Name: "from", Usage: "Version to shift traffic from (defaults to the alias's current version)"},
+							&cli.StringFlag{Name: "steps", Usage: "Percentages of traffic for each step", Value: "10,25,50,100"},
+							&cli.DurationFlag{Name: "interval", Usage: "Time to wait between steps", Value: time.Minute},
+							&cli.IntFlag{Name: "checks", Usage: "Health-check invocations of the new version after each step", Value: 3},
+							&cli.Float64Flag{Name: "max-error-rate", Usage: "Roll back when more than this fraction of health checks fail", Value: 0},
+							&cli.StringFlag{Name: "payload", Usage: "Inline JSON health-check payload (- reads stdin)"},
+							&cli.PathFlag{Name: "payload-file", Usage: "Read the health-check payload from a file (- reads stdin)", TakesFile: true},
+							&cli.GenericFlag{Name: "set", Usage: "Set a payload field, e.g. --set check=deep (repeatable)", Value: &payload.Assignments{}},
+							&cli.PathFlag{Name: "state", Usage: "State file (defaults to one per function and alias in the config directory)", TakesFile: true},
+							&cli.BoolFlag{Name: "plain", Usage: "Print progress lines instead of the live view"},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.StartCanary(session(c), c.Args().First(), clicommands.CanaryOptions{
+								Alias:        c.String("alias"),
+								FromVersion:  c.String("from"),
+								ToVersion:    c.String("to"),
+								Steps:        c.String("steps"),
+								Interval:     c.Duration("interval"),
+								Checks:       c.Int("checks"),
+								MaxErrorRate: c.Float64("max-error-rate"),
+								Payload: payload.Options{
+									Inline: c.String("payload"),
+									File:   c.Path("payload-file"),
+									Sets:   *c.Generic("set").(*payload.Assignments),
+								},
+								StatePath: c.Path("state"),
+								Plain:     c.Bool("plain"),
+							})
+						},
+					},
+					{
+						Name:      "resume",
+						Usage:     "Continue an interrupted canary release",
+						ArgsUsage: "[function]",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "alias", Usage: "Alias being shifted"},
+							&cli.PathFlag{Name: "state", Usage: "State file of the release", TakesFile: true},
+							&cli.BoolFlag{Name: "plain", Usage: "Print progress lines instead of the live view"},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.ResumeCanary(session(c), c.Args().First(), clicommands.CanaryOptions{
+								Alias:     c.String("alias"),
+								StatePath: c.Path("state"),
+								Plain:     c.Bool("plain"),
+							})
+						},
+					},
+				},
+			},
+			{
+				Name:  "inventory",
+				Usage: "Audit Lambda functions across accounts and regions",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "export",
+						Usage: "Write every function's metadata and tags as CSV, JSON, NDJSON or Markdown",
+						Flags: []cli.Flag{
+							&cli.StringSliceFlag{Name: "account", Aliases: []string{"a"}, Usage: "Account ID to export (repeatable)"},
+							&cli.StringFlag{Name: "account-regex", Usage: "Export every account whose name matches this regular expression"},
+							&cli.BoolFlag{Name: "all-accounts", Usage: "Export every account the SSO session can access"},
+							&cli.StringFlag{Name: "role", Aliases: []string{"r"}, Usage: "Role to assume in each account"},
+							&cli.StringSliceFlag{Name: "region", Usage: "Region to search (repeatable)"},
+							&cli.BoolFlag{Name: "all-regions", Usage: "Search every enabled region (or those in $" + awsinterface.RegionsEnvVariable + ")"},
+							&cli.GenericFlag{Name: "filter", Usage: "Filter functions as in list_lambdas (repeatable)", Value: &payload.Assignments{}},
+							&cli.StringFlag{Name: "format", Aliases: []string{"f"}, Usage: "csv, json, ndjson or markdown", Value: "csv"},
+							&cli.StringSliceFlag{Name: "column", Aliases: []string{"c"}, Usage: "Columns to include, in order (repeatable or comma-separated): " + strings.Join(inventory.ColumnNames(), ", ") + " or tag:<key>"},
+							&cli.StringFlag{Name: "sort", Usage: "Column to sort by, - for descending; accounts are written one after another, each sorted on its own", Value: "name"},
+							&cli.PathFlag{Name: "output-file", Usage: "Write to this file instead of stdout", TakesFile: true},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.ExportInventory(session(c), clicommands.InventoryOptions{
+								Selector: fleet.Selector{
+									AccountIDs: c.StringSlice("account"),
+									NameRegex:  c.String("account-regex"),
+									All:        c.Bool("all-accounts"),
+								},
+								RoleName:   c.String("role"),
+								Regions:    c.StringSlice("region"),
+								AllRegions: c.Bool("all-regions"),
+								Filters:    *c.Generic("filter").(*payload.Assignments),
+								Format:     c.String("format"),
+								Columns:    c.StringSlice("column"),
+								SortBy:     c.String("sort"),
+								OutputFile: c.Path("output-file"),
+							})
+						},
+					},
+				},
+			},
+			{
+				Name:      "diff",
+				Usage:     "Compare the configuration of two functions; exits 1 if they differ and 2 on errors",
+				ArgsUsage: "<[account/role/]function[:qualifier]> <[account/role/]function[:qualifier]>",
+				Flags: []cli.Flag{
+					&cli.BoolFlag{Name: "values", Usage: "Compare environment variable values, not just keys"},
+					&cli.BoolFlag{Name: "show-secrets", Usage: "Print values of secret-looking variables instead of hashes"},
+					&cli.StringSliceFlag{Name: "ignore", Usage: "Skip keys with this prefix, e.g. --ignore tag. (repeatable)"},
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+				},
+				Action: func(c *cli.Context) error {
+					if c.NArg() != 2 {
+						return cli.Exit("diff needs exactly two functions", 2)
+					}
+					differ, err := clicommands.DiffFunctions(session(c), c.Args().Get(0), c.Args().Get(1), clicommands.DiffOptions{
+						Values:      c.Bool("values"),
+						ShowSecrets: c.Bool("show-secrets"),
+						Ignore:      c.StringSlice("ignore"),
+						Format:      c.String("output"),
+					})
+					if err != nil {
+						return cli.Exit(err.Error(), 2)
+					}
+					if differ {
+						return cli.Exit("", 1)
+					}
+					return nil
+				},
+			},
+			{
+				Name:  "event_sources",
+				Usage: "Inspect, pause and resume a function's event source mappings",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "list",
+						Usage:     "List mappings with their state, batch size and last processing result",
+						ArgsUsage: "<function>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.ListEventSources(session(c), c.Args().First(), c.String("output"))
+						},
+					},
+					{
+						Name:      "enable",
+						Usage:     "Resume polling the event source",
+						ArgsUsage: "<uuid>",
+						Action: func(c *cli.Context) error {
+							return clicommands.SetEventSourceEnabled(session(c), c.Args().First(), true)
+						},
+					},
+					{
+						Name:      "disable",
+						Usage:     "Stop polling the event source",
+						ArgsUsage: "<uuid>",
+						Action: func(c *cli.Context) error {
+							return clicommands.SetEventSourceEnabled(session(c), c.Args().First(), false)
+						},
+					},
+				},
+			},
+			{
+				Name:  "url",
+				Usage: "Inspect and call a function's URL",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "config",
+						Usage:     "Show the URL, auth type and invoke mode",
+						ArgsUsage: "<function[:qualifier]>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.ShowFunctionURL(session(c), c.Args().First(), c.String("output"))
+						},
+					},
+					{
+						Name:      "invoke",
+						Usage:     "Send an HTTP request to the function URL, signed for AWS_IAM auth",
+						ArgsUsage: "<function[:qualifier]>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "method", Aliases: []string{"X"}, Usage: "HTTP method", Value: "GET"},
+							&cli.StringFlag{Name: "path", Usage: "Path and query string to request", Value: "/"},
+							&cli.StringSliceFlag{Name: "header", Aliases: []string{"H"}, Usage: "Request header, e.g. -H 'Content-Type: application/json' (repeatable)"},
+							&cli.StringFlag{Name: "data", Aliases: []string{"d"}, Usage: "Request body"},
+							&cli.PathFlag{Name: "data-file", Usage: "Read the request body from a file (- reads stdin)", TakesFile: true},
+							&cli.StringFlag{Name: "endpoint", Usage: "Call this base URL instead of the function's, e.g. a local emulator"},
+							&cli.BoolFlag{Name: "unsigned", Usage: "Do not sign the request"},
+							&cli.BoolFlag{Name: "include", Aliases: []string{"i"}, Usage: "Print the response headers"},
+							&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.InvokeFunctionURL(session(c), c.Args().First(), clicommands.URLOptions{
+								Method:   c.String("method"),
+								Path:     c.String("path"),
+								Headers:  c.StringSlice("header"),
+								Data:     c.String("data"),
+								DataFile: c.Path("data-file"),
+								Endpoint: c.String("endpoint"),
+								Unsigned: c.Bool("unsigned"),
+								Include:  c.Bool("include"),
+								Format:   c.String("output"),
+							})
+						},
+					},
+				},
+			},
+			{
+				Name:  "concurrency",
+				Usage: "View and change reserved and provisioned concurrency",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "show",
+						Usage:     "Show reserved and provisioned concurrency and the account's unreserved pool",
+						ArgsUsage: "<function>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.ShowConcurrency(session(c), c.Args().First(), c.String("output"))
+						},
+					},
+					{
+						Name:      "reserve",
+						Usage:     "Reserve concurrent executions for a function (0 throttles it, none removes the reservation)",
+						ArgsUsage: "<function> <executions|none>",
+						Action: func(c *cli.Context) error {
+							return clicommands.ReserveConcurrency(session(c), c.Args().First(), c.Args().Get(1))
+						},
+					},
+					{
+						Name:      "provision",
+						Usage:     "Pre-warm executions for a version or alias (none removes the configuration)",
+						ArgsUsage: "<function[:qualifier]> <executions|none>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "qualifier", Aliases: []string{"q"}, Usage: "Version or alias to provision"},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.ProvisionConcurrency(session(c), c.Args().First(), c.String("qualifier"), c.Args().Get(1))
+						},
+					},
+				},
+			},
+			{
+				Name:  "env",
+				Usage: "View and edit a Lambda function's environment variables",
+				Subcommands: []*cli.Command{
+					{
+						Name:      "show",
+						Usage:     "Print the environment variables, masking secret-looking values",
+						ArgsUsage: "<function>",
+						Flags: []cli.Flag{
+							&cli.BoolFlag{Name: "show-secrets", Usage: "Print values of secret-looking keys"},
+							&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.ShowEnvironment(session(c), c.Args().First(), c.String("output"), c.Bool("show-secrets"))
+						},
+					},
+					{
+						Name:      "set",
+						Usage:     "Set one or more variables",
+						ArgsUsage: "<function> KEY=VALUE...",
+						Flags:     envFlags(),
+						Action: func(c *cli.Context) error {
+							return clicommands.SetEnvironment(session(c), c.Args().First(), c.Args().Tail(), envOptions(c))
+						},
+					},
+					{
+						Name:      "unset",
+						Usage:     "Remove one or more variables",
+						ArgsUsage: "<function> KEY...",
+						Flags:     envFlags(),
+						Action: func(c *cli.Context) error {
+							return clicommands.UnsetEnvironment(session(c), c.Args().First(), c.Args().Tail(), envOptions(c))
+						},
+					},
+					{
+						Name:      "import",
+						Usage:     "Merge the variables of a dotenv file",
+						ArgsUsage: "<function> <file>",
+						Flags: append(envFlags(),
+							&cli.BoolFlag{Name: "replace", Usage: "Remove variables that are not in the file"},
+						),
+						Action: func(c *cli.Context) error {
+							return clicommands.ImportEnvironment(session(c), c.Args().First(), c.Args().Get(1), envOptions(c))
+						},
+					},
+				},
+			},
+			{
+				Name:      "batch",
+				Usage:     "Invoke a list of (function, payload) jobs from a JSON, NDJSON or CSV file",
+				ArgsUsage: "<jobs-file>",
+				Flags: []cli.Flag{
+					&cli.IntFlag{Name: "concurrency", Aliases: []string{"c"}, Usage: "Maximum number of jobs in flight", Value: 4},
+					&cli.IntFlag{Name: "retries", Usage: "Retries for a throttled invocation", Value: 3},
+					&cli.StringFlag{Name: "on-error", Usage: "continue or fail-fast (stop starting new jobs after the first failure)", Value: "continue"},
+					&cli.StringFlag{Name: "invocation-type", Usage: "RequestResponse, Event or DryRun", Value: "RequestResponse"},
+					&cli.StringFlag{Name: "qualifier", Aliases: []string{"q"}, Usage: "Version or alias for jobs that do not name one"},
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+				},
+				Action: func(c *cli.Context) error {
+					return clicommands.RunBatch(session(c), c.Args().First(), clicommands.BatchOptions{
+						Concurrency:    c.Int("concurrency"),
+						MaxRetries:     c.Int("retries"),
+						Policy:         c.String("on-error"),
+						InvocationType: c.String("invocation-type"),
+						Qualifier:      c.String("qualifier"),
+						Format:         c.String("output"),
+					})
+				},
+			},
+			{
+				Name:      "fleet",
+				Usage:     "Invoke a Lambda function in several accounts at once (--profile is the SSO start URL)",
+				ArgsUsage: "<function[:qualifier]>",
+				Flags: []cli.Flag{
+					&cli.StringSliceFlag{Name: "account", Aliases: []string{"a"}, Usage: "Account ID to invoke in (repeatable)"},
+					&cli.StringFlag{Name: "account-regex", Usage: "Invoke in every account whose name matches this regular expression"},
+					&cli.BoolFlag{Name: "all-accounts", Usage: "Invoke in every account the SSO session can access"},
+					&cli.StringFlag{Name: "role", Aliases: []string{"r"}, Usage: "Role to assume in each account", Required: true},
+					&cli.IntFlag{Name: "concurrency", Aliases: []string{"c"}, Usage: "Maximum number of accounts in flight", Value: 4},
+					&cli.StringFlag{Name: "payload", Usage: "Inline JSON payload (- reads stdin)"},
+					&cli.PathFlag{Name: "payload-file", Usage: "Read the JSON payload from a file (- reads stdin)", TakesFile: true},
+					&cli.GenericFlag{Name: "set", Usage: "Set a payload field, e.g. --set service.replicas=3 (repeatable)", Value: &payload.Assignments{}},
+					&cli.StringFlag{Name: "invocation-type", Usage: "RequestResponse, Event or DryRun", Value: "RequestResponse"},
+					&cli.StringFlag{Name: "qualifier", Aliases: []string{"q"}, Usage: "Version or alias to invoke"},
+					&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+				},
 				Action: func(c *cli.Context) error {
 					profile := c.String("profile")
-					return clicommands.ListLambdas(profile)
+					return clicommands.RunFleet(profile, c.Args().First(), clicommands.FleetOptions{
+						Selector: fleet.Selector{
+							AccountIDs: c.StringSlice("account"),
+							NameRegex:  c.String("account-regex"),
+							All:        c.Bool("all-accounts"),
+						},
+						RoleName:    c.String("role"),
+						Concurrency: c.Int("concurrency"),
+						Payload: payload.Options{
+							Inline: c.String("payload"),
+							File:   c.Path("payload-file"),
+							Sets:   *c.Generic("set").(*payload.Assignments),
+						},
+						InvocationType: c.String("invocation-type"),
+						Qualifier:      c.String("qualifier"),
+						Format:         c.String("output"),
+					})
+				},
+			},
+			{
+				Name:  "history",
+				Usage: "Browse and replay recorded invocations",
+				Subcommands: []*cli.Command{
+					{
+						Name:  "list",
+						Usage: "List recorded invocations, newest first",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "function", Aliases: []string{"f"}, Usage: "Only show invocations of this function"},
+							&cli.IntFlag{Name: "limit", Aliases: []string{"n"}, Usage: "Show at most this many entries (0 for all)", Value: 20},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.ListHistory(c.String("function"), c.Int("limit"))
+						},
+					},
+					{
+						Name:      "show",
+						Usage:     "Show a recorded invocation with its payload and response",
+						ArgsUsage: "<id>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Output format: text, json or yaml", Value: "text"},
+						},
+						Action: func(c *cli.Context) error {
+							return clicommands.ShowHistory(c.Args().First(), c.String("output"))
+						},
+					},
+					{
+						Name:      "replay",
+						Usage:     "Invoke a recorded payload again, optionally edited",
+						ArgsUsage: "<id>",
+						Flags: []cli.Flag{
+							&cli.StringFlag{Name: "payload", Usage: "Inline JSON merged over the recorded payload (- reads stdin)"},
+							&cli.PathFlag{Name: "payload-file", Usage: "JSON file merged over the recorded payload (- reads stdin)", TakesFile: true},
+							&cli.GenericFlag{Name: "set", Usage: "Set a payload field, e.g. --set service.replicas=3 (repeatable)", Value: &payload.Assignments{}},
+							&cli.StringFlag{Name: "schema", Usage: "Validate the payload against this JSON Schema file or URL"},
+							&cli.BoolFlag{Name: "no-validate", Usage: "Skip JSON Schema validation of the payload"},
+							&cli.StringFlag{Name: "invocation-type", Usage: "RequestResponse, Event or DryRun (defaults to the recorded type)"},
+							&cli.BoolFlag{Name: "tail", Usage: "Print the last 4 KB of the function's execution log"},
+							&cli.StringFlag{Name: "qualifier", Aliases: []string{"q"}, Usage: "Version or alias to invoke (defaults to the recorded one)"},
+							&cli.BoolFlag{Name: "stream", Usage: "Invoke with response streaming and print chunks as they arrive"},
+							&cli.StringFlag{Name: "output", Aliases: []string{"o"}, Usage: "Print only the response, as json, yaml, table or raw"},
+							&cli.StringFlag{Name: "query", Usage: "JMESPath expression selecting part of the response, e.g. items[].id (implies --output json)"},
+						},
+						Action: func(c *cli.Context) error {
+							opts := clicommands.LambdaOptions{
+								Payload: payload.Options{
+									Inline: c.String("payload"),
+									File:   c.Path("payload-file"),
+									Sets:   *c.Generic("set").(*payload.Assignments),
+								},
+								Schema:         c.String("schema"),
+								SkipValidation: c.Bool("no-validate"),
+								InvocationType: c.String("invocation-type"),
+								TailLogs:       c.Bool("tail"),
+								Qualifier:      c.String("qualifier"),
+								Stream:         c.Bool("stream"),
+								Response:       output.ResponseOptions{Format: c.String("output"), Query: c.String("query")},
+							}
+							return clicommands.ReplayHistory(session(c), c.Args().First(), opts)
+						},
+					},
 				},
 			},
 		},
@@ -49,6 +628,18 @@ func main() {
 				Aliases: []string{"p"},
 				Usage:   "AWS SSO profile name",
 			},
+			&cli.StringFlag{Name: "account", Usage: "Account ID to assume the role in", EnvVars: []string{clicommands.AccountEnvVariable}},
+			&cli.StringFlag{Name: "role", Usage: "Role to assume after logging in", EnvVars: []string{clicommands.RoleEnvVariable}},
+			&cli.IntFlag{Name: "max-attempts", Usage: "Attempts per AWS call, including the first, overriding the policy"},
+			&cli.Float64Flag{Name: "rate-limit", Usage: "AWS calls per second, overriding the policy; 0 turns the limit off"},
+		},
+		Before: func(c *cli.Context) error {
+			var rateLimit *float64
+			if c.IsSet("rate-limit") {
+				value := c.Float64("rate-limit")
+				rateLimit = &value
+			}
+			return clicommands.ApplyPolicy(c.Int("max-attempts"), rateLimit)
 		},
 		Action: func(c *cli.Context) error {
 			if c.NArg() == 0 {
@@ -65,9 +656,43 @@ func main() {
 	}
 }
 
+// session reads the global login flags, which a subcommand may shadow with
+// flags of its own.
+func session(c *cli.Context) clicommands.Session {
+	root := c
+	for _, ctx := range c.Lineage() {
+		// The outermost context only carries the Go context.
+		if ctx.App != nil {
+			root = ctx
+		}
+	}
+	return clicommands.Session{
+		StartURL:  root.String("profile"),
+		AccountID: root.String("account"),
+		RoleName:  root.String("role"),
+	}
+}
+
 func runCharmInterface() error {
 	initialModel := clicommands.InitialModel()
 	p := tea.NewProgram(initialModel)
 	_, err := p.Run()
 	return err
 }
+
+func envFlags() []cli.Flag {
+	return []cli.Flag{
+		&cli.BoolFlag{Name: "yes", Aliases: []string{"y"}, Usage: "Apply the changes without asking"},
+		&cli.BoolFlag{Name: "dry-run", Usage: "Only print the changes"},
+		&cli.BoolFlag{Name: "show-secrets", Usage: "Print values of secret-looking keys in the preview"},
+	}
+}
+
+func envOptions(c *cli.Context) clicommands.EnvOptions {
+	return clicommands.EnvOptions{
+		ShowSecrets: c.Bool("show-secrets"),
+		Yes:         c.Bool("yes"),
+		DryRun:      c.Bool("dry-run"),
+		Replace:     c.Bool("replace"),
+	}
+}
@@ -3,13 +3,19 @@ package awsInterface
 import (
 	"aws_utility/pkg/logger"
 	"context"
+	"encoding/base64"
 	"fmt"
 	"github.com/aws/aws-sdk-go-v2/credentials"
+	"math"
+	"sort"
+	"strings"
 	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
 	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
 	"github.com/aws/aws-sdk-go-v2/service/sso"
 	"github.com/aws/aws-sdk-go-v2/service/ssooidc"
 )
@@ -24,6 +30,16 @@ type AWSInterface struct {
 	ssoStartURL   string
 	clientID      string
 	clientSecret  string
+	accountID     string
+	roleName      string
+}
+
+// Identity describes where invocations made through an AWSInterface run.
+type Identity struct {
+	StartURL  string `json:"start_url,omitempty" yaml:"start_url,omitempty"`
+	AccountID string `json:"account_id,omitempty" yaml:"account_id,omitempty"`
+	RoleName  string `json:"role_name,omitempty" yaml:"role_name,omitempty"`
+	Region    string `json:"region,omitempty" yaml:"region,omitempty"`
 }
 
 type Account struct {
@@ -36,6 +52,129 @@ type Role struct {
 	AccountID string
 }
 
+type LambdaFunction struct {
+	Name         string `json:"name" yaml:"name"`
+	ARN          string `json:"arn" yaml:"arn"`
+	Runtime      string `json:"runtime,omitempty" yaml:"runtime,omitempty"`
+	Architecture string `json:"architecture" yaml:"architecture"`
+	MemorySize   int32  `json:"memory_size" yaml:"memory_size"`
+	Timeout      int32  `json:"timeout" yaml:"timeout"`
+	LastModified string `json:"last_modified" yaml:"last_modified"`
+	Description  string `json:"description,omitempty" yaml:"description,omitempty"`
+	PackageType  string `json:"package_type" yaml:"package_type"`
+	Handler      string `json:"handler,omitempty" yaml:"handler,omitempty"`
+	Region       string `json:"region" yaml:"region"`
+}
+
+// Summary is a one-line description of the function's configuration.
+func (f LambdaFunction) Summary() string {
+	runtime := f.Runtime
+	if runtime == "" {
+		runtime = f.PackageType
+	}
+	parts := []string{runtime, f.Architecture, fmt.Sprintf("%d MB", f.MemorySize), fmt.Sprintf("%ds", f.Timeout)}
+	if f.Region != "" {
+		parts = append([]string{f.Region}, parts...)
+	}
+	if f.LastModified != "" {
+		parts = append(parts, "modified "+f.LastModified)
+	}
+	summary := strings.Join(parts, " · ")
+	if f.Description != "" {
+		summary += " — " + f.Description
+	}
+	return summary
+}
+
+type InvokeOptions struct {
+	InvocationType types.InvocationType
+	TailLogs       bool
+	Qualifier      string
+	// SingleAttempt turns off the policy's retries for callers that retry
+	// on their own, such as batch runs.
+	SingleAttempt bool
+}
+
+type FunctionVersion struct {
+	Version      string
+	Description  string
+	LastModified string
+}
+
+type Alias struct {
+	Name                     string
+	FunctionVersion          string
+	Description              string
+	AdditionalVersionWeights map[string]float64
+}
+
+// Routing describes where the alias sends traffic, e.g. "5" or
+// "5 (90%), 6 (10%)" when a weighted routing config is set.
+func (a Alias) Routing() string {
+	if len(a.AdditionalVersionWeights) == 0 {
+		return a.FunctionVersion
+	}
+
+	versions := make([]string, 0, len(a.AdditionalVersionWeights))
+	for version := range a.AdditionalVersionWeights {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+
+	primary := 1.0
+	parts := make([]string, 0, len(versions)+1)
+	for _, version := range versions {
+		weight := a.AdditionalVersionWeights[version]
+		primary -= weight
+		parts = append(parts, fmt.Sprintf("%s (%g%%)", version, percent(weight)))
+	}
+	parts = append([]string{fmt.Sprintf("%s (%g%%)", a.FunctionVersion, percent(primary))}, parts...)
+	return strings.Join(parts, ", ")
+}
+
+func percent(weight float64) float64 {
+	return math.Round(weight*10000) / 100
+}
+
+type Qualifier struct {
+	Name        string
+	Description string
+}
+
+// SplitQualifier splits "function:qualifier" (or a qualified function ARN)
+// into the function name and the version or alias.
+func SplitQualifier(name string) (string, string) {
+	if strings.HasPrefix(name, "arn:") {
+		parts := strings.Split(name, ":")
+		if len(parts) == 8 {
+			return strings.Join(parts[:7], ":"), parts[7]
+		}
+		return name, ""
+	}
+	if strings.Count(name, ":") == 1 {
+		function, qualifier, _ := strings.Cut(name, ":")
+		return function, qualifier
+	}
+	return name, ""
+}
+
+type InvokeResult struct {
+	InvocationType  types.InvocationType
+	StatusCode      int32
+	FunctionError   string
+	ExecutedVersion string
+	LogResult       string
+	Payload         []byte
+	// RequestID identifies the invocation in the function's logs.
+	RequestID string
+}
+
+// Failed reports whether the function itself raised an error; the Invoke
+// call still succeeds in that case.
+func (r *InvokeResult) Failed() bool {
+	return r.FunctionError != ""
+}
+
 type AuthenticationInfo struct {
 	DeviceCode              string
 	UserCode                string
@@ -46,9 +185,7 @@ type AuthenticationInfo struct {
 }
 
 func NewAWSInterface(ssoStartURL string) (*AWSInterface, error) {
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
-		config.WithRegion("us-east-1"),
-	)
+	cfg, err := loadConfig(config.WithRegion("us-east-1"))
 	if err != nil {
 		return nil, fmt.Errorf("failed to load AWS configuration: %v", err)
 	}
@@ -79,7 +216,7 @@ func (a *AWSInterface) AssumeRole(accountID, roleName string) error {
 	}
 
 	// Create a new AWS config with the role credentials
-	cfg, err := config.LoadDefaultConfig(context.TODO(),
+	cfg, err := loadConfig(
 		config.WithRegion("us-east-1"),
 		config.WithCredentialsProvider(credentials.NewStaticCredentialsProvider(
 			*output.RoleCredentials.AccessKeyId,
@@ -94,10 +231,41 @@ func (a *AWSInterface) AssumeRole(accountID, roleName string) error {
 	// Update the AWS config and create a new Lambda client
 	a.cfg = cfg
 	a.lambdaClient = lambda.NewFromConfig(cfg)
+	a.accountID = accountID
+	a.roleName = roleName
 
 	return nil
 }
 
+// ForRole returns a copy of the interface with credentials for roleName in
+// accountID, leaving a itself untouched so several accounts can be used
+// concurrently from one SSO session.
+func (a *AWSInterface) ForRole(accountID, roleName string) (*AWSInterface, error) {
+	target := *a
+	if err := target.AssumeRole(accountID, roleName); err != nil {
+		return nil, fmt.Errorf("account %s: %v", accountID, err)
+	}
+	return &target, nil
+}
+
+// checkRole fails calls made before a role is assumed, which have neither a
+// Lambda client nor the role's credentials.
+func (a *AWSInterface) checkRole() error {
+	if a.lambdaClient == nil {
+		return fmt.Errorf("no role assumed, assume a role first")
+	}
+	return nil
+}
+
+func (a *AWSInterface) Identity() Identity {
+	return Identity{
+		StartURL:  a.ssoStartURL,
+		AccountID: a.accountID,
+		RoleName:  a.roleName,
+		Region:    a.cfg.Region,
+	}
+}
+
 func (a *AWSInterface) RegisterClient() error {
 	logger.Info("Starting RegisterClient()")
 	registerClientInput := &ssooidc.RegisterClientInput{
@@ -107,8 +275,7 @@ func (a *AWSInterface) RegisterClient() error {
 	}
 
 	logger.Info("Running with input: ")
-	ctx, _ := context.WithTimeout(context.TODO(), time.Second*1)
-	registerClientOutput, err := a.ssooidcClient.RegisterClient(ctx, registerClientInput)
+	registerClientOutput, err := a.ssooidcClient.RegisterClient(context.TODO(), registerClientInput)
 	if err != nil {
 		return fmt.Errorf("failed to register client: %v", err)
 	}
@@ -206,8 +373,16 @@ func (a *AWSInterface) ListAccounts() ([]Account, error) {
 	return accounts, nil
 }
 
-func (a *AWSInterface) ListLambdaFunctions() ([]string, error) {
-	var functionNames []string
+func (a *AWSInterface) ListLambdaFunctions(filter FunctionFilter) ([]LambdaFunction, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	matchName, err := filter.nameMatcher()
+	if err != nil {
+		return nil, err
+	}
+
+	var functions []LambdaFunction
 	var marker *string
 
 	for {
@@ -221,7 +396,11 @@ func (a *AWSInterface) ListLambdaFunctions() ([]string, error) {
 		}
 
 		for _, function := range result.Functions {
-			functionNames = append(functionNames, *function.FunctionName)
+			converted := newLambdaFunction(function)
+			if !filter.matches(converted, matchName) {
+				continue
+			}
+			functions = append(functions, converted)
 		}
 
 		if result.NextMarker == nil {
@@ -230,21 +409,272 @@ func (a *AWSInterface) ListLambdaFunctions() ([]string, error) {
 		marker = result.NextMarker
 	}
 
-	return functionNames, nil
+	if len(filter.Tags) > 0 {
+		return a.filterByTags(functions, filter.Tags)
+	}
+	return functions, nil
+}
+
+func newLambdaFunction(function types.FunctionConfiguration) LambdaFunction {
+	architectures := make([]string, len(function.Architectures))
+	for i, architecture := range function.Architectures {
+		architectures[i] = string(architecture)
+	}
+
+	return LambdaFunction{
+		Name:         aws.ToString(function.FunctionName),
+		ARN:          aws.ToString(function.FunctionArn),
+		Runtime:      string(function.Runtime),
+		Architecture: strings.Join(architectures, ","),
+		MemorySize:   aws.ToInt32(function.MemorySize),
+		Timeout:      aws.ToInt32(function.Timeout),
+		LastModified: aws.ToString(function.LastModified),
+		Description:  aws.ToString(function.Description),
+		PackageType:  string(function.PackageType),
+		Handler:      aws.ToString(function.Handler),
+		Region:       RegionFromARN(aws.ToString(function.FunctionArn)),
+	}
 }
 
-func (a *AWSInterface) InvokeLambda(functionName string, payload []byte) ([]byte, error) {
+// RegionFromARN returns the region field of an ARN such as
+// arn:aws:lambda:eu-west-1:123456789012:function:name, or "" for a plain
+// function name.
+func RegionFromARN(arn string) string {
+	parts := strings.Split(arn, ":")
+	if !strings.HasPrefix(arn, "arn:") || len(parts) < 4 {
+		return ""
+	}
+	return parts[3]
+}
+
+func (a *AWSInterface) GetFunctionTags(functionName string) (map[string]string, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	input := &lambda.GetFunctionInput{
+		FunctionName: aws.String(functionName),
+	}
+
+	result, err := a.lambdaClient.GetFunction(context.TODO(), input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get Lambda function tags: %v", err)
+	}
+
+	return result.Tags, nil
+}
+
+func ParseInvocationType(value string) (types.InvocationType, error) {
+	if value == "" {
+		return types.InvocationTypeRequestResponse, nil
+	}
+	for _, invocationType := range types.InvocationTypeRequestResponse.Values() {
+		if strings.EqualFold(value, string(invocationType)) {
+			return invocationType, nil
+		}
+	}
+	return "", fmt.Errorf("invalid invocation type %q, expected one of RequestResponse, Event or DryRun", value)
+}
+
+func (a *AWSInterface) InvokeLambda(functionName string, payload []byte, opts InvokeOptions) (*InvokeResult, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	invocationType := opts.InvocationType
+	if invocationType == "" {
+		invocationType = types.InvocationTypeRequestResponse
+	}
+
 	input := &lambda.InvokeInput{
+		FunctionName:   aws.String(functionName),
+		Payload:        payload,
+		InvocationType: invocationType,
+	}
+	if opts.Qualifier != "" {
+		input.Qualifier = aws.String(opts.Qualifier)
+	}
+	if opts.TailLogs && invocationType == types.InvocationTypeRequestResponse {
+		input.LogType = types.LogTypeTail
+	}
+
+	var optFns []func(*lambda.Options)
+	if opts.SingleAttempt {
+		optFns = append(optFns, func(o *lambda.Options) { o.RetryMaxAttempts = 1 })
+	}
+	output, err := a.lambdaClient.Invoke(context.TODO(), input, optFns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke Lambda function: %w", err)
+	}
+
+	result := &InvokeResult{
+		InvocationType:  invocationType,
+		StatusCode:      output.StatusCode,
+		FunctionError:   aws.ToString(output.FunctionError),
+		ExecutedVersion: aws.ToString(output.ExecutedVersion),
+		Payload:         output.Payload,
+	}
+	result.RequestID, _ = awsmiddleware.GetRequestIDMetadata(output.ResultMetadata)
+	if output.LogResult != nil {
+		logs, err := base64.StdEncoding.DecodeString(*output.LogResult)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode Lambda log result: %v", err)
+		}
+		result.LogResult = string(logs)
+	}
+
+	return result, nil
+}
+
+func (a *AWSInterface) ListVersions(functionName string) ([]FunctionVersion, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	var versions []FunctionVersion
+	var marker *string
+
+	for {
+		input := &lambda.ListVersionsByFunctionInput{
+			FunctionName: aws.String(functionName),
+			Marker:       marker,
+		}
+
+		result, err := a.lambdaClient.ListVersionsByFunction(context.TODO(), input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Lambda function versions: %v", err)
+		}
+
+		for _, version := range result.Versions {
+			versions = append(versions, FunctionVersion{
+				Version:      aws.ToString(version.Version),
+				Description:  aws.ToString(version.Description),
+				LastModified: aws.ToString(version.LastModified),
+			})
+		}
+
+		if result.NextMarker == nil {
+			break
+		}
+		marker = result.NextMarker
+	}
+
+	return versions, nil
+}
+
+func (a *AWSInterface) ListAliases(functionName string) ([]Alias, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	var aliases []Alias
+	var marker *string
+
+	for {
+		input := &lambda.ListAliasesInput{
+			FunctionName: aws.String(functionName),
+			Marker:       marker,
+		}
+
+		result, err := a.lambdaClient.ListAliases(context.TODO(), input)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list Lambda function aliases: %v", err)
+		}
+
+		for _, alias := range result.Aliases {
+			converted := Alias{
+				Name:            aws.ToString(alias.Name),
+				FunctionVersion: aws.ToString(alias.FunctionVersion),
+				Description:     aws.ToString(alias.Description),
+			}
+			if alias.RoutingConfig != nil {
+				converted.AdditionalVersionWeights = alias.RoutingConfig.AdditionalVersionWeights
+			}
+			aliases = append(aliases, converted)
+		}
+
+		if result.NextMarker == nil {
+			break
+		}
+		marker = result.NextMarker
+	}
+
+	return aliases, nil
+}
+
+func (a *AWSInterface) GetAlias(functionName, aliasName string) (*Alias, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	output, err := a.lambdaClient.GetAlias(context.TODO(), &lambda.GetAliasInput{
 		FunctionName: aws.String(functionName),
-		Payload:      payload,
+		Name:         aws.String(aliasName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get alias %s: %v", aliasName, err)
 	}
 
-	result, err := a.lambdaClient.Invoke(context.TODO(), input)
+	alias := &Alias{
+		Name:            aws.ToString(output.Name),
+		FunctionVersion: aws.ToString(output.FunctionVersion),
+		Description:     aws.ToString(output.Description),
+	}
+	if output.RoutingConfig != nil {
+		alias.AdditionalVersionWeights = output.RoutingConfig.AdditionalVersionWeights
+	}
+	return alias, nil
+}
+
+// SetAliasRouting points the alias at version and sends the given share of
+// traffic (0-1) to each version in weights. Nil weights clear the routing.
+func (a *AWSInterface) SetAliasRouting(functionName, aliasName, version string, weights map[string]float64) error {
+	if err := a.checkRole(); err != nil {
+		return err
+	}
+	if weights == nil {
+		weights = map[string]float64{}
+	}
+	_, err := a.lambdaClient.UpdateAlias(context.TODO(), &lambda.UpdateAliasInput{
+		FunctionName:    aws.String(functionName),
+		Name:            aws.String(aliasName),
+		FunctionVersion: aws.String(version),
+		RoutingConfig:   &types.AliasRoutingConfiguration{AdditionalVersionWeights: weights},
+	})
 	if err != nil {
-		return nil, fmt.Errorf("failed to invoke Lambda function: %v", err)
+		return fmt.Errorf("failed to update routing of alias %s: %v", aliasName, err)
+	}
+	return nil
+}
+
+// ListQualifiers returns $LATEST followed by the function's aliases and
+// published versions, for use in pickers.
+func (a *AWSInterface) ListQualifiers(functionName string) ([]Qualifier, error) {
+	aliases, err := a.ListAliases(functionName)
+	if err != nil {
+		return nil, err
+	}
+	versions, err := a.ListVersions(functionName)
+	if err != nil {
+		return nil, err
+	}
+
+	qualifiers := []Qualifier{{Name: "$LATEST", Description: "Unpublished code"}}
+	for _, alias := range aliases {
+		description := "alias → " + alias.Routing()
+		if alias.Description != "" {
+			description += " — " + alias.Description
+		}
+		qualifiers = append(qualifiers, Qualifier{Name: alias.Name, Description: description})
+	}
+	for i := len(versions) - 1; i >= 0; i-- {
+		version := versions[i]
+		if version.Version == "$LATEST" {
+			continue
+		}
+		description := "version, modified " + version.LastModified
+		if version.Description != "" {
+			description += " — " + version.Description
+		}
+		qualifiers = append(qualifiers, Qualifier{Name: version.Version, Description: description})
 	}
 
-	return result.Payload, nil
+	return qualifiers, nil
 }
 
 func (a *AWSInterface) ListRoles(accountID string) ([]Role, error) {
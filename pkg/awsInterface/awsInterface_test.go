@@ -0,0 +1,40 @@
+package awsInterface
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+// TestCallsNeedARole checks that Lambda calls made after logging in but
+// before a role is assumed fail instead of panicking on the missing client.
+func TestCallsNeedARole(t *testing.T) {
+	awsInterface, err := NewAWSInterface("https://example.awsapps.com/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	calls := map[string]func() error{
+		"ListLambdaFunctions": func() error { _, err := awsInterface.ListLambdaFunctions(FunctionFilter{}); return err },
+		"InvokeLambda":        func() error { _, err := awsInterface.InvokeLambda("fn", nil, InvokeOptions{}); return err },
+		"InvokeLambdaStream": func() error {
+			_, err := awsInterface.InvokeLambdaStream(context.Background(), "fn", nil, InvokeOptions{})
+			return err
+		},
+		"GetAlias":           func() error { _, err := awsInterface.GetAlias("fn", "live"); return err },
+		"SetAliasRouting":    func() error { return awsInterface.SetAliasRouting("fn", "live", "1", nil) },
+		"UpdateEnvironment":  func() error { return awsInterface.UpdateEnvironment("fn", nil, "") },
+		"GetConcurrency":     func() error { _, err := awsInterface.GetConcurrency("fn"); return err },
+		"DescribeFunction":   func() error { _, err := awsInterface.DescribeFunction("fn", ""); return err },
+		"FunctionLogGroup":   func() error { _, err := awsInterface.FunctionLogGroup("fn"); return err },
+		"ListEventSources":   func() error { _, err := awsInterface.ListEventSourceMappings("fn"); return err },
+		"GetFunctionURL":     func() error { _, err := awsInterface.GetFunctionURLConfig("fn", ""); return err },
+		"Deploy":             func() error { _, err := awsInterface.Deploy("fn", DeployOptions{}); return err },
+		"RemoveProvisioning": func() error { return awsInterface.RemoveProvisionedConcurrency("fn", "live") },
+	}
+	for name, call := range calls {
+		if err := call(); err == nil || !strings.Contains(err.Error(), "no role assumed") {
+			t.Errorf("%s() without a role error = %v, want no role assumed", name, err)
+		}
+	}
+}
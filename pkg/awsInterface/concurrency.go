@@ -0,0 +1,156 @@
+package awsInterface
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// Concurrency is a function's concurrency allocation together with the
+// account-wide limits it draws from.
+type Concurrency struct {
+	FunctionName string `json:"function_name" yaml:"function_name"`
+	// Reserved is nil when the function uses the unreserved pool.
+	Reserved              *int32                   `json:"reserved,omitempty" yaml:"reserved,omitempty"`
+	AccountLimit          int32                    `json:"account_limit" yaml:"account_limit"`
+	UnreservedConcurrency int32                    `json:"unreserved_concurrency" yaml:"unreserved_concurrency"`
+	Provisioned           []ProvisionedConcurrency `json:"provisioned,omitempty" yaml:"provisioned,omitempty"`
+}
+
+type ProvisionedConcurrency struct {
+	Qualifier    string `json:"qualifier" yaml:"qualifier"`
+	Requested    int32  `json:"requested" yaml:"requested"`
+	Available    int32  `json:"available" yaml:"available"`
+	Allocated    int32  `json:"allocated" yaml:"allocated"`
+	Status       string `json:"status" yaml:"status"`
+	StatusReason string `json:"status_reason,omitempty" yaml:"status_reason,omitempty"`
+	LastModified string `json:"last_modified,omitempty" yaml:"last_modified,omitempty"`
+}
+
+func (a *AWSInterface) GetConcurrency(functionName string) (*Concurrency, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	reserved, err := a.lambdaClient.GetFunctionConcurrency(context.TODO(), &lambda.GetFunctionConcurrencyInput{
+		FunctionName: aws.String(functionName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get reserved concurrency: %v", err)
+	}
+	settings, err := a.lambdaClient.GetAccountSettings(context.TODO(), &lambda.GetAccountSettingsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get account settings: %v", err)
+	}
+
+	concurrency := &Concurrency{
+		FunctionName: functionName,
+		Reserved:     reserved.ReservedConcurrentExecutions,
+	}
+	if settings.AccountLimit != nil {
+		concurrency.AccountLimit = settings.AccountLimit.ConcurrentExecutions
+		concurrency.UnreservedConcurrency = aws.ToInt32(settings.AccountLimit.UnreservedConcurrentExecutions)
+	}
+
+	var marker *string
+	for {
+		output, err := a.lambdaClient.ListProvisionedConcurrencyConfigs(context.TODO(), &lambda.ListProvisionedConcurrencyConfigsInput{
+			FunctionName: aws.String(functionName),
+			Marker:       marker,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list provisioned concurrency: %v", err)
+		}
+		for _, config := range output.ProvisionedConcurrencyConfigs {
+			_, qualifier := SplitQualifier(aws.ToString(config.FunctionArn))
+			concurrency.Provisioned = append(concurrency.Provisioned, ProvisionedConcurrency{
+				Qualifier:    qualifier,
+				Requested:    aws.ToInt32(config.RequestedProvisionedConcurrentExecutions),
+				Available:    aws.ToInt32(config.AvailableProvisionedConcurrentExecutions),
+				Allocated:    aws.ToInt32(config.AllocatedProvisionedConcurrentExecutions),
+				Status:       string(config.Status),
+				StatusReason: aws.ToString(config.StatusReason),
+				LastModified: aws.ToString(config.LastModified),
+			})
+		}
+		if output.NextMarker == nil {
+			break
+		}
+		marker = output.NextMarker
+	}
+
+	return concurrency, nil
+}
+
+// SetReservedConcurrency reserves executions for the function; 0 throttles
+// it completely.
+func (a *AWSInterface) SetReservedConcurrency(functionName string, executions int32) error {
+	if err := a.checkRole(); err != nil {
+		return err
+	}
+	_, err := a.lambdaClient.PutFunctionConcurrency(context.TODO(), &lambda.PutFunctionConcurrencyInput{
+		FunctionName:                 aws.String(functionName),
+		ReservedConcurrentExecutions: aws.Int32(executions),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to set reserved concurrency: %v", err)
+	}
+	return nil
+}
+
+// RemoveReservedConcurrency returns the function to the unreserved pool.
+func (a *AWSInterface) RemoveReservedConcurrency(functionName string) error {
+	if err := a.checkRole(); err != nil {
+		return err
+	}
+	_, err := a.lambdaClient.DeleteFunctionConcurrency(context.TODO(), &lambda.DeleteFunctionConcurrencyInput{
+		FunctionName: aws.String(functionName),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove reserved concurrency: %v", err)
+	}
+	return nil
+}
+
+// SetProvisionedConcurrency pre-warms executions for a version or alias.
+// Allocation continues in the background; GetConcurrency reports progress.
+func (a *AWSInterface) SetProvisionedConcurrency(functionName, qualifier string, executions int32) (*ProvisionedConcurrency, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	if qualifier == "" || qualifier == "$LATEST" {
+		return nil, fmt.Errorf("provisioned concurrency needs a published version or an alias")
+	}
+	output, err := a.lambdaClient.PutProvisionedConcurrencyConfig(context.TODO(), &lambda.PutProvisionedConcurrencyConfigInput{
+		FunctionName:                    aws.String(functionName),
+		Qualifier:                       aws.String(qualifier),
+		ProvisionedConcurrentExecutions: aws.Int32(executions),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set provisioned concurrency: %v", err)
+	}
+	return &ProvisionedConcurrency{
+		Qualifier:    qualifier,
+		Requested:    aws.ToInt32(output.RequestedProvisionedConcurrentExecutions),
+		Available:    aws.ToInt32(output.AvailableProvisionedConcurrentExecutions),
+		Allocated:    aws.ToInt32(output.AllocatedProvisionedConcurrentExecutions),
+		Status:       string(output.Status),
+		StatusReason: aws.ToString(output.StatusReason),
+		LastModified: aws.ToString(output.LastModified),
+	}, nil
+}
+
+func (a *AWSInterface) RemoveProvisionedConcurrency(functionName, qualifier string) error {
+	if err := a.checkRole(); err != nil {
+		return err
+	}
+	_, err := a.lambdaClient.DeleteProvisionedConcurrencyConfig(context.TODO(), &lambda.DeleteProvisionedConcurrencyConfigInput{
+		FunctionName: aws.String(functionName),
+		Qualifier:    aws.String(qualifier),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to remove provisioned concurrency: %v", err)
+	}
+	return nil
+}
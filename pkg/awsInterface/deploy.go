@@ -0,0 +1,185 @@
+package awsInterface
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const (
+	// maxDirectUploadSize is the largest zip UpdateFunctionCode accepts inline.
+	maxDirectUploadSize = 50 * 1024 * 1024
+
+	defaultDeployTimeout = 5 * time.Minute
+	deployPollInterval   = 2 * time.Second
+)
+
+type DeployOptions struct {
+	// Exactly one of ZipFile (a local path) and ImageURI must be set.
+	ZipFile  string
+	ImageURI string
+	// Publish publishes a version once the update succeeds; Alias, if set,
+	// is then moved to that version and implies Publish.
+	Publish     bool
+	Alias       string
+	Description string
+	Timeout     time.Duration
+	// Progress, if set, is called with a line per deployment step.
+	Progress func(string)
+}
+
+type DeployResult struct {
+	FunctionName   string `json:"function_name" yaml:"function_name"`
+	PreviousSha256 string `json:"previous_sha256" yaml:"previous_sha256"`
+	CodeSha256     string `json:"code_sha256" yaml:"code_sha256"`
+	Version        string `json:"version,omitempty" yaml:"version,omitempty"`
+	Alias          string `json:"alias,omitempty" yaml:"alias,omitempty"`
+}
+
+// CodeUnchangedError is returned when the new code has the same SHA-256 as
+// the deployed code; nothing is published and no alias is moved.
+type CodeUnchangedError struct {
+	FunctionName string
+	CodeSha256   string
+}
+
+func (e *CodeUnchangedError) Error() string {
+	return fmt.Sprintf("code for '%s' is unchanged (SHA-256 %s), refusing to deploy", e.FunctionName, e.CodeSha256)
+}
+
+// Deploy uploads new code with UpdateFunctionCode, waits for the update to
+// finish and optionally publishes a version and moves an alias to it.
+func (a *AWSInterface) Deploy(functionName string, opts DeployOptions) (*DeployResult, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	if (opts.ZipFile == "") == (opts.ImageURI == "") {
+		return nil, fmt.Errorf("exactly one of a zip file or an image URI is required")
+	}
+	progress := opts.Progress
+	if progress == nil {
+		progress = func(string) {}
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = defaultDeployTimeout
+	}
+
+	current, err := a.lambdaClient.GetFunctionConfiguration(context.TODO(), &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(functionName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read function configuration: %v", err)
+	}
+	result := &DeployResult{FunctionName: functionName, PreviousSha256: aws.ToString(current.CodeSha256)}
+	progress(fmt.Sprintf("Current code SHA-256: %s", result.PreviousSha256))
+
+	input := &lambda.UpdateFunctionCodeInput{
+		FunctionName: aws.String(functionName),
+		RevisionId:   current.RevisionId,
+	}
+	if opts.ZipFile != "" {
+		zip, err := os.ReadFile(opts.ZipFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read zip file: %v", err)
+		}
+		if len(zip) > maxDirectUploadSize {
+			return nil, fmt.Errorf("%s is %d bytes, larger than the %d bytes UpdateFunctionCode accepts directly", opts.ZipFile, len(zip), maxDirectUploadSize)
+		}
+		sum := sha256.Sum256(zip)
+		if sha := base64.StdEncoding.EncodeToString(sum[:]); sha == result.PreviousSha256 {
+			return nil, &CodeUnchangedError{FunctionName: functionName, CodeSha256: sha}
+		}
+		input.ZipFile = zip
+		progress(fmt.Sprintf("Uploading %s (%d bytes)", opts.ZipFile, len(zip)))
+	} else {
+		input.ImageUri = aws.String(opts.ImageURI)
+		progress(fmt.Sprintf("Updating image to %s", opts.ImageURI))
+	}
+
+	updated, err := a.lambdaClient.UpdateFunctionCode(context.TODO(), input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update function code: %v", err)
+	}
+	result.CodeSha256 = aws.ToString(updated.CodeSha256)
+	if result.CodeSha256 == result.PreviousSha256 {
+		return nil, &CodeUnchangedError{FunctionName: functionName, CodeSha256: result.CodeSha256}
+	}
+	progress(fmt.Sprintf("New code SHA-256: %s", result.CodeSha256))
+
+	if err := a.waitForUpdate(functionName, timeout, progress); err != nil {
+		return nil, err
+	}
+
+	if !opts.Publish && opts.Alias == "" {
+		return result, nil
+	}
+
+	progress("Publishing version")
+	published, err := a.lambdaClient.PublishVersion(context.TODO(), &lambda.PublishVersionInput{
+		FunctionName: aws.String(functionName),
+		CodeSha256:   aws.String(result.CodeSha256),
+		Description:  aws.String(opts.Description),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to publish version: %v", err)
+	}
+	result.Version = aws.ToString(published.Version)
+	progress(fmt.Sprintf("Published version %s", result.Version))
+
+	if opts.Alias != "" {
+		progress(fmt.Sprintf("Moving alias %s to version %s", opts.Alias, result.Version))
+		// An empty routing config also clears any weighted traffic shift.
+		_, err := a.lambdaClient.UpdateAlias(context.TODO(), &lambda.UpdateAliasInput{
+			FunctionName:    aws.String(functionName),
+			Name:            aws.String(opts.Alias),
+			FunctionVersion: aws.String(result.Version),
+			RoutingConfig:   &types.AliasRoutingConfiguration{},
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to move alias %s to version %s: %v", opts.Alias, result.Version, err)
+		}
+		result.Alias = opts.Alias
+	}
+
+	progress("Deployment complete")
+	return result, nil
+}
+
+// waitForUpdate polls until LastUpdateStatus leaves InProgress.
+func (a *AWSInterface) waitForUpdate(functionName string, timeout time.Duration, progress func(string)) error {
+	if err := a.checkRole(); err != nil {
+		return err
+	}
+	deadline := time.Now().Add(timeout)
+	for {
+		configuration, err := a.lambdaClient.GetFunctionConfiguration(context.TODO(), &lambda.GetFunctionConfigurationInput{
+			FunctionName: aws.String(functionName),
+		})
+		if err != nil {
+			return fmt.Errorf("failed to read function configuration: %v", err)
+		}
+
+		switch configuration.LastUpdateStatus {
+		case types.LastUpdateStatusSuccessful:
+			progress("Update successful")
+			return nil
+		case types.LastUpdateStatusFailed:
+			return fmt.Errorf("update of '%s' failed: %s (%s)", functionName,
+				aws.ToString(configuration.LastUpdateStatusReason), configuration.LastUpdateStatusReasonCode)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out after %s waiting for '%s' to finish updating", timeout, functionName)
+		}
+		progress(fmt.Sprintf("Waiting for update (status %s)", configuration.LastUpdateStatus))
+		time.Sleep(deployPollInterval)
+	}
+}
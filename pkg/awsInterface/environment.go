@@ -0,0 +1,59 @@
+package awsInterface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// Environment is a function's environment variables together with the
+// revision they were read at.
+type Environment struct {
+	Variables  map[string]string
+	RevisionID string
+}
+
+func (a *AWSInterface) GetEnvironment(functionName string) (*Environment, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	output, err := a.lambdaClient.GetFunctionConfiguration(context.TODO(), &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(functionName),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to read function configuration: %v", err)
+	}
+
+	environment := &Environment{Variables: map[string]string{}, RevisionID: aws.ToString(output.RevisionId)}
+	if output.Environment != nil {
+		for key, value := range output.Environment.Variables {
+			environment.Variables[key] = value
+		}
+	}
+	return environment, nil
+}
+
+// UpdateEnvironment replaces the function's environment variables, but only
+// if the function is still at revisionID.
+func (a *AWSInterface) UpdateEnvironment(functionName string, variables map[string]string, revisionID string) error {
+	if err := a.checkRole(); err != nil {
+		return err
+	}
+	_, err := a.lambdaClient.UpdateFunctionConfiguration(context.TODO(), &lambda.UpdateFunctionConfigurationInput{
+		FunctionName: aws.String(functionName),
+		Environment:  &types.Environment{Variables: variables},
+		RevisionId:   aws.String(revisionID),
+	})
+	var preconditionFailed *types.PreconditionFailedException
+	if errors.As(err, &preconditionFailed) {
+		return fmt.Errorf("'%s' was modified after its environment was read, review the changes again", functionName)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update environment variables: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,25 @@
+package awsInterface
+
+import (
+	"errors"
+	"strconv"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// IsThrottle reports whether err is Lambda rejecting a request because a
+// concurrency or request-rate limit was hit. The second result is the
+// service's Retry-After hint, or zero when it gave none.
+func IsThrottle(err error) (bool, time.Duration) {
+	var throttle *types.TooManyRequestsException
+	if !errors.As(err, &throttle) {
+		return false, 0
+	}
+	if throttle.RetryAfterSeconds != nil {
+		if seconds, err := strconv.Atoi(*throttle.RetryAfterSeconds); err == nil {
+			return true, time.Duration(seconds) * time.Second
+		}
+	}
+	return true, 0
+}
@@ -0,0 +1,142 @@
+package awsInterface
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+type EventSourceMapping struct {
+	UUID                 string `json:"uuid" yaml:"uuid"`
+	Source               string `json:"source" yaml:"source"`
+	EventSourceArn       string `json:"event_source_arn,omitempty" yaml:"event_source_arn,omitempty"`
+	State                string `json:"state" yaml:"state"`
+	StateReason          string `json:"state_reason,omitempty" yaml:"state_reason,omitempty"`
+	BatchSize            int32  `json:"batch_size" yaml:"batch_size"`
+	BatchingWindow       int32  `json:"batching_window_seconds,omitempty" yaml:"batching_window_seconds,omitempty"`
+	LastProcessingResult string `json:"last_processing_result,omitempty" yaml:"last_processing_result,omitempty"`
+	LastModified         string `json:"last_modified,omitempty" yaml:"last_modified,omitempty"`
+}
+
+// Enabled reports whether the mapping is polling, or about to.
+func (m EventSourceMapping) Enabled() bool {
+	switch m.State {
+	case "Enabled", "Enabling", "Creating", "Updating":
+		return true
+	}
+	return false
+}
+
+// Summary is a one-line description of the mapping.
+func (m EventSourceMapping) Summary() string {
+	source := m.EventSourceArn
+	if i := strings.LastIndex(source, ":"); i >= 0 {
+		source = source[i+1:]
+	}
+	parts := []string{fmt.Sprintf("%s %s", m.Source, source), m.State, fmt.Sprintf("batch %d", m.BatchSize)}
+	if m.LastProcessingResult != "" {
+		parts = append(parts, "last result: "+m.LastProcessingResult)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (a *AWSInterface) ListEventSourceMappings(functionName string) ([]EventSourceMapping, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	var mappings []EventSourceMapping
+	var marker *string
+	for {
+		output, err := a.lambdaClient.ListEventSourceMappings(context.TODO(), &lambda.ListEventSourceMappingsInput{
+			FunctionName: aws.String(functionName),
+			Marker:       marker,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to list event source mappings: %v", err)
+		}
+		for _, mapping := range output.EventSourceMappings {
+			mappings = append(mappings, newEventSourceMapping(mapping))
+		}
+		if output.NextMarker == nil {
+			break
+		}
+		marker = output.NextMarker
+	}
+	return mappings, nil
+}
+
+// SetEventSourceMappingEnabled pauses or resumes a mapping. The change is
+// asynchronous; the returned state is usually Enabling or Disabling.
+func (a *AWSInterface) SetEventSourceMappingEnabled(uuid string, enabled bool) (*EventSourceMapping, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	output, err := a.lambdaClient.UpdateEventSourceMapping(context.TODO(), &lambda.UpdateEventSourceMappingInput{
+		UUID:    aws.String(uuid),
+		Enabled: aws.Bool(enabled),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to update event source mapping %s: %v", uuid, err)
+	}
+	mapping := newEventSourceMapping(types.EventSourceMappingConfiguration{
+		UUID:                           output.UUID,
+		EventSourceArn:                 output.EventSourceArn,
+		SelfManagedEventSource:         output.SelfManagedEventSource,
+		State:                          output.State,
+		StateTransitionReason:          output.StateTransitionReason,
+		BatchSize:                      output.BatchSize,
+		MaximumBatchingWindowInSeconds: output.MaximumBatchingWindowInSeconds,
+		LastProcessingResult:           output.LastProcessingResult,
+		LastModified:                   output.LastModified,
+	})
+	return &mapping, nil
+}
+
+func newEventSourceMapping(mapping types.EventSourceMappingConfiguration) EventSourceMapping {
+	converted := EventSourceMapping{
+		UUID:                 aws.ToString(mapping.UUID),
+		EventSourceArn:       aws.ToString(mapping.EventSourceArn),
+		State:                aws.ToString(mapping.State),
+		StateReason:          aws.ToString(mapping.StateTransitionReason),
+		BatchSize:            aws.ToInt32(mapping.BatchSize),
+		BatchingWindow:       aws.ToInt32(mapping.MaximumBatchingWindowInSeconds),
+		LastProcessingResult: aws.ToString(mapping.LastProcessingResult),
+	}
+	if mapping.LastModified != nil {
+		converted.LastModified = mapping.LastModified.Format(time.RFC3339)
+	}
+	converted.Source = eventSourceKind(converted.EventSourceArn)
+	if converted.Source == "" && mapping.SelfManagedEventSource != nil {
+		converted.Source = "Kafka"
+	}
+	return converted
+}
+
+// eventSourceKind names the service behind an event source ARN, e.g.
+// arn:aws:sqs:... is "SQS".
+func eventSourceKind(arn string) string {
+	parts := strings.Split(arn, ":")
+	if len(parts) < 6 {
+		return ""
+	}
+	switch parts[2] {
+	case "sqs":
+		return "SQS"
+	case "kinesis":
+		return "Kinesis"
+	case "dynamodb":
+		return "DynamoDB"
+	case "kafka":
+		return "MSK"
+	case "mq":
+		return "MQ"
+	case "rds":
+		return "DocumentDB"
+	}
+	return parts[2]
+}
@@ -0,0 +1,194 @@
+package awsInterface
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+const lastModifiedLayout = "2006-01-02T15:04:05.000-0700"
+
+type FunctionFilter struct {
+	NameGlob       string
+	NameRegex      string
+	Runtimes       []string
+	Tags           map[string]string
+	ModifiedAfter  time.Time
+	ModifiedBefore time.Time
+}
+
+// ParseFilter builds a FunctionFilter from key=value expressions:
+//
+//	name=<glob>  regex=<regexp>  runtime=<runtime>[,<runtime>...]
+//	tag=<key>[=<value>]  modified-after=<date>  modified-before=<date>
+//
+// Dates are YYYY-MM-DD or RFC 3339.
+func ParseFilter(expressions []string) (FunctionFilter, error) {
+	var filter FunctionFilter
+	for _, expression := range expressions {
+		key, value, ok := strings.Cut(expression, "=")
+		if !ok {
+			return filter, fmt.Errorf("invalid filter %q, expected key=value", expression)
+		}
+
+		switch key {
+		case "name":
+			filter.NameGlob = value
+		case "regex":
+			filter.NameRegex = value
+		case "runtime":
+			filter.Runtimes = append(filter.Runtimes, strings.Split(value, ",")...)
+		case "tag":
+			tagKey, tagValue, _ := strings.Cut(value, "=")
+			if filter.Tags == nil {
+				filter.Tags = map[string]string{}
+			}
+			filter.Tags[tagKey] = tagValue
+		case "modified-after", "modified-before":
+			t, err := parseDate(value)
+			if err != nil {
+				return filter, fmt.Errorf("invalid filter %q: %v", expression, err)
+			}
+			if key == "modified-after" {
+				filter.ModifiedAfter = t
+			} else {
+				filter.ModifiedBefore = t
+			}
+		default:
+			return filter, fmt.Errorf("unknown filter %q", key)
+		}
+	}
+
+	if _, err := filter.nameMatcher(); err != nil {
+		return filter, err
+	}
+	return filter, nil
+}
+
+func parseDate(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	return time.Parse("2006-01-02", value)
+}
+
+func (f FunctionFilter) nameMatcher() (func(string) bool, error) {
+	var regex *regexp.Regexp
+	if f.NameRegex != "" {
+		var err error
+		regex, err = regexp.Compile(f.NameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid name regex: %v", err)
+		}
+	}
+	if f.NameGlob != "" {
+		if _, err := path.Match(f.NameGlob, ""); err != nil {
+			return nil, fmt.Errorf("invalid name glob: %v", err)
+		}
+	}
+
+	return func(name string) bool {
+		if f.NameGlob != "" {
+			if matched, _ := path.Match(f.NameGlob, name); !matched {
+				return false
+			}
+		}
+		return regex == nil || regex.MatchString(name)
+	}, nil
+}
+
+// matches applies every criterion except tags, which need an API call.
+func (f FunctionFilter) matches(function LambdaFunction, matchName func(string) bool) bool {
+	if !matchName(function.Name) {
+		return false
+	}
+
+	if len(f.Runtimes) > 0 {
+		found := false
+		for _, runtime := range f.Runtimes {
+			if strings.EqualFold(runtime, function.Runtime) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+
+	if !f.ModifiedAfter.IsZero() || !f.ModifiedBefore.IsZero() {
+		modified, err := time.Parse(lastModifiedLayout, function.LastModified)
+		if err != nil {
+			return false
+		}
+		if !f.ModifiedAfter.IsZero() && modified.Before(f.ModifiedAfter) {
+			return false
+		}
+		if !f.ModifiedBefore.IsZero() && modified.After(f.ModifiedBefore) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// filterByTags keeps the functions carrying the tags, listing the tags of
+// several functions at a time.
+func (a *AWSInterface) filterByTags(functions []LambdaFunction, tags map[string]string) ([]LambdaFunction, error) {
+	const concurrency = 8
+
+	var wg sync.WaitGroup
+	matched := make([]bool, len(functions))
+	errs := make([]error, len(functions))
+	semaphore := make(chan struct{}, concurrency)
+
+	for i, function := range functions {
+		wg.Add(1)
+		go func(i int, function LambdaFunction) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			matched[i], errs[i] = a.matchesTags(function, tags)
+		}(i, function)
+	}
+	wg.Wait()
+
+	var filtered []LambdaFunction
+	for i, function := range functions {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		if matched[i] {
+			filtered = append(filtered, function)
+		}
+	}
+	return filtered, nil
+}
+
+func (a *AWSInterface) matchesTags(function LambdaFunction, tags map[string]string) (bool, error) {
+	if err := a.checkRole(); err != nil {
+		return false, err
+	}
+	output, err := a.lambdaClient.ListTags(context.TODO(), &lambda.ListTagsInput{
+		Resource: aws.String(function.ARN),
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to list tags for %s: %v", function.Name, err)
+	}
+
+	for key, value := range tags {
+		actual, ok := output.Tags[key]
+		if !ok || (value != "" && actual != value) {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,89 @@
+package awsInterface
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFilter(t *testing.T) {
+	filter, err := ParseFilter([]string{
+		"name=api-*",
+		"runtime=go1.x,provided.al2",
+		"tag=team=core",
+		"tag=owner",
+		"modified-after=2024-01-01",
+		"modified-before=2024-02-01T12:00:00Z",
+	})
+	if err != nil {
+		t.Fatalf("ParseFilter() error = %v", err)
+	}
+	want := FunctionFilter{
+		NameGlob:       "api-*",
+		Runtimes:       []string{"go1.x", "provided.al2"},
+		Tags:           map[string]string{"team": "core", "owner": ""},
+		ModifiedAfter:  time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		ModifiedBefore: time.Date(2024, 2, 1, 12, 0, 0, 0, time.UTC),
+	}
+	if !reflect.DeepEqual(filter, want) {
+		t.Errorf("ParseFilter() = %+v, want %+v", filter, want)
+	}
+}
+
+func TestParseFilterErrors(t *testing.T) {
+	for expression, want := range map[string]string{
+		"name":                     "expected key=value",
+		"size=1":                   "unknown filter",
+		"modified-after=yesterday": "invalid filter",
+		"regex=(":                  "invalid name regex",
+		"name=[":                   "invalid name glob",
+	} {
+		if _, err := ParseFilter([]string{expression}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseFilter(%q) error = %v, want %q", expression, err, want)
+		}
+	}
+}
+
+func TestFilterMatches(t *testing.T) {
+	function := LambdaFunction{
+		Name:         "api-orders",
+		Runtime:      "provided.al2",
+		LastModified: "2024-03-10T08:00:00.000+0000",
+	}
+
+	tests := []struct {
+		expressions []string
+		want        bool
+	}{
+		{expressions: nil, want: true},
+		{expressions: []string{"name=api-*"}, want: true},
+		{expressions: []string{"name=web-*"}, want: false},
+		{expressions: []string{"regex=orders$"}, want: true},
+		{expressions: []string{"name=api-*", "regex=^api-users"}, want: false},
+		{expressions: []string{"runtime=go1.x,PROVIDED.AL2"}, want: true},
+		{expressions: []string{"runtime=python3.12"}, want: false},
+		{expressions: []string{"modified-after=2024-03-01", "modified-before=2024-04-01"}, want: true},
+		{expressions: []string{"modified-after=2024-03-11"}, want: false},
+		{expressions: []string{"modified-before=2024-03-10"}, want: false},
+	}
+	for _, test := range tests {
+		filter, err := ParseFilter(test.expressions)
+		if err != nil {
+			t.Fatalf("ParseFilter(%q) error = %v", test.expressions, err)
+		}
+		matchName, err := filter.nameMatcher()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := filter.matches(function, matchName); got != test.want {
+			t.Errorf("matches(%q) = %v, want %v", test.expressions, got, test.want)
+		}
+	}
+
+	filter, _ := ParseFilter([]string{"modified-after=2024-01-01"})
+	matchName, _ := filter.nameMatcher()
+	if filter.matches(LambdaFunction{Name: "x", LastModified: "not a date"}, matchName) {
+		t.Error("matches() = true for an unparseable modification date, want false")
+	}
+}
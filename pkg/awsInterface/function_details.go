@@ -0,0 +1,107 @@
+package awsInterface
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+type FunctionDetails struct {
+	LambdaFunction         `yaml:",inline"`
+	Version                string            `json:"version" yaml:"version"`
+	Role                   string            `json:"role" yaml:"role"`
+	EnvironmentKeys        []string          `json:"environment_keys,omitempty" yaml:"environment_keys,omitempty"`
+	VpcConfig              *VpcConfig        `json:"vpc_config,omitempty" yaml:"vpc_config,omitempty"`
+	Layers                 []Layer           `json:"layers,omitempty" yaml:"layers,omitempty"`
+	ReservedConcurrency    *int32            `json:"reserved_concurrency,omitempty" yaml:"reserved_concurrency,omitempty"`
+	DeadLetterTarget       string            `json:"dead_letter_target,omitempty" yaml:"dead_letter_target,omitempty"`
+	Tags                   map[string]string `json:"tags,omitempty" yaml:"tags,omitempty"`
+	CodeSha256             string            `json:"code_sha256" yaml:"code_sha256"`
+	CodeSize               int64             `json:"code_size" yaml:"code_size"`
+	ImageURI               string            `json:"image_uri,omitempty" yaml:"image_uri,omitempty"`
+	State                  string            `json:"state" yaml:"state"`
+	StateReason            string            `json:"state_reason,omitempty" yaml:"state_reason,omitempty"`
+	LastUpdateStatus       string            `json:"last_update_status" yaml:"last_update_status"`
+	LastUpdateStatusReason string            `json:"last_update_status_reason,omitempty" yaml:"last_update_status_reason,omitempty"`
+	RevisionID             string            `json:"revision_id" yaml:"revision_id"`
+}
+
+type VpcConfig struct {
+	VpcID            string   `json:"vpc_id" yaml:"vpc_id"`
+	SubnetIDs        []string `json:"subnet_ids" yaml:"subnet_ids"`
+	SecurityGroupIDs []string `json:"security_group_ids" yaml:"security_group_ids"`
+}
+
+type Layer struct {
+	ARN      string `json:"arn" yaml:"arn"`
+	CodeSize int64  `json:"code_size" yaml:"code_size"`
+}
+
+// DescribeFunction fetches the full configuration of a function, optionally
+// qualified with a version or alias. Environment variable values are not
+// included, only their keys.
+func (a *AWSInterface) DescribeFunction(functionName, qualifier string) (*FunctionDetails, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	input := &lambda.GetFunctionInput{
+		FunctionName: aws.String(functionName),
+	}
+	if qualifier != "" {
+		input.Qualifier = aws.String(qualifier)
+	}
+
+	output, err := a.lambdaClient.GetFunction(context.TODO(), input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe Lambda function: %v", err)
+	}
+	configuration := output.Configuration
+
+	details := &FunctionDetails{
+		LambdaFunction:         newLambdaFunction(*configuration),
+		Version:                aws.ToString(configuration.Version),
+		Role:                   aws.ToString(configuration.Role),
+		Tags:                   output.Tags,
+		CodeSha256:             aws.ToString(configuration.CodeSha256),
+		CodeSize:               configuration.CodeSize,
+		State:                  string(configuration.State),
+		StateReason:            aws.ToString(configuration.StateReason),
+		LastUpdateStatus:       string(configuration.LastUpdateStatus),
+		LastUpdateStatusReason: aws.ToString(configuration.LastUpdateStatusReason),
+		RevisionID:             aws.ToString(configuration.RevisionId),
+	}
+
+	if configuration.Environment != nil {
+		for key := range configuration.Environment.Variables {
+			details.EnvironmentKeys = append(details.EnvironmentKeys, key)
+		}
+		sort.Strings(details.EnvironmentKeys)
+	}
+	if vpc := configuration.VpcConfig; vpc != nil && aws.ToString(vpc.VpcId) != "" {
+		details.VpcConfig = &VpcConfig{
+			VpcID:            aws.ToString(vpc.VpcId),
+			SubnetIDs:        vpc.SubnetIds,
+			SecurityGroupIDs: vpc.SecurityGroupIds,
+		}
+	}
+	for _, layer := range configuration.Layers {
+		details.Layers = append(details.Layers, Layer{
+			ARN:      aws.ToString(layer.Arn),
+			CodeSize: layer.CodeSize,
+		})
+	}
+	if output.Concurrency != nil {
+		details.ReservedConcurrency = output.Concurrency.ReservedConcurrentExecutions
+	}
+	if configuration.DeadLetterConfig != nil {
+		details.DeadLetterTarget = aws.ToString(configuration.DeadLetterConfig.TargetArn)
+	}
+	if output.Code != nil {
+		details.ImageURI = aws.ToString(output.Code.ImageUri)
+	}
+
+	return details, nil
+}
@@ -0,0 +1,157 @@
+package awsInterface
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+type FunctionURLConfig struct {
+	URL          string `json:"url" yaml:"url"`
+	AuthType     string `json:"auth_type" yaml:"auth_type"`
+	InvokeMode   string `json:"invoke_mode,omitempty" yaml:"invoke_mode,omitempty"`
+	FunctionArn  string `json:"function_arn" yaml:"function_arn"`
+	LastModified string `json:"last_modified,omitempty" yaml:"last_modified,omitempty"`
+}
+
+func (a *AWSInterface) GetFunctionURLConfig(functionName, qualifier string) (*FunctionURLConfig, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	input := &lambda.GetFunctionUrlConfigInput{
+		FunctionName: aws.String(functionName),
+	}
+	if qualifier != "" {
+		input.Qualifier = aws.String(qualifier)
+	}
+	output, err := a.lambdaClient.GetFunctionUrlConfig(context.TODO(), input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get function URL: %v", err)
+	}
+	return &FunctionURLConfig{
+		URL:          aws.ToString(output.FunctionUrl),
+		AuthType:     string(output.AuthType),
+		InvokeMode:   string(output.InvokeMode),
+		FunctionArn:  aws.ToString(output.FunctionArn),
+		LastModified: aws.ToString(output.LastModifiedTime),
+	}, nil
+}
+
+type URLRequest struct {
+	Method  string
+	Path    string
+	Headers http.Header
+	Body    []byte
+	// Endpoint replaces the discovered function URL, e.g. for a local
+	// emulator. Requests to it are signed unless Unsigned is set.
+	Endpoint string
+	Unsigned bool
+}
+
+type URLResponse struct {
+	URL        string      `json:"url" yaml:"url"`
+	StatusCode int         `json:"status_code" yaml:"status_code"`
+	Status     string      `json:"status" yaml:"status"`
+	Headers    http.Header `json:"headers" yaml:"headers"`
+	Body       string      `json:"body" yaml:"body"`
+	DurationMs int64       `json:"duration_ms" yaml:"duration_ms"`
+}
+
+// InvokeFunctionURL sends an HTTP request to the function's URL, signing it
+// with the role's credentials when the URL uses AWS_IAM auth.
+func (a *AWSInterface) InvokeFunctionURL(functionName, qualifier string, request URLRequest) (*URLResponse, error) {
+	endpoint := request.Endpoint
+	sign := !request.Unsigned
+	if endpoint == "" {
+		config, err := a.GetFunctionURLConfig(functionName, qualifier)
+		if err != nil {
+			return nil, err
+		}
+		endpoint = config.URL
+		sign = sign && config.AuthType == string(types.FunctionUrlAuthTypeAwsIam)
+	}
+
+	target, err := url.Parse(strings.TrimSuffix(endpoint, "/") + "/" + strings.TrimPrefix(request.Path, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid function URL: %v", err)
+	}
+	method := strings.ToUpper(request.Method)
+	if method == "" {
+		method = http.MethodGet
+	}
+	req, err := http.NewRequest(method, target.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build request: %v", err)
+	}
+	for key, values := range request.Headers {
+		for _, value := range values {
+			req.Header.Add(key, value)
+		}
+	}
+
+	started := time.Now()
+	var resp *http.Response
+	if sign {
+		resp, err = a.sendSigned(context.TODO(), req, request.Body, "lambda", urlRegion(target.Host, a.cfg.Region))
+	} else {
+		if len(request.Body) > 0 {
+			req.Body = io.NopCloser(bytes.NewReader(request.Body))
+			req.ContentLength = int64(len(request.Body))
+		}
+		resp, err = http.DefaultClient.Do(req)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to call %s: %v", target, err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %v", err)
+	}
+	return &URLResponse{
+		URL:        target.String(),
+		StatusCode: resp.StatusCode,
+		Status:     resp.Status,
+		Headers:    resp.Header,
+		Body:       string(body),
+		DurationMs: time.Since(started).Milliseconds(),
+	}, nil
+}
+
+// urlRegion reads the region from a host like
+// abc123.lambda-url.eu-west-1.on.aws.
+func urlRegion(host, fallback string) string {
+	parts := strings.Split(strings.Split(host, ":")[0], ".")
+	for i, part := range parts {
+		if part == "lambda-url" && i+1 < len(parts) {
+			return parts[i+1]
+		}
+	}
+	return fallback
+}
+
+// ParseHeaders reads "Name: value" lines, as given to curl's -H.
+func ParseHeaders(lines []string) (http.Header, error) {
+	headers := http.Header{}
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok || strings.TrimSpace(name) == "" {
+			return nil, fmt.Errorf("invalid header %q, expected Name: value", line)
+		}
+		headers.Add(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+	return headers, nil
+}
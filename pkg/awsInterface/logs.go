@@ -0,0 +1,339 @@
+package awsInterface
+
+import (
+	"aws_utility/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs"
+	"github.com/aws/aws-sdk-go-v2/service/cloudwatchlogs/types"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// followLookback is how far back each poll looks again, so events that are
+// ingested late are still picked up; seen events are skipped by ID.
+const followLookback = 30 * time.Second
+
+type LogEvent struct {
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+	LogStream string    `json:"log_stream" yaml:"log_stream"`
+	Message   string    `json:"message" yaml:"message"`
+	EventID   string    `json:"event_id" yaml:"event_id"`
+}
+
+// Line formats the event for display, one line per event where the
+// message allows.
+func (e LogEvent) Line() string {
+	return e.Timestamp.Local().Format("2006-01-02 15:04:05.000") + " " + strings.TrimRight(e.Message, "\n")
+}
+
+type LogQuery struct {
+	LogGroup string
+	// Start and End bound the events by timestamp; a zero End means now.
+	Start         time.Time
+	End           time.Time
+	FilterPattern string
+	// RequestID keeps only the events of one invocation.
+	RequestID string
+}
+
+// pattern is the filter sent to CloudWatch. A request ID is matched as a
+// quoted term unless a filter pattern is given, in which case it is
+// matched locally since the two cannot always be combined.
+func (q LogQuery) pattern() string {
+	if q.FilterPattern == "" && q.RequestID != "" {
+		return `"` + q.RequestID + `"`
+	}
+	return q.FilterPattern
+}
+
+func (q LogQuery) matches(event LogEvent) bool {
+	return q.RequestID == "" || strings.Contains(event.Message, q.RequestID)
+}
+
+// LogBatch is one poll's worth of new events, or an error from that poll.
+type LogBatch struct {
+	Events []LogEvent
+	Err    error
+}
+
+// IsLogGroupNotFound reports whether err means the log group does not
+// exist, which is the case until the function first runs.
+func IsLogGroupNotFound(err error) bool {
+	var notFound *types.ResourceNotFoundException
+	return errors.As(err, &notFound)
+}
+
+// logsClient returns a CloudWatch Logs client for the interface's role and
+// region.
+func (a *AWSInterface) logsClient() (*cloudwatchlogs.Client, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	return cloudwatchlogs.NewFromConfig(a.cfg), nil
+}
+
+// FunctionLogGroup returns the log group the function writes to: the one
+// in its logging configuration, or /aws/lambda/<name> by default.
+func (a *AWSInterface) FunctionLogGroup(functionName string) (string, error) {
+	if err := a.checkRole(); err != nil {
+		return "", err
+	}
+	functionName, _ = SplitQualifier(functionName)
+	output, err := a.lambdaClient.GetFunctionConfiguration(context.TODO(), &lambda.GetFunctionConfigurationInput{
+		FunctionName: aws.String(functionName),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to get Lambda function configuration: %v", err)
+	}
+	if output.LoggingConfig != nil && aws.ToString(output.LoggingConfig.LogGroup) != "" {
+		return aws.ToString(output.LoggingConfig.LogGroup), nil
+	}
+	return "/aws/lambda/" + aws.ToString(output.FunctionName), nil
+}
+
+// FilterLogs returns the events matching query, oldest first. A positive
+// limit stops after that many events.
+func (a *AWSInterface) FilterLogs(query LogQuery, limit int) ([]LogEvent, error) {
+	return a.filterLogs(context.TODO(), query, limit)
+}
+
+func (a *AWSInterface) filterLogs(ctx context.Context, query LogQuery, limit int) ([]LogEvent, error) {
+	client, err := a.logsClient()
+	if err != nil {
+		return nil, err
+	}
+	input := &cloudwatchlogs.FilterLogEventsInput{
+		LogGroupName: aws.String(query.LogGroup),
+	}
+	if pattern := query.pattern(); pattern != "" {
+		input.FilterPattern = aws.String(pattern)
+	}
+	if !query.Start.IsZero() {
+		input.StartTime = aws.Int64(query.Start.UnixMilli())
+	}
+	if !query.End.IsZero() {
+		input.EndTime = aws.Int64(query.End.UnixMilli())
+	}
+
+	var events []LogEvent
+	paginator := cloudwatchlogs.NewFilterLogEventsPaginator(client, input)
+	for paginator.HasMorePages() {
+		output, err := paginator.NextPage(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("failed to filter log events: %w", err)
+		}
+		for _, event := range output.Events {
+			logEvent := LogEvent{
+				Timestamp: time.UnixMilli(aws.ToInt64(event.Timestamp)),
+				LogStream: aws.ToString(event.LogStreamName),
+				Message:   aws.ToString(event.Message),
+				EventID:   aws.ToString(event.EventId),
+			}
+			if query.matches(logEvent) {
+				events = append(events, logEvent)
+			}
+		}
+		if limit > 0 && len(events) >= limit {
+			break
+		}
+	}
+
+	// Events from several log streams are not guaranteed to interleave.
+	sort.SliceStable(events, func(i, j int) bool { return events[i].Timestamp.Before(events[j].Timestamp) })
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events, nil
+}
+
+// FollowLogs sends the events matching query from its start on, then new
+// ones as they arrive, until ctx is done, and closes the channel. New events
+// come from a Live Tail session; when one cannot be started or ends, the
+// log group is polled every interval instead. Only batches with events or
+// an error are sent.
+func (a *AWSInterface) FollowLogs(ctx context.Context, query LogQuery, interval time.Duration) <-chan LogBatch {
+	batches := make(chan LogBatch)
+	go func() {
+		defer close(batches)
+
+		if query.Start.IsZero() {
+			query.Start = time.Now()
+		}
+		query.End = time.Time{}
+		send := func(batch LogBatch) bool {
+			select {
+			case batches <- batch:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		newest, err := a.liveTail(ctx, query, send)
+		if ctx.Err() != nil {
+			return
+		}
+		logger.Warn("Polling for log events instead of live tailing:", err)
+		if newest.After(query.Start) {
+			query.Start = newest.Add(time.Millisecond)
+		}
+		a.pollLogs(ctx, query, interval, send)
+	}()
+	return batches
+}
+
+// liveTail sends the events since query's start, then those of a Live Tail
+// session, until ctx is done or the session fails or ends, which it does
+// after three hours. It returns the time of the newest event sent.
+func (a *AWSInterface) liveTail(ctx context.Context, query LogQuery, send func(LogBatch) bool) (time.Time, error) {
+	newest := query.Start
+	client, err := a.logsClient()
+	if err != nil {
+		return newest, err
+	}
+	// Live Tail only takes log group ARNs.
+	groups, err := client.DescribeLogGroups(ctx, &cloudwatchlogs.DescribeLogGroupsInput{
+		LogGroupNamePrefix: aws.String(query.LogGroup),
+	})
+	if err != nil {
+		return newest, fmt.Errorf("failed to describe log group: %w", err)
+	}
+	groupARN := ""
+	for _, group := range groups.LogGroups {
+		if aws.ToString(group.LogGroupName) == query.LogGroup {
+			groupARN = aws.ToString(group.LogGroupArn)
+		}
+	}
+	if groupARN == "" {
+		return newest, fmt.Errorf("log group %s does not exist yet", query.LogGroup)
+	}
+
+	input := &cloudwatchlogs.StartLiveTailInput{LogGroupIdentifiers: []string{groupARN}}
+	if pattern := query.pattern(); pattern != "" {
+		input.LogEventFilterPattern = aws.String(pattern)
+	}
+	output, err := client.StartLiveTail(ctx, input)
+	if err != nil {
+		return newest, fmt.Errorf("failed to start live tail: %w", err)
+	}
+	stream := output.GetStream()
+	defer stream.Close()
+
+	// The session only has events from its start, so earlier ones are read
+	// separately while its first events wait in the stream.
+	if started := time.Now(); started.After(query.Start) {
+		window := query
+		window.End = started
+		events, err := a.filterLogs(ctx, window, 0)
+		if len(events) > 0 || err != nil {
+			if !send(LogBatch{Events: events, Err: err}) {
+				return newest, nil
+			}
+		}
+		if n := len(events); n > 0 && events[n-1].Timestamp.After(newest) {
+			newest = events[n-1].Timestamp
+		}
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return newest, nil
+		case message, ok := <-stream.Events():
+			if !ok {
+				if err := stream.Err(); err != nil {
+					return newest, fmt.Errorf("live tail failed: %w", err)
+				}
+				return newest, fmt.Errorf("live tail session ended")
+			}
+			update, ok := message.(*types.StartLiveTailResponseStreamMemberSessionUpdate)
+			if !ok {
+				continue
+			}
+			var batch LogBatch
+			for _, result := range update.Value.SessionResults {
+				event := LogEvent{
+					Timestamp: time.UnixMilli(aws.ToInt64(result.Timestamp)),
+					LogStream: aws.ToString(result.LogStreamName),
+					Message:   aws.ToString(result.Message),
+				}
+				if query.matches(event) {
+					batch.Events = append(batch.Events, event)
+					if event.Timestamp.After(newest) {
+						newest = event.Timestamp
+					}
+				}
+			}
+			if len(batch.Events) == 0 {
+				continue
+			}
+			sort.SliceStable(batch.Events, func(i, j int) bool { return batch.Events[i].Timestamp.Before(batch.Events[j].Timestamp) })
+			if !send(batch) {
+				return newest, nil
+			}
+		}
+	}
+}
+
+// pollLogs filters the log group for new events every interval until ctx
+// is done. A missing log group is reported once and then waited for, since
+// it is created on the function's first run.
+func (a *AWSInterface) pollLogs(ctx context.Context, query LogQuery, interval time.Duration, send func(LogBatch) bool) {
+	newest := query.Start
+	seen := map[string]time.Time{}
+	reportedMissing := false
+
+	for {
+		window := query
+		if lookback := newest.Add(-followLookback); lookback.After(query.Start) {
+			window.Start = lookback
+		}
+
+		var batch LogBatch
+		events, err := a.filterLogs(ctx, window, 0)
+		switch {
+		case ctx.Err() != nil:
+			return
+		case IsLogGroupNotFound(err):
+			if !reportedMissing {
+				reportedMissing = true
+				batch.Err = fmt.Errorf("log group %s does not exist yet, waiting for it", query.LogGroup)
+			}
+		case err != nil:
+			batch.Err = err
+		}
+
+		for _, event := range events {
+			if _, ok := seen[event.EventID]; ok {
+				continue
+			}
+			seen[event.EventID] = event.Timestamp
+			batch.Events = append(batch.Events, event)
+			if event.Timestamp.After(newest) {
+				newest = event.Timestamp
+			}
+		}
+		for id, timestamp := range seen {
+			if timestamp.Before(window.Start) {
+				delete(seen, id)
+			}
+		}
+
+		if len(batch.Events) > 0 || batch.Err != nil {
+			if !send(batch) {
+				return
+			}
+		}
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			return
+		}
+	}
+}
@@ -0,0 +1,377 @@
+package awsInterface
+
+import (
+	"aws_utility/pkg/configdir"
+	"aws_utility/pkg/logger"
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/aws/retry"
+	"github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/smithy-go/middleware"
+	"gopkg.in/yaml.v3"
+)
+
+// PolicyFileEnvVariable overrides the location of the policy file, which is
+// policy.yaml in aws_utility's user config directory by default.
+const PolicyFileEnvVariable = "AWS_UTILITY_POLICY_FILE"
+
+// defaultTimeout is the key in Policy.Timeouts for operations not listed.
+const defaultTimeout = "default"
+
+// Policy controls how AWS calls are retried, timed out and rate limited.
+// It applies to every client of every AWSInterface.
+type Policy struct {
+	// MaxAttempts includes the first attempt; 1 disables retries.
+	MaxAttempts int `yaml:"max_attempts"`
+	// Retries wait a random time up to InitialBackoff doubled for each
+	// attempt, capped at MaxBackoff, or longer if the service asks for it.
+	InitialBackoff time.Duration `yaml:"initial_backoff"`
+	MaxBackoff     time.Duration `yaml:"max_backoff"`
+	// RequestsPerSecond limits the calls made by the whole process, with
+	// bursts of up to Burst calls; zero means no limit.
+	RequestsPerSecond float64 `yaml:"requests_per_second"`
+	Burst             int     `yaml:"burst"`
+	// Timeouts bounds each call, retries included, by operation name such
+	// as Invoke, or "default" for the others. Zero means no timeout.
+	Timeouts map[string]time.Duration `yaml:"timeouts"`
+}
+
+func DefaultPolicy() Policy {
+	return Policy{
+		MaxAttempts:    3,
+		InitialBackoff: 200 * time.Millisecond,
+		MaxBackoff:     20 * time.Second,
+		Burst:          10,
+		Timeouts: map[string]time.Duration{
+			defaultTimeout:   time.Minute,
+			"RegisterClient": 10 * time.Second,
+			// Synchronous invocations may run for the function's maximum
+			// timeout of 15 minutes.
+			"Invoke":                   15*time.Minute + 30*time.Second,
+			"InvokeWithResponseStream": 0,
+			// Live Tail sessions last up to three hours.
+			"StartLiveTail":      0,
+			"UpdateFunctionCode": 10 * time.Minute,
+		},
+	}
+}
+
+func (p Policy) Validate() error {
+	switch {
+	case p.MaxAttempts < 1:
+		return fmt.Errorf("max_attempts must be at least 1")
+	case p.InitialBackoff < 0 || p.MaxBackoff < 0:
+		return fmt.Errorf("backoff cannot be negative")
+	case p.RequestsPerSecond < 0 || p.Burst < 0:
+		return fmt.Errorf("rate limit cannot be negative")
+	}
+	for operation, timeout := range p.Timeouts {
+		if timeout < 0 {
+			return fmt.Errorf("timeout of %s cannot be negative", operation)
+		}
+	}
+	return nil
+}
+
+// Timeout returns the time allowed for a call to operation.
+func (p Policy) Timeout(operation string) time.Duration {
+	if timeout, ok := p.Timeouts[operation]; ok {
+		return timeout
+	}
+	return p.Timeouts[defaultTimeout]
+}
+
+// Backoff returns how long to wait before attempt, the second one being
+// the first retry.
+func (p Policy) Backoff(attempt int, err error) time.Duration {
+	backoff := p.MaxBackoff
+	if shift := attempt - 2; shift < 30 {
+		if exp := p.InitialBackoff << shift; exp > 0 && exp < backoff {
+			backoff = exp
+		}
+	}
+	// Waiting at least half the backoff keeps retries from piling up
+	// immediately when the jitter comes out low.
+	delay := backoff / 2
+	if backoff > 1 {
+		delay += time.Duration(rand.Int63n(int64(backoff - delay)))
+	}
+	if _, retryAfter := IsThrottle(err); retryAfter > delay {
+		delay = retryAfter
+	}
+	return delay
+}
+
+// PolicyPath returns where the policy file is read from.
+func PolicyPath() (string, error) {
+	return configdir.Path(PolicyFileEnvVariable, "policy.yaml")
+}
+
+// LoadPolicy reads the policy file. Settings it leaves out keep their
+// defaults, and a missing file gives DefaultPolicy.
+func LoadPolicy() (Policy, error) {
+	policy := DefaultPolicy()
+	path, err := PolicyPath()
+	if err != nil {
+		return policy, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return policy, nil
+	}
+	if err != nil {
+		return policy, fmt.Errorf("failed to read policy: %v", err)
+	}
+	if err := yaml.Unmarshal(data, &policy); err != nil {
+		return DefaultPolicy(), fmt.Errorf("failed to parse policy %s: %v", path, err)
+	}
+	if err := policy.Validate(); err != nil {
+		return DefaultPolicy(), fmt.Errorf("invalid policy %s: %v", path, err)
+	}
+	return policy, nil
+}
+
+var (
+	policyMu     sync.Mutex
+	policyLoaded bool
+	activePolicy Policy
+	limiter      = &rateLimiter{}
+)
+
+// CurrentPolicy returns the policy in effect, loading it on first use. An
+// unreadable policy file is logged and the defaults are used instead.
+func CurrentPolicy() Policy {
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	if !policyLoaded {
+		policy, err := LoadPolicy()
+		if err != nil {
+			logger.Warn("Using the default retry policy:", err)
+		}
+		setPolicy(policy)
+	}
+	return activePolicy
+}
+
+// SetPolicy replaces the policy for clients created afterwards; the rate
+// limit applies to existing ones too.
+func SetPolicy(policy Policy) error {
+	if err := policy.Validate(); err != nil {
+		return err
+	}
+	policyMu.Lock()
+	defer policyMu.Unlock()
+	setPolicy(policy)
+	return nil
+}
+
+func setPolicy(policy Policy) {
+	activePolicy = policy
+	policyLoaded = true
+	limiter.configure(policy.RequestsPerSecond, policy.Burst)
+}
+
+// loadConfig loads the AWS configuration with the current policy's
+// retryer, rate limit and timeouts.
+func loadConfig(optFns ...func(*config.LoadOptions) error) (aws.Config, error) {
+	policy := CurrentPolicy()
+	optFns = append([]func(*config.LoadOptions) error{
+		config.WithRetryer(func() aws.Retryer { return newPolicyRetryer(policy) }),
+		config.WithAPIOptions([]func(*middleware.Stack) error{
+			func(stack *middleware.Stack) error { return addPolicyMiddleware(stack, policy) },
+		}),
+	}, optFns...)
+	return config.LoadDefaultConfig(context.TODO(), optFns...)
+}
+
+// Retry describes a failed attempt that is about to be retried.
+type Retry struct {
+	Service     string
+	Operation   string
+	Attempt     int
+	MaxAttempts int
+	Delay       time.Duration
+	Err         error
+}
+
+func (r Retry) String() string {
+	return fmt.Sprintf("Retrying %s %s in %s (attempt %d of %d) after: %v",
+		r.Service, r.Operation, r.Delay.Round(time.Millisecond), r.Attempt, r.MaxAttempts, r.Err)
+}
+
+var (
+	retryObserversMu  sync.Mutex
+	retryObservers    = map[int]func(Retry){}
+	nextRetryObserver int
+)
+
+// OnRetry calls fn, from the goroutine making the call, whenever an AWS call
+// is retried. The returned function stops the notifications.
+func OnRetry(fn func(Retry)) func() {
+	retryObserversMu.Lock()
+	defer retryObserversMu.Unlock()
+	id := nextRetryObserver
+	nextRetryObserver++
+	retryObservers[id] = fn
+	return func() {
+		retryObserversMu.Lock()
+		defer retryObserversMu.Unlock()
+		delete(retryObservers, id)
+	}
+}
+
+func notifyRetry(r Retry) {
+	logger.Warn(r.String())
+	retryObserversMu.Lock()
+	observers := make([]func(Retry), 0, len(retryObservers))
+	for _, fn := range retryObservers {
+		observers = append(observers, fn)
+	}
+	retryObserversMu.Unlock()
+	for _, fn := range observers {
+		fn(r)
+	}
+}
+
+// attemptsKey holds the number of attempts made by a call in its context.
+type attemptsKey struct{}
+
+// addPolicyMiddleware applies the operation's timeout and starts counting
+// its attempts. It runs after the operation name is registered.
+func addPolicyMiddleware(stack *middleware.Stack, policy Policy) error {
+	return stack.Initialize.Add(middleware.InitializeMiddlewareFunc("AWSUtilityPolicy",
+		func(ctx context.Context, in middleware.InitializeInput, next middleware.InitializeHandler) (middleware.InitializeOutput, middleware.Metadata, error) {
+			ctx = context.WithValue(ctx, attemptsKey{}, new(int))
+			if timeout := policy.Timeout(awsmiddleware.GetOperationName(ctx)); timeout > 0 {
+				var cancel context.CancelFunc
+				ctx, cancel = context.WithTimeout(ctx, timeout)
+				defer cancel()
+			}
+			return next.HandleInitialize(ctx, in)
+		}), middleware.After)
+}
+
+// policyRetryer retries the errors the SDK considers retryable, waiting in
+// GetRetryToken rather than RetryDelay so the wait can be reported with the
+// operation it is for.
+type policyRetryer struct {
+	policy   Policy
+	standard *retry.Standard
+}
+
+func newPolicyRetryer(policy Policy) *policyRetryer {
+	return &policyRetryer{
+		policy: policy,
+		standard: retry.NewStandard(func(o *retry.StandardOptions) {
+			o.MaxAttempts = policy.MaxAttempts
+		}),
+	}
+}
+
+func nopRelease(error) error { return nil }
+
+func (r *policyRetryer) IsErrorRetryable(err error) bool {
+	return r.standard.IsErrorRetryable(err)
+}
+
+func (r *policyRetryer) MaxAttempts() int {
+	return r.policy.MaxAttempts
+}
+
+func (r *policyRetryer) RetryDelay(int, error) (time.Duration, error) {
+	return 0, nil
+}
+
+func (r *policyRetryer) GetInitialToken() func(error) error {
+	return nopRelease
+}
+
+func (r *policyRetryer) GetAttemptToken(ctx context.Context) (func(error) error, error) {
+	if attempts, ok := ctx.Value(attemptsKey{}).(*int); ok {
+		*attempts++
+	}
+	if err := limiter.wait(ctx); err != nil {
+		return nil, &aws.RequestCanceledError{Err: err}
+	}
+	return nopRelease, nil
+}
+
+func (r *policyRetryer) GetRetryToken(ctx context.Context, opErr error) (func(error) error, error) {
+	attempt := 2
+	if attempts, ok := ctx.Value(attemptsKey{}).(*int); ok {
+		attempt = *attempts + 1
+	}
+	delay := r.policy.Backoff(attempt, opErr)
+	retry := Retry{
+		Service:     awsmiddleware.GetServiceID(ctx),
+		Operation:   awsmiddleware.GetOperationName(ctx),
+		Attempt:     attempt,
+		MaxAttempts: r.policy.MaxAttempts,
+		Delay:       delay,
+		Err:         opErr,
+	}
+	// Calls outside an operation, such as the credential chain's lookups,
+	// have nothing to report them by.
+	if retry.Service != "" || retry.Operation != "" {
+		notifyRetry(retry)
+	}
+	select {
+	case <-time.After(delay):
+		return nopRelease, nil
+	case <-ctx.Done():
+		return nil, &aws.RequestCanceledError{Err: ctx.Err()}
+	}
+}
+
+// rateLimiter is a token bucket shared by all AWS calls of the process.
+type rateLimiter struct {
+	mu     sync.Mutex
+	rate   float64
+	burst  float64
+	tokens float64
+	last   time.Time
+}
+
+func (l *rateLimiter) configure(rate float64, burst int) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rate = rate
+	l.burst = float64(max(burst, 1))
+	l.tokens = l.burst
+	l.last = time.Now()
+}
+
+// wait blocks until a call may be made or ctx is done.
+func (l *rateLimiter) wait(ctx context.Context) error {
+	for {
+		l.mu.Lock()
+		if l.rate <= 0 {
+			l.mu.Unlock()
+			return nil
+		}
+		now := time.Now()
+		l.tokens = min(l.burst, l.tokens+now.Sub(l.last).Seconds()*l.rate)
+		l.last = now
+		if l.tokens >= 1 {
+			l.tokens--
+			l.mu.Unlock()
+			return nil
+		}
+		wait := time.Duration((1 - l.tokens) / l.rate * float64(time.Second))
+		l.mu.Unlock()
+
+		select {
+		case <-time.After(wait):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package awsInterface
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func TestLoadPolicy(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "policy.yaml")
+	t.Setenv(PolicyFileEnvVariable, path)
+
+	policy, err := LoadPolicy()
+	if err != nil || policy.MaxAttempts != DefaultPolicy().MaxAttempts {
+		t.Fatalf("LoadPolicy() without a file = %+v, %v, want the defaults", policy, err)
+	}
+	if policy.RequestsPerSecond != 0 {
+		t.Errorf("default policy limits calls to %v per second, want no limit", policy.RequestsPerSecond)
+	}
+
+	if err := os.WriteFile(path, []byte("max_attempts: 5\nrequests_per_second: 2.5\ntimeouts:\n  Invoke: 1m\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	policy, err = LoadPolicy()
+	if err != nil {
+		t.Fatalf("LoadPolicy() error = %v", err)
+	}
+	if policy.MaxAttempts != 5 || policy.RequestsPerSecond != 2.5 || policy.Timeout("Invoke") != time.Minute {
+		t.Errorf("LoadPolicy() = %+v, want the file's settings", policy)
+	}
+	if policy.Burst != DefaultPolicy().Burst || policy.Timeout("ListFunctions") != time.Minute || policy.Timeout("InvokeWithResponseStream") != 0 {
+		t.Errorf("LoadPolicy() = %+v, want defaults for what the file leaves out", policy)
+	}
+
+	for content, want := range map[string]string{
+		"max_attempts: [":            "failed to parse policy",
+		"max_attempts: 0\n":          "max_attempts must be at least 1",
+		"max_backoff: -1s\n":         "backoff cannot be negative",
+		"timeouts:\n  Invoke: -1s\n": "timeout of Invoke",
+		"requests_per_second: -1\n":  "rate limit cannot be negative",
+	} {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+		policy, err := LoadPolicy()
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("LoadPolicy(%q) error = %v, want %q", content, err, want)
+		}
+		if policy.MaxAttempts != DefaultPolicy().MaxAttempts {
+			t.Errorf("LoadPolicy(%q) = %+v, want the defaults", content, policy)
+		}
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	policy := Policy{InitialBackoff: 100 * time.Millisecond, MaxBackoff: time.Second}
+	for i := 0; i < 20; i++ {
+		if got := policy.Backoff(2, nil); got < 50*time.Millisecond || got > 100*time.Millisecond {
+			t.Fatalf("first retry waits %s, want 50ms to 100ms", got)
+		}
+		if got := policy.Backoff(4, nil); got < 200*time.Millisecond || got > 400*time.Millisecond {
+			t.Fatalf("third retry waits %s, want 200ms to 400ms", got)
+		}
+		if got := policy.Backoff(60, nil); got < 500*time.Millisecond || got > time.Second {
+			t.Fatalf("late retry waits %s, want it capped at 1s", got)
+		}
+	}
+	throttle := &types.TooManyRequestsException{RetryAfterSeconds: aws.String("3")}
+	if got := policy.Backoff(2, throttle); got != 3*time.Second {
+		t.Errorf("Backoff() of a throttle = %s, want the 3s Lambda asked for", got)
+	}
+}
+
+// usePolicy makes clients created by the test use policy.
+func usePolicy(t *testing.T, policy Policy) {
+	t.Helper()
+	previous := CurrentPolicy()
+	if err := SetPolicy(policy); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { SetPolicy(previous) })
+}
+
+// throttlingLambda throttles the first throttles invocations.
+type throttlingLambda struct {
+	mu        sync.Mutex
+	throttles int
+	calls     int
+}
+
+func (f *throttlingLambda) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/federation/credentials" {
+		fmt.Fprint(w, `{"roleCredentials": {"accessKeyId": "a", "secretAccessKey": "b", "sessionToken": "c", "expiration": 0}}`)
+		return
+	}
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.calls++
+	if f.calls <= f.throttles {
+		w.Header().Set("X-Amzn-ErrorType", "TooManyRequestsException")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"Type": "User", "message": "Rate exceeded"}`)
+		return
+	}
+	fmt.Fprint(w, `{"ok": true}`)
+}
+
+func invokeThrough(t *testing.T, fake http.Handler, opts InvokeOptions) (*InvokeResult, error) {
+	t.Helper()
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+
+	base, err := NewAWSInterface("https://example.awsapps.com/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	awsInterface, err := base.ForRole("111111111111", "Admin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return awsInterface.InvokeLambda("orders", []byte(`{}`), opts)
+}
+
+func TestPolicyRetriesThrottledCalls(t *testing.T) {
+	usePolicy(t, Policy{MaxAttempts: 3, InitialBackoff: time.Millisecond, MaxBackoff: 10 * time.Millisecond})
+	var retries []Retry
+	defer OnRetry(func(r Retry) { retries = append(retries, r) })()
+
+	fake := &throttlingLambda{throttles: 2}
+	result, err := invokeThrough(t, fake, InvokeOptions{})
+	if err != nil || string(result.Payload) != `{"ok": true}` {
+		t.Fatalf("InvokeLambda() = %v, %v, want success on the third attempt", result, err)
+	}
+	if len(retries) != 2 || retries[0].Operation != "Invoke" || retries[1].Attempt != 3 || retries[1].MaxAttempts != 3 {
+		t.Errorf("retries = %+v", retries)
+	}
+
+	fake = &throttlingLambda{throttles: 5}
+	if _, err := invokeThrough(t, fake, InvokeOptions{}); !errors.As(err, new(*types.TooManyRequestsException)) {
+		t.Errorf("InvokeLambda() error = %v, want the throttle once attempts run out", err)
+	}
+	if fake.calls != 3 {
+		t.Errorf("Lambda was called %d times, want max_attempts", fake.calls)
+	}
+
+	fake = &throttlingLambda{throttles: 5}
+	if _, err := invokeThrough(t, fake, InvokeOptions{SingleAttempt: true}); err == nil || fake.calls != 1 {
+		t.Errorf("InvokeLambda() with a single attempt called Lambda %d times, error = %v", fake.calls, err)
+	}
+}
+
+func TestRateLimiterWaitsForTokens(t *testing.T) {
+	limiter := &rateLimiter{}
+	limiter.configure(20, 2)
+	start := time.Now()
+	for i := 0; i < 4; i++ {
+		if err := limiter.wait(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if elapsed := time.Since(start); elapsed < 90*time.Millisecond {
+		t.Errorf("4 calls at 20 per second with a burst of 2 took %s, want about 100ms", elapsed)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	limiter.configure(0.1, 1)
+	limiter.wait(ctx)
+	if err := limiter.wait(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("wait() error = %v, want the context's", err)
+	}
+}
@@ -0,0 +1,157 @@
+package awsInterface
+
+import (
+	"aws_utility/pkg/logger"
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/ec2"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+)
+
+// RegionsEnvVariable holds a comma-separated list of regions to search
+// instead of every enabled one.
+const RegionsEnvVariable = "AWS_UTILITY_REGIONS"
+
+// defaultRegions are the regions enabled in every account, used when the
+// enabled regions cannot be looked up.
+var defaultRegions = []string{
+	"ap-northeast-1", "ap-northeast-2", "ap-northeast-3", "ap-south-1", "ap-southeast-1", "ap-southeast-2",
+	"ca-central-1", "eu-central-1", "eu-north-1", "eu-west-1", "eu-west-2", "eu-west-3",
+	"sa-east-1", "us-east-1", "us-east-2", "us-west-1", "us-west-2",
+}
+
+func (a *AWSInterface) Region() string {
+	return a.cfg.Region
+}
+
+// ForRegion returns a copy of the interface whose Lambda calls go to region.
+func (a *AWSInterface) ForRegion(region string) *AWSInterface {
+	if region == "" || region == a.cfg.Region {
+		return a
+	}
+	target := *a
+	target.cfg = a.cfg.Copy()
+	target.cfg.Region = region
+	if a.lambdaClient != nil {
+		target.lambdaClient = lambda.NewFromConfig(target.cfg)
+	}
+	return &target
+}
+
+// ConfiguredRegions returns the regions listed in RegionsEnvVariable, if any.
+func ConfiguredRegions() []string {
+	return splitRegions(os.Getenv(RegionsEnvVariable))
+}
+
+func splitRegions(value string) []string {
+	var regions []string
+	for _, region := range strings.Split(value, ",") {
+		if region = strings.TrimSpace(region); region != "" {
+			regions = append(regions, region)
+		}
+	}
+	return regions
+}
+
+// Regions picks the regions to search: the given ones, else the configured
+// ones, else those enabled for the account.
+func (a *AWSInterface) Regions(regions []string) []string {
+	if len(regions) > 0 {
+		return regions
+	}
+	if configured := ConfiguredRegions(); len(configured) > 0 {
+		return configured
+	}
+	enabled, err := a.EnabledRegions()
+	if err != nil {
+		logger.Warn("Failed to look up enabled regions, using the default ones:", err)
+		return defaultRegions
+	}
+	return enabled
+}
+
+// EnabledRegions asks EC2 which regions are enabled for the account.
+func (a *AWSInterface) EnabledRegions() ([]string, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	output, err := ec2.NewFromConfig(a.cfg).DescribeRegions(context.TODO(), &ec2.DescribeRegionsInput{})
+	if err != nil {
+		return nil, fmt.Errorf("failed to describe regions: %v", err)
+	}
+	regions := make([]string, len(output.Regions))
+	for i, region := range output.Regions {
+		regions[i] = aws.ToString(region.RegionName)
+	}
+	sort.Strings(regions)
+	return regions, nil
+}
+
+// RegionErrors is returned alongside the functions that could be listed
+// when some regions failed.
+type RegionErrors map[string]error
+
+func (e RegionErrors) Error() string {
+	regions := make([]string, 0, len(e))
+	for region := range e {
+		regions = append(regions, region)
+	}
+	sort.Strings(regions)
+	messages := make([]string, len(regions))
+	for i, region := range regions {
+		messages[i] = fmt.Sprintf("%s: %v", region, e[region])
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ListLambdaFunctionsInRegions lists matching functions in every region
+// concurrently, sorted by region and name. Each function's Region tells
+// where to invoke it.
+func (a *AWSInterface) ListLambdaFunctionsInRegions(filter FunctionFilter, regions []string) ([]LambdaFunction, error) {
+	const concurrency = 8
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	var functions []LambdaFunction
+	failed := RegionErrors{}
+	semaphore := make(chan struct{}, concurrency)
+
+	for _, region := range regions {
+		wg.Add(1)
+		go func(region string) {
+			defer wg.Done()
+			semaphore <- struct{}{}
+			defer func() { <-semaphore }()
+
+			found, err := a.ForRegion(region).ListLambdaFunctions(filter)
+			mu.Lock()
+			defer mu.Unlock()
+			if err != nil {
+				failed[region] = err
+				return
+			}
+			functions = append(functions, found...)
+		}(region)
+	}
+	wg.Wait()
+
+	sort.Slice(functions, func(i, j int) bool {
+		if functions[i].Region != functions[j].Region {
+			return functions[i].Region < functions[j].Region
+		}
+		return functions[i].Name < functions[j].Name
+	})
+	if len(failed) == len(regions) && len(regions) > 0 {
+		return nil, fmt.Errorf("failed to list Lambda functions in any region: %v", failed)
+	}
+	if len(failed) > 0 {
+		return functions, failed
+	}
+	return functions, nil
+}
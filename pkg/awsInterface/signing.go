@@ -0,0 +1,43 @@
+package awsInterface
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	v4 "github.com/aws/aws-sdk-go-v2/aws/signer/v4"
+)
+
+// sendSigned signs req with the interface's credentials for service in
+// region and sends it. It is used for function URLs, which are called
+// directly rather than through an SDK client.
+func (a *AWSInterface) sendSigned(ctx context.Context, req *http.Request, body []byte, service, region string) (*http.Response, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	credentials, err := a.cfg.Credentials.Retrieve(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve credentials: %v", err)
+	}
+
+	sum := sha256.Sum256(body)
+	req.Body = http.NoBody
+	if len(body) > 0 {
+		req.Body = io.NopCloser(bytes.NewReader(body))
+		req.ContentLength = int64(len(body))
+	}
+	if err := v4.NewSigner().SignHTTP(ctx, credentials, req, hex.EncodeToString(sum[:]), service, region, time.Now()); err != nil {
+		return nil, fmt.Errorf("failed to sign request: %v", err)
+	}
+
+	client := a.cfg.HTTPClient
+	if client == nil {
+		client = http.DefaultClient
+	}
+	return client.Do(req)
+}
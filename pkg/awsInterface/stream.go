@@ -0,0 +1,138 @@
+package awsInterface
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsmiddleware "github.com/aws/aws-sdk-go-v2/aws/middleware"
+	"github.com/aws/aws-sdk-go-v2/service/lambda"
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// InvokeStream is a response-streaming invocation in progress. Events
+// delivers payload chunks as they arrive and is closed after the final
+// event, which carries either Complete or Err.
+type InvokeStream struct {
+	StatusCode      int32
+	ExecutedVersion string
+	RequestID       string
+	Events          <-chan StreamEvent
+}
+
+type StreamEvent struct {
+	Chunk    []byte
+	Complete *StreamComplete
+	Err      error
+}
+
+type StreamComplete struct {
+	ErrorCode    string
+	ErrorDetails string
+	LogResult    string
+}
+
+func (c *StreamComplete) Failed() bool {
+	return c.ErrorCode != ""
+}
+
+// InvokeLambdaStream starts a response-streaming invocation. Cancelling ctx
+// closes the response stream and Events, so a caller that stops reading
+// early must cancel it.
+func (a *AWSInterface) InvokeLambdaStream(ctx context.Context, functionName string, payload []byte, opts InvokeOptions) (*InvokeStream, error) {
+	if err := a.checkRole(); err != nil {
+		return nil, err
+	}
+	input := &lambda.InvokeWithResponseStreamInput{
+		FunctionName:   aws.String(functionName),
+		Payload:        payload,
+		InvocationType: types.ResponseStreamingInvocationTypeRequestResponse,
+	}
+	switch opts.InvocationType {
+	case "", types.InvocationTypeRequestResponse:
+	case types.InvocationTypeDryRun:
+		input.InvocationType = types.ResponseStreamingInvocationTypeDryRun
+	default:
+		return nil, fmt.Errorf("invocation type %s is not supported for response streaming", opts.InvocationType)
+	}
+	if opts.Qualifier != "" {
+		input.Qualifier = aws.String(opts.Qualifier)
+	}
+	if opts.TailLogs {
+		input.LogType = types.LogTypeTail
+	}
+
+	output, err := a.lambdaClient.InvokeWithResponseStream(ctx, input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to invoke Lambda function with response streaming: %v", err)
+	}
+
+	events := make(chan StreamEvent)
+	go func() {
+		defer close(events)
+		send := func(event StreamEvent) bool {
+			select {
+			case events <- event:
+				return true
+			case <-ctx.Done():
+				return false
+			}
+		}
+
+		stream := output.GetStream()
+		if stream == nil {
+			send(StreamEvent{Complete: &StreamComplete{}})
+			return
+		}
+		defer stream.Close()
+
+		for {
+			var event types.InvokeWithResponseStreamResponseEvent
+			var ok bool
+			select {
+			case event, ok = <-stream.Events():
+			case <-ctx.Done():
+				return
+			}
+			if !ok {
+				break
+			}
+			switch value := event.(type) {
+			case *types.InvokeWithResponseStreamResponseEventMemberPayloadChunk:
+				if !send(StreamEvent{Chunk: value.Value.Payload}) {
+					return
+				}
+			case *types.InvokeWithResponseStreamResponseEventMemberInvokeComplete:
+				complete := &StreamComplete{
+					ErrorCode:    aws.ToString(value.Value.ErrorCode),
+					ErrorDetails: aws.ToString(value.Value.ErrorDetails),
+				}
+				if value.Value.LogResult != nil {
+					logs, err := base64.StdEncoding.DecodeString(*value.Value.LogResult)
+					if err != nil {
+						send(StreamEvent{Err: fmt.Errorf("failed to decode Lambda log result: %v", err)})
+						return
+					}
+					complete.LogResult = string(logs)
+				}
+				send(StreamEvent{Complete: complete})
+				return
+			}
+		}
+
+		if err := stream.Err(); err != nil {
+			send(StreamEvent{Err: fmt.Errorf("response stream failed: %v", err)})
+			return
+		}
+		send(StreamEvent{Err: fmt.Errorf("response stream ended without an InvokeComplete event")})
+	}()
+
+	requestID, _ := awsmiddleware.GetRequestIDMetadata(output.ResultMetadata)
+	return &InvokeStream{
+		StatusCode:      output.StatusCode,
+		ExecutedVersion: aws.ToString(output.ExecutedVersion),
+		RequestID:       requestID,
+		Events:          events,
+	}, nil
+}
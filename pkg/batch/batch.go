@@ -0,0 +1,183 @@
+package batch
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/logger"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	PolicyContinue = "continue"
+	PolicyFailFast = "fail-fast"
+
+	StatusSuccess       = "success"
+	StatusFunctionError = "function_error"
+	StatusFailed        = "failed"
+	StatusSkipped       = "skipped"
+)
+
+// InvokeFunc performs one invocation; AWSInterface.InvokeLambda satisfies it.
+type InvokeFunc func(functionName string, payload []byte, opts awsinterface.InvokeOptions) (*awsinterface.InvokeResult, error)
+
+type Options struct {
+	Concurrency int
+	// MaxRetries is how many times a throttled invocation is retried, with
+	// the policy's backoff. Invocations are otherwise not retried.
+	MaxRetries int
+	Policy     string
+	Invoke     awsinterface.InvokeOptions
+	// OnResult, if set, is called as each job finishes.
+	OnResult func(Result)
+}
+
+type Result struct {
+	Index         int             `json:"index" yaml:"index"`
+	Job           string          `json:"job" yaml:"job"`
+	Function      string          `json:"function" yaml:"function"`
+	Qualifier     string          `json:"qualifier,omitempty" yaml:"qualifier,omitempty"`
+	Status        string          `json:"status" yaml:"status"`
+	Attempts      int             `json:"attempts" yaml:"attempts"`
+	DurationMs    int64           `json:"duration_ms" yaml:"duration_ms"`
+	StatusCode    int32           `json:"status_code,omitempty" yaml:"status_code,omitempty"`
+	FunctionError string          `json:"function_error,omitempty" yaml:"function_error,omitempty"`
+	Error         string          `json:"error,omitempty" yaml:"error,omitempty"`
+	Response      json.RawMessage `json:"response,omitempty" yaml:"-"`
+}
+
+func (r Result) Duration() time.Duration {
+	return time.Duration(r.DurationMs) * time.Millisecond
+}
+
+func (r Result) Succeeded() bool {
+	return r.Status == StatusSuccess
+}
+
+func CheckPolicy(policy string) error {
+	switch policy {
+	case PolicyContinue, PolicyFailFast:
+		return nil
+	}
+	return fmt.Errorf("invalid error policy %q, expected continue or fail-fast", policy)
+}
+
+// Run invokes every job with at most opts.Concurrency in flight and returns
+// one Result per job in job order. Under the fail-fast policy the first
+// failure stops new jobs from starting; they are reported as skipped.
+func Run(jobs []Job, invoke InvokeFunc, opts Options) []Result {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]Result, len(jobs))
+	var mu sync.Mutex
+	stopped := false
+
+	queue := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range queue {
+				mu.Lock()
+				skip := stopped
+				mu.Unlock()
+
+				var result Result
+				if skip {
+					result = newResult(index, jobs[index])
+					result.Status = StatusSkipped
+				} else {
+					result = runJob(index, jobs[index], invoke, opts)
+				}
+
+				mu.Lock()
+				results[index] = result
+				if !result.Succeeded() && result.Status != StatusSkipped && opts.Policy == PolicyFailFast {
+					stopped = true
+				}
+				if opts.OnResult != nil {
+					opts.OnResult(result)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+
+	for index := range jobs {
+		queue <- index
+	}
+	close(queue)
+	wg.Wait()
+	return results
+}
+
+func newResult(index int, job Job) Result {
+	function, qualifier := awsinterface.SplitQualifier(job.Function)
+	if job.Qualifier != "" {
+		qualifier = job.Qualifier
+	}
+	return Result{Index: index, Job: job.Label(), Function: function, Qualifier: qualifier}
+}
+
+func runJob(index int, job Job, invoke InvokeFunc, opts Options) (result Result) {
+	result = newResult(index, job)
+	started := time.Now()
+	defer func() { result.DurationMs = time.Since(started).Milliseconds() }()
+
+	payloadDoc := job.Payload
+	if payloadDoc == nil {
+		payloadDoc = map[string]interface{}{}
+	}
+	payloadJson, err := json.Marshal(payloadDoc)
+	if err != nil {
+		result.Status = StatusFailed
+		result.Error = fmt.Sprintf("failed to marshal payload: %v", err)
+		return result
+	}
+
+	invokeOptions := opts.Invoke
+	if result.Qualifier != "" {
+		invokeOptions.Qualifier = result.Qualifier
+	}
+	result.Qualifier = invokeOptions.Qualifier
+	// Throttles are retried below, so the SDK must not retry them as well.
+	invokeOptions.SingleAttempt = true
+	policy := awsinterface.CurrentPolicy()
+
+	var invokeResult *awsinterface.InvokeResult
+	for attempt := 1; ; attempt++ {
+		result.Attempts = attempt
+		invokeResult, err = invoke(result.Function, payloadJson, invokeOptions)
+		if throttled, _ := awsinterface.IsThrottle(err); !throttled || attempt > opts.MaxRetries {
+			break
+		}
+		delay := policy.Backoff(attempt+1, err)
+		logger.Warn(fmt.Sprintf("Job %s throttled, retrying in %s (attempt %d of %d)", result.Job, delay, attempt+1, opts.MaxRetries+1))
+		time.Sleep(delay)
+	}
+
+	switch {
+	case err != nil:
+		result.Status = StatusFailed
+		result.Error = err.Error()
+	case invokeResult.Failed():
+		result.Status = StatusFunctionError
+		result.FunctionError = invokeResult.FunctionError
+	default:
+		result.Status = StatusSuccess
+	}
+	if invokeResult != nil {
+		result.StatusCode = invokeResult.StatusCode
+		if json.Valid(invokeResult.Payload) {
+			result.Response = invokeResult.Payload
+		} else if len(invokeResult.Payload) > 0 {
+			result.Response, _ = json.Marshal(string(invokeResult.Payload))
+		}
+	}
+	return result
+}
@@ -0,0 +1,215 @@
+package batch
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+func writeJobs(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func payloads(t *testing.T, jobs []Job) []string {
+	t.Helper()
+	var encoded []string
+	for _, job := range jobs {
+		payloadJson, err := json.Marshal(job.Payload)
+		if err != nil {
+			t.Fatal(err)
+		}
+		encoded = append(encoded, string(payloadJson))
+	}
+	return encoded
+}
+
+func TestLoadJobsJSON(t *testing.T) {
+	jobs, err := LoadJobs(writeJobs(t, "jobs.json", `[{"name": "a", "function": "fn:live", "payload": {"n": 1}}, {"function": "fn", "qualifier": "2"}]`))
+	if err != nil {
+		t.Fatalf("LoadJobs() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].Label() != "a" || jobs[1].Label() != "fn:2" {
+		t.Errorf("LoadJobs() = %+v", jobs)
+	}
+	if got := strings.Join(payloads(t, jobs), " "); got != `{"n":1} null` {
+		t.Errorf("LoadJobs() payloads = %s", got)
+	}
+}
+
+func TestLoadJobsNDJSON(t *testing.T) {
+	jobs, err := LoadJobs(writeJobs(t, "jobs.jsonl", "{\"function\": \"fn\", \"payload\": {\"n\": 1}}\n\n{\"function\": \"other\"}\n"))
+	if err != nil {
+		t.Fatalf("LoadJobs() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].Function != "fn" || jobs[1].Function != "other" {
+		t.Errorf("LoadJobs() = %+v", jobs)
+	}
+
+	_, err = LoadJobs(writeJobs(t, "jobs.ndjson", "{\"function\": \"fn\"}\n{\n"))
+	if err == nil || !strings.Contains(err.Error(), "line 2") {
+		t.Errorf("LoadJobs() error = %v, want the bad line", err)
+	}
+}
+
+func TestLoadJobsCSV(t *testing.T) {
+	jobs, err := LoadJobs(writeJobs(t, "jobs.csv", "function,name,payload,service.name,replicas\nfn,first,\"{\"\"debug\"\": true}\",api,3\nfn,,,web,\n"))
+	if err != nil {
+		t.Fatalf("LoadJobs() error = %v", err)
+	}
+	if len(jobs) != 2 || jobs[0].Label() != "first" || jobs[1].Label() != "fn" {
+		t.Errorf("LoadJobs() = %+v", jobs)
+	}
+	want := []string{`{"debug":true,"replicas":3,"service":{"name":"api"}}`, `{"service":{"name":"web"}}`}
+	if got := payloads(t, jobs); strings.Join(got, " ") != strings.Join(want, " ") {
+		t.Errorf("LoadJobs() payloads = %v, want %v", got, want)
+	}
+
+	_, err = LoadJobs(writeJobs(t, "jobs.csv", "function,payload\nfn,{\n"))
+	if err == nil || !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("LoadJobs() error = %v, want the bad row", err)
+	}
+}
+
+func TestLoadJobsKeepsLargeNumbers(t *testing.T) {
+	for name, content := range map[string]string{
+		"jobs.json":  `[{"function": "fn", "payload": {"order_id": 12345678901234567890, "amount": 1.10}}]`,
+		"jobs.jsonl": `{"function": "fn", "payload": {"order_id": 12345678901234567890, "amount": 1.10}}`,
+		"jobs.csv":   "function,order_id,amount\nfn,12345678901234567890,1.10\n",
+	} {
+		jobs, err := LoadJobs(writeJobs(t, name, content))
+		if err != nil {
+			t.Fatalf("LoadJobs(%s) error = %v", name, err)
+		}
+		if got, want := payloads(t, jobs)[0], `{"amount":1.10,"order_id":12345678901234567890}`; got != want {
+			t.Errorf("LoadJobs(%s) payload = %s, want %s", name, got, want)
+		}
+	}
+}
+
+func TestLoadJobsRejects(t *testing.T) {
+	for name, content := range map[string]string{
+		"jobs.txt":    "fn",
+		"jobs.json":   `[]`,
+		"jobs.jsonl":  `{"name": "x"}`,
+		"jobs.ndjson": `{"function": "fn"} {"function": "other"}`,
+	} {
+		if _, err := LoadJobs(writeJobs(t, name, content)); err == nil {
+			t.Errorf("LoadJobs(%s with %q) error = nil, want an error", name, content)
+		}
+	}
+}
+
+// fakeInvoke answers like Lambda: functions named "fails" fail, "error"
+// reports a function error and everything else succeeds.
+func fakeInvoke(functionName string, payload []byte, opts awsinterface.InvokeOptions) (*awsinterface.InvokeResult, error) {
+	switch functionName {
+	case "fails":
+		return nil, errors.New("boom")
+	case "error":
+		return &awsinterface.InvokeResult{StatusCode: 200, FunctionError: "Unhandled", Payload: []byte(`{"errorMessage":"x"}`)}, nil
+	}
+	return &awsinterface.InvokeResult{StatusCode: 200, Payload: []byte(`"done"`)}, nil
+}
+
+func statuses(results []Result) string {
+	var s []string
+	for _, result := range results {
+		s = append(s, result.Status)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestRunContinuesAfterFailures(t *testing.T) {
+	jobs := []Job{{Function: "ok"}, {Function: "fails"}, {Function: "error"}, {Function: "ok:live"}}
+	var reported int
+	results := Run(jobs, fakeInvoke, Options{Concurrency: 2, Policy: PolicyContinue, OnResult: func(Result) { reported++ }})
+
+	if got, want := statuses(results), "success,failed,function_error,success"; got != want {
+		t.Errorf("Run() statuses = %s, want %s", got, want)
+	}
+	if reported != len(jobs) {
+		t.Errorf("OnResult called %d times, want %d", reported, len(jobs))
+	}
+	if results[3].Qualifier != "live" || string(results[0].Response) != `"done"` {
+		t.Errorf("Run() results = %+v", results)
+	}
+}
+
+func TestRunFailFastSkipsRemainingJobs(t *testing.T) {
+	jobs := []Job{{Function: "ok"}, {Function: "fails"}, {Function: "ok"}, {Function: "ok"}}
+	results := Run(jobs, fakeInvoke, Options{Concurrency: 1, Policy: PolicyFailFast})
+	if got, want := statuses(results), "success,failed,skipped,skipped"; got != want {
+		t.Errorf("Run() statuses = %s, want %s", got, want)
+	}
+}
+
+func TestRunRetriesThrottles(t *testing.T) {
+	var mu sync.Mutex
+	calls := map[string]int{}
+	invoke := func(functionName string, payload []byte, opts awsinterface.InvokeOptions) (*awsinterface.InvokeResult, error) {
+		if !opts.SingleAttempt {
+			t.Errorf("%s was invoked with the SDK's retries on top of the batch's", functionName)
+		}
+		mu.Lock()
+		calls[functionName]++
+		call := calls[functionName]
+		mu.Unlock()
+		if functionName == "always" || call == 1 {
+			return nil, &types.TooManyRequestsException{}
+		}
+		return fakeInvoke(functionName, payload, opts)
+	}
+
+	results := Run([]Job{{Function: "once"}, {Function: "always"}}, invoke, Options{Concurrency: 2, MaxRetries: 1})
+	if got, want := statuses(results), "success,failed"; got != want {
+		t.Errorf("Run() statuses = %s, want %s", got, want)
+	}
+	if results[0].Attempts != 2 || results[1].Attempts != 2 {
+		t.Errorf("Run() attempts = %d and %d, want 2 each", results[0].Attempts, results[1].Attempts)
+	}
+}
+
+func TestRunLimitsConcurrency(t *testing.T) {
+	var mu sync.Mutex
+	inFlight, peak := 0, 0
+	release := make(chan struct{})
+	invoke := func(functionName string, payload []byte, opts awsinterface.InvokeOptions) (*awsinterface.InvokeResult, error) {
+		mu.Lock()
+		inFlight++
+		if inFlight > peak {
+			peak = inFlight
+		}
+		mu.Unlock()
+		<-release
+		mu.Lock()
+		inFlight--
+		mu.Unlock()
+		return fakeInvoke(functionName, payload, opts)
+	}
+
+	jobs := make([]Job, 10)
+	for i := range jobs {
+		jobs[i] = Job{Function: "ok"}
+	}
+	done := make(chan []Result)
+	go func() { done <- Run(jobs, invoke, Options{Concurrency: 3}) }()
+	for range jobs {
+		release <- struct{}{}
+	}
+	<-done
+	if peak > 3 {
+		t.Errorf("%d invocations ran at once, want at most 3", peak)
+	}
+}
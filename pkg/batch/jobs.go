@@ -0,0 +1,169 @@
+package batch
+
+import (
+	"aws_utility/pkg/payload"
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Job is one invocation in a batch. Function may carry a ":qualifier"
+// suffix instead of setting Qualifier.
+type Job struct {
+	Name      string      `json:"name,omitempty" yaml:"name,omitempty"`
+	Function  string      `json:"function" yaml:"function"`
+	Qualifier string      `json:"qualifier,omitempty" yaml:"qualifier,omitempty"`
+	Payload   interface{} `json:"payload,omitempty" yaml:"payload,omitempty"`
+}
+
+// Label identifies the job in summaries: its name if set, otherwise the
+// function it invokes.
+func (j Job) Label() string {
+	if j.Name != "" {
+		return j.Name
+	}
+	if j.Qualifier != "" {
+		return j.Function + ":" + j.Qualifier
+	}
+	return j.Function
+}
+
+// LoadJobs reads jobs from a file whose extension selects the format:
+//
+//	.json            an array of jobs
+//	.ndjson, .jsonl  one job object per line
+//	.csv             a header row naming function, and optionally name,
+//	                 qualifier and payload (JSON text); every other column
+//	                 sets that payload field, e.g. a "service" column
+//
+// "-" reads NDJSON from stdin.
+func LoadJobs(path string) ([]Job, error) {
+	var raw []byte
+	var err error
+	if path == "-" {
+		raw, err = io.ReadAll(os.Stdin)
+	} else {
+		raw, err = os.ReadFile(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read jobs file: %v", err)
+	}
+
+	var jobs []Job
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".json":
+		if err := decode(raw, &jobs); err != nil {
+			return nil, fmt.Errorf("failed to parse jobs file %s: %v", path, err)
+		}
+	case ".csv":
+		jobs, err = parseCSV(raw)
+	case ".ndjson", ".jsonl", "":
+		jobs, err = parseNDJSON(raw)
+	default:
+		return nil, fmt.Errorf("unsupported jobs file %s, expected .json, .ndjson, .jsonl or .csv", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse jobs file %s: %v", path, err)
+	}
+
+	if len(jobs) == 0 {
+		return nil, fmt.Errorf("jobs file %s contains no jobs", path)
+	}
+	for i, job := range jobs {
+		if job.Function == "" {
+			return nil, fmt.Errorf("job %d in %s has no function", i+1, path)
+		}
+	}
+	return jobs, nil
+}
+
+// decode is json.Unmarshal keeping payload numbers as json.Number, so that
+// large integers such as IDs are sent exactly as written.
+func decode(raw []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	if err := decoder.Decode(v); err != nil {
+		return err
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return fmt.Errorf("unexpected data after the document")
+	}
+	return nil
+}
+
+func parseNDJSON(raw []byte) ([]Job, error) {
+	var jobs []Job
+	scanner := bufio.NewScanner(bytes.NewReader(raw))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		var job Job
+		if err := decode(line, &job); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, scanner.Err()
+}
+
+func parseCSV(raw []byte) ([]Job, error) {
+	records, err := csv.NewReader(bytes.NewReader(raw)).ReadAll()
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := records[0]
+	var jobs []Job
+	for rowIndex, record := range records[1:] {
+		var job Job
+		var doc interface{}
+		for i, column := range header {
+			value := record[i]
+			switch column {
+			case "name":
+				job.Name = value
+			case "function":
+				job.Function = value
+			case "qualifier":
+				job.Qualifier = value
+			case "payload":
+				if value == "" {
+					continue
+				}
+				parsed, err := payload.Parse([]byte(value))
+				if err != nil {
+					return nil, fmt.Errorf("row %d: %v", rowIndex+2, err)
+				}
+				doc = parsed
+			}
+		}
+		for i, column := range header {
+			switch column {
+			case "name", "function", "qualifier", "payload":
+				continue
+			}
+			if record[i] == "" {
+				continue
+			}
+			doc, err = payload.SetPath(doc, column, payload.ParseValue(record[i]))
+			if err != nil {
+				return nil, fmt.Errorf("row %d: %v", rowIndex+2, err)
+			}
+		}
+		job.Payload = doc
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
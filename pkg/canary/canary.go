@@ -0,0 +1,307 @@
+package canary
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/configdir"
+	"aws_utility/pkg/history"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	DirEnvVariable = "AWS_UTILITY_CANARY_DIR"
+
+	StatusRunning    = "running"
+	StatusCompleted  = "completed"
+	StatusRolledBack = "rolled_back"
+	StatusFailed     = "failed"
+)
+
+// Plan describes a canary release: the alias moves from FromVersion to
+// ToVersion through Steps, each the share of traffic (0-1] sent to
+// ToVersion. The last step should be 1.
+type Plan struct {
+	Function    string          `json:"function"`
+	Alias       string          `json:"alias"`
+	FromVersion string          `json:"from_version"`
+	ToVersion   string          `json:"to_version"`
+	Steps       []float64       `json:"steps"`
+	Interval    Duration        `json:"interval"`
+	Payload     json.RawMessage `json:"payload"`
+	// ChecksPerStep health-check invocations run after each shift; the
+	// release rolls back when more than MaxErrorRate of them fail.
+	ChecksPerStep int     `json:"checks_per_step"`
+	MaxErrorRate  float64 `json:"max_error_rate"`
+}
+
+type StepResult struct {
+	Weight      float64   `json:"weight"`
+	Invocations int       `json:"invocations"`
+	Errors      int       `json:"errors"`
+	LastError   string    `json:"last_error,omitempty"`
+	At          time.Time `json:"at"`
+}
+
+func (r StepResult) ErrorRate() float64 {
+	if r.Invocations == 0 {
+		return 0
+	}
+	return float64(r.Errors) / float64(r.Invocations)
+}
+
+// State is what the state file holds. CurrentStep is the index of the next
+// step to apply, so a resumed run picks up where the previous one stopped.
+type State struct {
+	Plan        Plan         `json:"plan"`
+	Status      string       `json:"status"`
+	CurrentStep int          `json:"current_step"`
+	Results     []StepResult `json:"results"`
+	Reason      string       `json:"reason,omitempty"`
+	StartedAt   time.Time    `json:"started_at"`
+	UpdatedAt   time.Time    `json:"updated_at"`
+}
+
+// Duration marshals as a Go duration string such as "2m30s".
+type Duration time.Duration
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func (d *Duration) UnmarshalJSON(raw []byte) error {
+	var text string
+	if err := json.Unmarshal(raw, &text); err != nil {
+		return err
+	}
+	parsed, err := time.ParseDuration(text)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+// ParseSteps reads percentages such as "10,25,50,100".
+func ParseSteps(value string) ([]float64, error) {
+	var steps []float64
+	previous := 0.0
+	for _, field := range strings.Split(value, ",") {
+		percent, err := strconv.ParseFloat(strings.TrimSpace(field), 64)
+		if err != nil || percent <= previous || percent > 100 {
+			return nil, fmt.Errorf("invalid steps %q, expected increasing percentages up to 100", value)
+		}
+		previous = percent
+		steps = append(steps, percent/100)
+	}
+	if previous != 100 {
+		steps = append(steps, 1)
+	}
+	return steps, nil
+}
+
+func Dir() (string, error) {
+	return configdir.Path(DirEnvVariable, "canary")
+}
+
+// DefaultStatePath is where the state of a release of function's alias is
+// kept unless another path is given.
+func DefaultStatePath(function, alias string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fmt.Sprintf("%s-%s.json", function, alias)), nil
+}
+
+func LoadState(path string) (*State, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read canary state: %v", err)
+	}
+	var state State
+	if err := json.Unmarshal(raw, &state); err != nil {
+		return nil, fmt.Errorf("failed to parse canary state %s: %v", path, err)
+	}
+	return &state, nil
+}
+
+func (s *State) Save(path string) error {
+	s.UpdatedAt = time.Now()
+	raw, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode canary state: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return fmt.Errorf("failed to create canary state directory: %v", err)
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, raw, 0o600); err != nil {
+		return fmt.Errorf("failed to write canary state: %v", err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// NewState validates plan and starts a fresh release.
+func NewState(plan Plan) (*State, error) {
+	if plan.Function == "" || plan.Alias == "" || plan.FromVersion == "" || plan.ToVersion == "" {
+		return nil, fmt.Errorf("a canary plan needs a function, an alias and both versions")
+	}
+	if plan.FromVersion == plan.ToVersion {
+		return nil, fmt.Errorf("alias %s already points at version %s", plan.Alias, plan.ToVersion)
+	}
+	if len(plan.Steps) == 0 || plan.Steps[len(plan.Steps)-1] != 1 {
+		return nil, fmt.Errorf("the last canary step must send 100%% of traffic to the new version")
+	}
+	if plan.ChecksPerStep < 1 {
+		plan.ChecksPerStep = 1
+	}
+	if len(plan.Payload) == 0 {
+		plan.Payload = json.RawMessage("{}")
+	}
+	now := time.Now()
+	return &State{Plan: plan, Status: StatusRunning, StartedAt: now, UpdatedAt: now}, nil
+}
+
+// Event reports progress; State is a snapshot taken when the event fired.
+type Event struct {
+	Message string
+	State   State
+}
+
+// Run applies the remaining steps of state, saving it to statePath after
+// every change. If a step cannot be applied or its health checks fail too
+// often the alias is pointed back at FromVersion and an error is returned.
+// When ctx is cancelled Run saves the state, still running so the release
+// can be resumed, and returns ctx.Err().
+func Run(ctx context.Context, awsInterface *awsinterface.AWSInterface, state *State, statePath string, progress func(Event)) error {
+	plan := state.Plan
+	notify := func(format string, args ...interface{}) {
+		if progress != nil {
+			snapshot := *state
+			snapshot.Results = append([]StepResult(nil), state.Results...)
+			progress(Event{Message: fmt.Sprintf(format, args...), State: snapshot})
+		}
+	}
+
+	if state.Status != StatusRunning {
+		return fmt.Errorf("canary release of %s:%s is %s, nothing to resume", plan.Function, plan.Alias, state.Status)
+	}
+
+	fail := func(err error) error {
+		state.Status = StatusFailed
+		state.Reason = err.Error()
+		if saveErr := state.Save(statePath); saveErr != nil {
+			return fmt.Errorf("%v (and %v)", err, saveErr)
+		}
+		notify("Failed: %v", err)
+		return err
+	}
+
+	// rollBack points the alias back at FromVersion after a failed step.
+	rollBack := func(reason string) error {
+		notify("Rolling back %s to version %s", plan.Alias, plan.FromVersion)
+		if err := awsInterface.SetAliasRouting(plan.Function, plan.Alias, plan.FromVersion, nil); err != nil {
+			return fail(fmt.Errorf("%s, and rollback failed: %v", reason, err))
+		}
+		state.Status = StatusRolledBack
+		state.Reason = reason
+		if err := state.Save(statePath); err != nil {
+			return err
+		}
+		notify("Rolled back: %s", reason)
+		return fmt.Errorf("canary release rolled back: %s", reason)
+	}
+
+	// stop saves the state as it is, so a resumed run repeats the step that
+	// was interrupted.
+	stop := func() error {
+		if err := state.Save(statePath); err != nil {
+			return err
+		}
+		notify("Stopped, the release can be resumed")
+		return ctx.Err()
+	}
+
+	for state.CurrentStep < len(plan.Steps) {
+		if ctx.Err() != nil {
+			return stop()
+		}
+		weight := plan.Steps[state.CurrentStep]
+		var err error
+		if weight >= 1 {
+			notify("Moving %s fully to version %s", plan.Alias, plan.ToVersion)
+			err = awsInterface.SetAliasRouting(plan.Function, plan.Alias, plan.ToVersion, nil)
+		} else {
+			notify("Routing %g%% of %s to version %s", weight*100, plan.Alias, plan.ToVersion)
+			err = awsInterface.SetAliasRouting(plan.Function, plan.Alias, plan.FromVersion, map[string]float64{plan.ToVersion: weight})
+		}
+		if err != nil {
+			// Earlier steps may have left traffic on the new version.
+			return rollBack(fmt.Sprintf("failed to route %g%% to version %s: %v", weight*100, plan.ToVersion, err))
+		}
+
+		result := healthCheck(ctx, awsInterface, plan, weight)
+		if ctx.Err() != nil {
+			return stop()
+		}
+		state.Results = append(state.Results, result)
+		notify("Health check at %g%%: %d of %d invocations failed", weight*100, result.Errors, result.Invocations)
+
+		if result.ErrorRate() > plan.MaxErrorRate {
+			reason := fmt.Sprintf("error rate %.0f%% at %g%% exceeds %.0f%%", result.ErrorRate()*100, weight*100, plan.MaxErrorRate*100)
+			if result.LastError != "" {
+				reason += ": " + result.LastError
+			}
+			return rollBack(reason)
+		}
+
+		state.CurrentStep++
+		if err := state.Save(statePath); err != nil {
+			return err
+		}
+
+		if state.CurrentStep < len(plan.Steps) && plan.Interval > 0 {
+			notify("Waiting %s before the next step", time.Duration(plan.Interval))
+			select {
+			case <-time.After(time.Duration(plan.Interval)):
+			case <-ctx.Done():
+				return stop()
+			}
+		}
+	}
+
+	state.Status = StatusCompleted
+	if err := state.Save(statePath); err != nil {
+		return err
+	}
+	notify("Release complete: %s now points at version %s", plan.Alias, plan.ToVersion)
+	return nil
+}
+
+// healthCheck invokes the new version itself rather than the alias, whose
+// routing would send most checks to the old version at low weights. It stops
+// early when ctx is cancelled.
+func healthCheck(ctx context.Context, awsInterface *awsinterface.AWSInterface, plan Plan, weight float64) StepResult {
+	result := StepResult{Weight: weight, At: time.Now()}
+	for i := 0; i < plan.ChecksPerStep && ctx.Err() == nil; i++ {
+		invokeResult, err := history.InvokeLambda(awsInterface, plan.Function, plan.Payload, awsinterface.InvokeOptions{
+			Qualifier: plan.ToVersion,
+		})
+		result.Invocations++
+		switch {
+		case err != nil:
+			result.Errors++
+			result.LastError = err.Error()
+		case invokeResult.Failed():
+			result.Errors++
+			result.LastError = fmt.Sprintf("version %s: %s", invokeResult.ExecutedVersion, invokeResult.FunctionError)
+		}
+	}
+	return result
+}
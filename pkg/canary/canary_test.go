@@ -0,0 +1,278 @@
+package canary
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/history"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestParseSteps(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []float64
+		wantErr bool
+	}{
+		{value: "10,25,50,100", want: []float64{0.1, 0.25, 0.5, 1}},
+		{value: "5, 50", want: []float64{0.05, 0.5, 1}},
+		{value: "100", want: []float64{1}},
+		{value: "50,25", wantErr: true},
+		{value: "10,10", wantErr: true},
+		{value: "0", wantErr: true},
+		{value: "150", wantErr: true},
+		{value: "ten", wantErr: true},
+		{value: "", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseSteps(test.value)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseSteps(%q) error = %v", test.value, err)
+			continue
+		}
+		if !test.wantErr && !reflect.DeepEqual(got, test.want) {
+			t.Errorf("ParseSteps(%q) = %v, want %v", test.value, got, test.want)
+		}
+	}
+}
+
+func TestNewStateRejectsInvalidPlans(t *testing.T) {
+	tests := []struct {
+		plan    Plan
+		wantErr string
+	}{
+		{plan: Plan{Function: "fn", FromVersion: "1", ToVersion: "2", Steps: []float64{1}}, wantErr: "needs a function"},
+		{plan: Plan{Function: "fn", Alias: "live", FromVersion: "1", ToVersion: "1", Steps: []float64{1}}, wantErr: "already points at"},
+		{plan: Plan{Function: "fn", Alias: "live", FromVersion: "1", ToVersion: "2", Steps: []float64{0.1, 0.5}}, wantErr: "last canary step"},
+		{plan: Plan{Function: "fn", Alias: "live", FromVersion: "1", ToVersion: "2"}, wantErr: "last canary step"},
+	}
+	for _, test := range tests {
+		if _, err := NewState(test.plan); err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("NewState(%+v) error = %v, want %q", test.plan, err, test.wantErr)
+		}
+	}
+}
+
+func TestStateSurvivesSaveAndLoad(t *testing.T) {
+	t.Setenv(DirEnvVariable, t.TempDir())
+	state, err := NewState(Plan{Function: "fn", Alias: "live", FromVersion: "1", ToVersion: "2", Steps: []float64{0.5, 1}, Interval: Duration(90 * time.Second)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state.Status != StatusRunning || state.Plan.ChecksPerStep != 1 || string(state.Plan.Payload) != "{}" {
+		t.Errorf("NewState() = %+v, want a running release with defaults", state)
+	}
+	state.CurrentStep = 1
+	state.Results = []StepResult{{Weight: 0.5, Invocations: 4, Errors: 1, LastError: "boom"}}
+
+	path, err := DefaultStatePath("fn", "live")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := state.Save(path); err != nil {
+		t.Fatalf("Save() error = %v", err)
+	}
+	loaded, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error = %v", err)
+	}
+	if loaded.Plan.Interval != Duration(90*time.Second) || loaded.CurrentStep != 1 || loaded.Results[0].ErrorRate() != 0.25 {
+		t.Errorf("LoadState() = %+v", loaded)
+	}
+
+	var d Duration
+	if err := json.Unmarshal([]byte(`"soon"`), &d); err == nil {
+		t.Error("a state file with an invalid interval loaded without an error")
+	}
+}
+
+// fakeLambda records the alias routing updates a release makes and the
+// versions it invokes, failing invocations while failing is set and
+// rejecting the routing update named by reject.
+type fakeLambda struct {
+	mu      sync.Mutex
+	failing bool
+	reject  string
+	routing []string
+	invoked []string
+}
+
+func (f *fakeLambda) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch {
+	case r.URL.Path == "/federation/credentials":
+		fmt.Fprint(w, `{"roleCredentials": {"accessKeyId": "a", "secretAccessKey": "b", "sessionToken": "c", "expiration": 0}}`)
+	case strings.Contains(r.URL.Path, "/aliases/"):
+		var input struct {
+			FunctionVersion string
+			RoutingConfig   struct{ AdditionalVersionWeights map[string]float64 }
+		}
+		json.NewDecoder(r.Body).Decode(&input)
+		route := input.FunctionVersion
+		for version, weight := range input.RoutingConfig.AdditionalVersionWeights {
+			route += fmt.Sprintf("+%s@%g", version, weight)
+		}
+		f.routing = append(f.routing, route)
+		if route == f.reject {
+			w.Header().Set("X-Amzn-ErrorType", "InvalidParameterValueException")
+			w.WriteHeader(http.StatusBadRequest)
+			fmt.Fprint(w, `{"message": "invalid routing"}`)
+			return
+		}
+		fmt.Fprint(w, `{}`)
+	default:
+		f.invoked = append(f.invoked, r.URL.Query().Get("Qualifier"))
+		if f.failing {
+			w.Header().Set("X-Amz-Function-Error", "Unhandled")
+		}
+		fmt.Fprint(w, `{}`)
+	}
+}
+
+func connect(t *testing.T, fake http.Handler) *awsinterface.AWSInterface {
+	t.Helper()
+	t.Setenv(history.DirEnvVariable, t.TempDir())
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+
+	base, err := awsinterface.NewAWSInterface("https://example.awsapps.com/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	awsInterface, err := base.ForRole("111111111111", "Deployer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return awsInterface
+}
+
+func TestRunShiftsTrafficStepByStep(t *testing.T) {
+	fake := &fakeLambda{}
+	awsInterface := connect(t, fake)
+	state, err := NewState(Plan{Function: "fn", Alias: "live", FromVersion: "1", ToVersion: "2", Steps: []float64{0.1, 0.5, 1}, ChecksPerStep: 2})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fn-live.json")
+
+	var events []string
+	if err := Run(context.Background(), awsInterface, state, path, func(event Event) { events = append(events, event.Message) }); err != nil {
+		t.Fatalf("Run() error = %v", err)
+	}
+	if want := []string{"1+2@0.1", "1+2@0.5", "2"}; !reflect.DeepEqual(fake.routing, want) {
+		t.Errorf("alias routing = %v, want %v", fake.routing, want)
+	}
+	if want := []string{"2", "2", "2", "2", "2", "2"}; !reflect.DeepEqual(fake.invoked, want) {
+		t.Errorf("health checks invoked %v, want the new version each time", fake.invoked)
+	}
+	saved, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != StatusCompleted || saved.CurrentStep != 3 || len(saved.Results) != 3 || saved.Results[0].Invocations != 2 {
+		t.Errorf("saved state = %+v", saved)
+	}
+	if last := events[len(events)-1]; !strings.HasPrefix(last, "Release complete") {
+		t.Errorf("last event = %q", last)
+	}
+
+	if err := Run(context.Background(), awsInterface, saved, path, nil); err == nil || !strings.Contains(err.Error(), "nothing to resume") {
+		t.Errorf("Run() of a completed release error = %v", err)
+	}
+}
+
+func TestRunRollsBackFailingVersion(t *testing.T) {
+	fake := &fakeLambda{failing: true}
+	awsInterface := connect(t, fake)
+	state, err := NewState(Plan{Function: "fn", Alias: "live", FromVersion: "1", ToVersion: "2", Steps: []float64{0.1, 1}, MaxErrorRate: 0.5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fn-live.json")
+
+	err = Run(context.Background(), awsInterface, state, path, nil)
+	if err == nil || !strings.Contains(err.Error(), "rolled back") {
+		t.Fatalf("Run() error = %v, want a rollback", err)
+	}
+	if want := []string{"1+2@0.1", "1"}; !reflect.DeepEqual(fake.routing, want) {
+		t.Errorf("alias routing = %v, want %v", fake.routing, want)
+	}
+	saved, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != StatusRolledBack || saved.CurrentStep != 0 || !strings.Contains(saved.Reason, "Unhandled") {
+		t.Errorf("saved state = %+v", saved)
+	}
+}
+
+func TestRunRollsBackWhenAStepCannotBeApplied(t *testing.T) {
+	fake := &fakeLambda{reject: "1+2@0.5"}
+	awsInterface := connect(t, fake)
+	state, err := NewState(Plan{Function: "fn", Alias: "live", FromVersion: "1", ToVersion: "2", Steps: []float64{0.1, 0.5, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fn-live.json")
+
+	err = Run(context.Background(), awsInterface, state, path, nil)
+	if err == nil || !strings.Contains(err.Error(), "failed to route 50% to version 2") {
+		t.Fatalf("Run() error = %v, want the failed step", err)
+	}
+	// The first step left 10% on the new version, which must be undone.
+	if want := []string{"1+2@0.1", "1+2@0.5", "1"}; !reflect.DeepEqual(fake.routing, want) {
+		t.Errorf("alias routing = %v, want %v", fake.routing, want)
+	}
+	if saved, err := LoadState(path); err != nil || saved.Status != StatusRolledBack {
+		t.Errorf("saved state = %+v, %v, want it rolled back", saved, err)
+	}
+}
+
+func TestRunStopsWhenCancelled(t *testing.T) {
+	fake := &fakeLambda{}
+	awsInterface := connect(t, fake)
+	state, err := NewState(Plan{Function: "fn", Alias: "live", FromVersion: "1", ToVersion: "2", Steps: []float64{0.1, 1}, Interval: Duration(time.Hour)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fn-live.json")
+
+	// Interrupt the release while it waits out the interval after step one.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	err = Run(ctx, awsInterface, state, path, func(event Event) {
+		if strings.HasPrefix(event.Message, "Waiting") {
+			cancel()
+		}
+	})
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("Run() error = %v, want it cancelled", err)
+	}
+	saved, err := LoadState(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if saved.Status != StatusRunning || saved.CurrentStep != 1 {
+		t.Fatalf("saved state = %+v, want a release that can be resumed at step two", saved)
+	}
+
+	saved.Plan.Interval = 0
+	if err := Run(context.Background(), awsInterface, saved, path, nil); err != nil {
+		t.Fatalf("Run() of the resumed release error = %v", err)
+	}
+	if want := []string{"1+2@0.1", "2"}; !reflect.DeepEqual(fake.routing, want) {
+		t.Errorf("alias routing = %v, want %v", fake.routing, want)
+	}
+}
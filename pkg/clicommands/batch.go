@@ -0,0 +1,106 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/batch"
+	"aws_utility/pkg/history"
+	"aws_utility/pkg/output"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+type BatchOptions struct {
+	Concurrency    int
+	MaxRetries     int
+	Policy         string
+	InvocationType string
+	Qualifier      string
+	Format         string
+}
+
+// RunBatch invokes every job in jobsFile, reporting progress on stderr and
+// the per-job results on stdout. It fails if any job did not succeed.
+func RunBatch(session Session, jobsFile string, opts BatchOptions) error {
+	if err := output.CheckFormat(opts.Format); err != nil {
+		return err
+	}
+	if err := batch.CheckPolicy(opts.Policy); err != nil {
+		return err
+	}
+	invocationType, err := awsinterface.ParseInvocationType(opts.InvocationType)
+	if err != nil {
+		return err
+	}
+
+	jobs, err := batch.LoadJobs(jobsFile)
+	if err != nil {
+		return err
+	}
+
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+
+	finished := 0
+	results := batch.Run(jobs, recordedInvoke(awsInterface), batch.Options{
+		Concurrency: opts.Concurrency,
+		MaxRetries:  opts.MaxRetries,
+		Policy:      opts.Policy,
+		Invoke: awsinterface.InvokeOptions{
+			InvocationType: invocationType,
+			Qualifier:      opts.Qualifier,
+		},
+		OnResult: func(result batch.Result) {
+			finished++
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s: %s (%s)\n", finished, len(jobs), result.Job, result.Status, result.Duration())
+		},
+	})
+
+	if opts.Format == output.FormatText {
+		err = writeBatchSummary(results)
+	} else {
+		err = output.Write(os.Stdout, opts.Format, results)
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Succeeded() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d jobs did not succeed", failed, len(results))
+	}
+	return nil
+}
+
+// recordedInvoke wraps InvokeLambda so every attempt lands in the
+// invocation history.
+func recordedInvoke(awsInterface *awsinterface.AWSInterface) batch.InvokeFunc {
+	return func(functionName string, payloadJson []byte, opts awsinterface.InvokeOptions) (*awsinterface.InvokeResult, error) {
+		return history.InvokeLambda(awsInterface, functionName, payloadJson, opts)
+	}
+}
+
+func writeBatchSummary(results []batch.Result) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "#\tJOB\tFUNCTION\tSTATUS\tATTEMPTS\tDURATION\tERROR")
+	for _, result := range results {
+		function := result.Function
+		if result.Qualifier != "" {
+			function += ":" + result.Qualifier
+		}
+		message := result.FunctionError
+		if result.Error != "" {
+			message = result.Error
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			result.Index+1, result.Job, function, result.Status, result.Attempts, result.Duration(), message)
+	}
+	return writer.Flush()
+}
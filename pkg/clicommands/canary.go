@@ -0,0 +1,279 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/canary"
+	"aws_utility/pkg/payload"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"time"
+
+	"github.com/charmbracelet/bubbles/spinner"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type CanaryOptions struct {
+	Alias        string
+	FromVersion  string
+	ToVersion    string
+	Steps        string
+	Interval     time.Duration
+	Checks       int
+	MaxErrorRate float64
+	Payload      payload.Options
+	StatePath    string
+	// Plain prints progress lines instead of the live view.
+	Plain bool
+}
+
+// StartCanary begins a canary release of functionName's alias and follows
+// it to completion or rollback.
+func StartCanary(session Session, functionName string, opts CanaryOptions) error {
+	if functionName == "" || opts.Alias == "" || opts.ToVersion == "" {
+		return fmt.Errorf("a function, --alias and --to version are required")
+	}
+	steps, err := canary.ParseSteps(opts.Steps)
+	if err != nil {
+		return err
+	}
+	payloadJson, err := payload.Build(opts.Payload)
+	if err != nil {
+		return err
+	}
+
+	statePath := opts.StatePath
+	if statePath == "" {
+		statePath, err = canary.DefaultStatePath(functionName, opts.Alias)
+		if err != nil {
+			return err
+		}
+	}
+	if existing, err := canary.LoadState(statePath); err == nil && existing.Status == canary.StatusRunning {
+		return fmt.Errorf("a canary release of %s:%s is already in progress, continue it with `canary resume`", functionName, opts.Alias)
+	}
+
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+
+	fromVersion := opts.FromVersion
+	if fromVersion == "" {
+		alias, err := awsInterface.GetAlias(functionName, opts.Alias)
+		if err != nil {
+			return err
+		}
+		fromVersion = alias.FunctionVersion
+	}
+
+	state, err := canary.NewState(canary.Plan{
+		Function:      functionName,
+		Alias:         opts.Alias,
+		FromVersion:   fromVersion,
+		ToVersion:     opts.ToVersion,
+		Steps:         steps,
+		Interval:      canary.Duration(opts.Interval),
+		Payload:       payloadJson,
+		ChecksPerStep: opts.Checks,
+		MaxErrorRate:  opts.MaxErrorRate,
+	})
+	if err != nil {
+		return err
+	}
+	if err := state.Save(statePath); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Canary state: %s\n", statePath)
+	return followCanary(awsInterface, state, statePath, opts.Plain)
+}
+
+// ResumeCanary continues an interrupted release from its state file, given
+// either directly or as function and alias.
+func ResumeCanary(session Session, functionName string, opts CanaryOptions) error {
+	statePath := opts.StatePath
+	if statePath == "" {
+		if functionName == "" || opts.Alias == "" {
+			return fmt.Errorf("give --state or a function and --alias")
+		}
+		var err error
+		statePath, err = canary.DefaultStatePath(functionName, opts.Alias)
+		if err != nil {
+			return err
+		}
+	}
+	state, err := canary.LoadState(statePath)
+	if err != nil {
+		return err
+	}
+
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+	return followCanary(awsInterface, state, statePath, opts.Plain)
+}
+
+// followCanary runs the release until it ends or is interrupted with ctrl+c,
+// which stops it between steps with the state saved.
+func followCanary(awsInterface *awsinterface.AWSInterface, state *canary.State, statePath string, plain bool) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	if plain {
+		return interrupted(canary.Run(ctx, awsInterface, state, statePath, func(event canary.Event) {
+			fmt.Fprintf(os.Stderr, "%s %s\n", time.Now().Format("15:04:05"), event.Message)
+		}))
+	}
+
+	// The view reads ctrl+c as a key, so it cancels the run itself. Events
+	// are dropped once the run is cancelled, as the view may be gone.
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	events := make(chan tea.Msg)
+	run := &canaryRun{finished: make(chan struct{})}
+	go func() {
+		defer close(run.finished)
+		run.err = canary.Run(ctx, awsInterface, state, statePath, func(event canary.Event) {
+			select {
+			case events <- canaryEventMsg{event: event}:
+			case <-ctx.Done():
+			}
+		})
+	}()
+
+	view := canaryModel{state: *state, events: events, run: run, cancel: cancel, spinner: spinner.New()}
+	_, err := tea.NewProgram(view).Run()
+	cancel()
+	// Wait for the run to save its state before exiting.
+	<-run.finished
+	if err != nil {
+		return err
+	}
+	return interrupted(run.err)
+}
+
+// canaryRun is the outcome of a release run; err is set once finished is
+// closed.
+type canaryRun struct {
+	finished chan struct{}
+	err      error
+}
+
+// interrupted explains how to continue a release that was stopped.
+func interrupted(err error) error {
+	if errors.Is(err, context.Canceled) {
+		return fmt.Errorf("interrupted, continue the release with `canary resume`")
+	}
+	return err
+}
+
+type canaryEventMsg struct {
+	event canary.Event
+}
+type canaryDoneMsg struct {
+	err error
+}
+
+// canaryModel is the live progress view of a canary release.
+type canaryModel struct {
+	state   canary.State
+	log     []string
+	events  <-chan tea.Msg
+	run     *canaryRun
+	cancel  context.CancelFunc
+	spinner spinner.Model
+	done    bool
+	err     error
+}
+
+func (m canaryModel) Init() tea.Cmd {
+	return tea.Batch(m.spinner.Tick, m.waitForEvent)
+}
+
+func (m canaryModel) waitForEvent() tea.Msg {
+	select {
+	case msg := <-m.events:
+		return msg
+	case <-m.run.finished:
+		return canaryDoneMsg{err: m.run.err}
+	}
+}
+
+func (m canaryModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+	switch msg := msg.(type) {
+	case tea.KeyMsg:
+		if msg.String() == "ctrl+c" {
+			m.cancel()
+			return m, tea.Quit
+		}
+	case canaryEventMsg:
+		m.state = msg.event.State
+		m.log = append(m.log, fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), msg.event.Message))
+		return m, m.waitForEvent
+	case canaryDoneMsg:
+		m.done = true
+		m.err = interrupted(msg.err)
+		return m, tea.Quit
+	case spinner.TickMsg:
+		var cmd tea.Cmd
+		m.spinner, cmd = m.spinner.Update(msg)
+		return m, cmd
+	}
+	return m, nil
+}
+
+func (m canaryModel) View() string {
+	plan := m.state.Plan
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(fmt.Sprintf("Canary release of %s:%s, version %s → %s", plan.Function, plan.Alias, plan.FromVersion, plan.ToVersion)) + "\n\n")
+
+	weight := 0.0
+	if m.state.CurrentStep > 0 {
+		weight = plan.Steps[m.state.CurrentStep-1]
+	}
+	if m.state.Status == canary.StatusRolledBack {
+		weight = 0
+	}
+	const width = 30
+	filled := int(weight * width)
+	b.WriteString(fmt.Sprintf("Traffic to %s: [%s%s] %g%%\n\n", plan.ToVersion, strings.Repeat("█", filled), strings.Repeat("░", width-filled), weight*100))
+
+	for i, step := range plan.Steps {
+		marker := "  "
+		switch {
+		case i < m.state.CurrentStep:
+			marker = "✓ "
+		case i == m.state.CurrentStep && m.state.Status == canary.StatusRunning:
+			marker = m.spinner.View()
+		}
+		line := fmt.Sprintf("%s %5g%%", marker, step*100)
+		if i < len(m.state.Results) {
+			result := m.state.Results[i]
+			line += fmt.Sprintf("  %d/%d failed", result.Errors, result.Invocations)
+		}
+		b.WriteString(line + "\n")
+	}
+
+	b.WriteString("\n")
+	start := 0
+	if len(m.log) > 8 {
+		start = len(m.log) - 8
+	}
+	for _, line := range m.log[start:] {
+		b.WriteString(logStyle.Render(line) + "\n")
+	}
+
+	switch {
+	case m.done && m.err != nil:
+		b.WriteString("\n" + errorStyle.Render(m.err.Error()) + "\n")
+	case m.done:
+		b.WriteString("\n" + headerStyle.Render("Release complete") + "\n")
+	default:
+		b.WriteString("\n(ctrl+c stops following; the state file lets you resume)\n")
+	}
+	return b.String()
+}
@@ -2,186 +2,924 @@ package clicommands
 
 import (
 	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/emulator"
+	"aws_utility/pkg/history"
 	"aws_utility/pkg/logger"
-	"encoding/json"
+	"aws_utility/pkg/output"
+	"aws_utility/pkg/payload"
+	"aws_utility/pkg/schema"
+	"aws_utility/pkg/templates"
+	"context"
+	"errors"
 	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
 
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
+	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
 )
 
 type model struct {
 	list            list.Model
+	templateList    list.Model
+	qualifierList   list.Model
+	historyList     list.Model
+	historyEntries  []history.Entry
 	selectedLambda  string
+	qualifier       string
 	awsProfile      string
-	clusterInput    textinput.Model
-	serviceInput    textinput.Model
-	tagInput        textinput.Model
+	profileInput    textinput.Model
+	varInput        textinput.Model
+	payloadEditor   textarea.Model
+	payloadJson     []byte
+	result          *awsinterface.InvokeResult
+	details         *awsinterface.FunctionDetails
+	mappings        []awsinterface.EventSourceMapping
+	mappingIndex    int
+	confirmToggle   bool
+	stream          bool
+	streamEvents    <-chan awsinterface.StreamEvent
+	streamCancel    context.CancelFunc
+	streamOutput    string
+	streamComplete  *awsinterface.StreamComplete
+	streamEntry     history.Entry
+	deployInput     textinput.Model
+	localInput      textinput.Model
+	localBootstrap  string
+	queryInput      textinput.Model
+	resultFormat    string
+	resultQuery     string
+	deployArtifact  string
+	deployLog       []string
+	deployResult    *awsinterface.DeployResult
+	deployEvents    <-chan tea.Msg
+	invocation      history.Entry
+	logBatches      <-chan awsinterface.LogBatch
+	logCancel       context.CancelFunc
+	logQuery        awsinterface.LogQuery
+	logGroup        string
+	logLines        []string
+	logReturn       string
+	retries         <-chan awsinterface.Retry
+	lastRetry       *awsinterface.Retry
+	lastRetryAt     time.Time
+	viewport        viewport.Model
+	templates       []templates.Template
+	schema          *schema.Schema
+	template        *templates.Template
+	varIndex        int
+	varValues       map[string]string
 	state           string
 	err             error
 	awsInterface    *awsinterface.AWSInterface
-	lambdaFunctions []string
+	lambdaFunctions []awsinterface.LambdaFunction
 }
 
+const jsonEditorItem = "JSON editor"
+
 func InitialModel() model {
+	payloadEditor := textarea.New()
+	payloadEditor.Placeholder = "{}"
+	payloadEditor.ShowLineNumbers = true
+	payloadEditor.SetWidth(80)
+	payloadEditor.SetHeight(12)
+
+	profileInput := textinput.New()
+	profileInput.Focus()
+
 	return model{
-		list:         list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0),
-		state:        "profile_input",
-		clusterInput: textinput.New(),
-		serviceInput: textinput.New(),
-		tagInput:     textinput.New(),
+		list:          list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0),
+		templateList:  list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0),
+		qualifierList: list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0),
+		historyList:   list.New([]list.Item{}, list.NewDefaultDelegate(), 0, 0),
+		state:         "profile_input",
+		profileInput:  profileInput,
+		varInput:      textinput.New(),
+		deployInput:   textinput.New(),
+		localInput:    textinput.New(),
+		queryInput:    textinput.New(),
+		payloadEditor: payloadEditor,
+		viewport:      viewport.New(80, 20),
+		retries:       watchRetries(),
 	}
 }
 
 func (m model) Init() tea.Cmd {
-	return textinput.Blink
+	return tea.Batch(textinput.Blink, waitForRetry(m.retries))
 }
 
 func (m model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
+	case tea.WindowSizeMsg:
+		m.list.SetSize(msg.Width, msg.Height-4)
+		m.templateList.SetSize(msg.Width, msg.Height-4)
+		m.qualifierList.SetSize(msg.Width, msg.Height-4)
+		m.historyList.SetSize(msg.Width, msg.Height-4)
+		m.payloadEditor.SetWidth(msg.Width)
+		m.viewport.Width = msg.Width
+		m.viewport.Height = msg.Height - 4
+		return m, nil
+	case retryMsg:
+		return m.showRetry(msg)
+	case retryExpiredMsg:
+		if msg.at.Equal(m.lastRetryAt) {
+			m.lastRetry = nil
+		}
+		return m, nil
 	case tea.KeyMsg:
 		switch m.state {
 		case "profile_input":
 			switch msg.String() {
 			case "enter":
-				m.awsProfile = m.clusterInput.Value()
+				m.awsProfile = m.profileInput.Value()
 				return m, m.fetchLambdaFunctions
 			}
 		case "lambda_selection":
+			if m.list.FilterState() == list.Filtering {
+				break
+			}
 			switch msg.String() {
+			case "h":
+				m.err = nil
+				return m, fetchHistory
+			case "u":
+				i, ok := m.list.SelectedItem().(item)
+				if ok {
+					m.selectedLambda = i.title
+					m.err = nil
+					m.deployInput.Reset()
+					m.deployInput.Placeholder = "path/to/function.zip or image URI"
+					m.state = "deploy_artifact"
+					return m, m.deployInput.Focus()
+				}
+			case "d":
+				i, ok := m.list.SelectedItem().(item)
+				if ok {
+					m.selectedLambda = i.title
+					m.err = nil
+					return m, m.fetchDetails
+				}
 			case "enter":
 				i, ok := m.list.SelectedItem().(item)
 				if ok {
 					m.selectedLambda = i.title
-					m.state = "cluster_input"
-					m.clusterInput.Focus()
-					return m, textinput.Blink
+					m.qualifier = ""
+					m.schema = nil
+					m.err = nil
+					return m, m.fetchQualifiers
+				}
+			}
+		case "streaming":
+			switch msg.String() {
+			case "q", "esc", "ctrl+c":
+				if m.streamCancel != nil {
+					m.streamCancel()
+				}
+				return m, tea.Quit
+			case "l":
+				if m.streamEvents == nil && m.streamEntry.RequestID != "" {
+					return m.openLogs(m.streamEntry.LogQuery())
+				}
+				return m, nil
+			}
+		case "result":
+			switch msg.String() {
+			case "q", "esc", "enter", "ctrl+c":
+				return m, tea.Quit
+			case "l":
+				if m.invocation.RequestID != "" {
+					return m.openLogs(m.invocation.LogQuery())
+				}
+			case "f":
+				m.resultFormat = nextResponseFormat(m.responseOptions().Format)
+			case "/":
+				if m.err == nil && m.result != nil {
+					m.queryInput.Placeholder = "JMESPath query, e.g. items[].id, empty for the whole response"
+					m.queryInput.SetValue(m.resultQuery)
+					m.state = "result_query"
+					return m, m.queryInput.Focus()
+				}
+			}
+			return m, nil
+		case "result_query":
+			switch msg.String() {
+			case "enter":
+				m.resultQuery = strings.TrimSpace(m.queryInput.Value())
+				m.queryInput.Blur()
+				m.state = "result"
+				return m, nil
+			case "esc":
+				m.queryInput.Blur()
+				m.state = "result"
+				return m, nil
+			case "ctrl+c":
+				return m, tea.Quit
+			}
+		case "logs":
+			switch msg.String() {
+			case "q", "ctrl+c":
+				m = m.closeLogs()
+				return m, tea.Quit
+			case "esc":
+				return m.closeLogs(), nil
+			}
+		case "deploy_artifact":
+			switch msg.String() {
+			case "enter":
+				if m.deployInput.Value() == "" {
+					return m, nil
+				}
+				m.deployArtifact = m.deployInput.Value()
+				m.deployInput.Reset()
+				m.deployInput.Placeholder = "alias to move, leave empty to skip publishing"
+				m.state = "deploy_alias"
+				return m, nil
+			case "esc":
+				m.deployInput.Blur()
+				m.state = "lambda_selection"
+				return m, nil
+			}
+		case "deploy_alias":
+			switch msg.String() {
+			case "enter":
+				alias := m.deployInput.Value()
+				m.deployInput.Blur()
+				return m.startDeploy(m.deployArtifact, alias)
+			case "esc":
+				m.deployInput.SetValue(m.deployArtifact)
+				m.state = "deploy_artifact"
+				return m, nil
+			}
+		case "deploying":
+			switch msg.String() {
+			case "ctrl+c":
+				return m, tea.Quit
+			case "q":
+				if m.deployEvents == nil {
+					return m, tea.Quit
 				}
+			case "esc":
+				if m.deployEvents == nil {
+					m.err = nil
+					m.state = "lambda_selection"
+				}
+			}
+			return m, nil
+		case "history":
+			if m.historyList.FilterState() == list.Filtering {
+				break
 			}
-		case "cluster_input":
+			switch msg.String() {
+			case "enter", "r":
+				entry, ok := m.selectedHistoryEntry()
+				if !ok {
+					return m, nil
+				}
+				return m.loadHistoryEntry(entry, msg.String() == "r")
+			case "esc":
+				m.state = "lambda_selection"
+				return m, nil
+			}
+		case "function_details":
+			if m.confirmToggle {
+				m.confirmToggle = false
+				if msg.String() == "y" && m.mappingIndex < len(m.mappings) {
+					return m, m.toggleMapping(m.mappings[m.mappingIndex])
+				}
+				return m, nil
+			}
+			switch msg.String() {
+			case "esc", "q":
+				m.state = "lambda_selection"
+				return m, nil
+			case "up", "k":
+				if m.mappingIndex > 0 {
+					m.mappingIndex--
+				}
+			case "down", "j":
+				if m.mappingIndex < len(m.mappings)-1 {
+					m.mappingIndex++
+				}
+			case "t":
+				// Disabling a mapping stops its queue or stream from being
+				// processed, so ask first as the env command does.
+				m.confirmToggle = m.mappingIndex < len(m.mappings)
+			case "l":
+				return m.openLogs(awsinterface.LogQuery{Start: time.Now().Add(-5 * time.Minute)})
+			}
+			return m, nil
+		case "qualifier_selection":
 			switch msg.String() {
 			case "enter":
-				m.state = "service_input"
-				m.serviceInput.Focus()
-				return m, textinput.Blink
+				i, ok := m.qualifierList.SelectedItem().(item)
+				if ok {
+					m.qualifier = i.title
+					m.loadTemplates()
+					m.state = "payload_mode"
+					return m, m.fetchSchema
+				}
+			case "esc":
+				m.state = "lambda_selection"
+				return m, nil
+			}
+		case "payload_mode":
+			switch msg.String() {
+			case "ctrl+t":
+				m.stream = !m.stream
+				return m, nil
+			case "ctrl+l":
+				m.localInput.Placeholder = "path to a locally built bootstrap, empty to invoke in AWS"
+				m.localInput.SetValue(m.localBootstrap)
+				if m.localBootstrap == "" {
+					m.localInput.SetValue(os.Getenv(emulator.BootstrapEnvVariable))
+				}
+				m.err = nil
+				m.state = "local_bootstrap"
+				return m, m.localInput.Focus()
+			case "enter":
+				i, ok := m.templateList.SelectedItem().(item)
+				if !ok {
+					return m, nil
+				}
+				if i.title == jsonEditorItem {
+					m.state = "payload_editor"
+					m.err = nil
+					return m, m.payloadEditor.Focus()
+				}
+				template, err := templates.Find(m.templates, i.title)
+				if err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.template = template
+				m.varValues = map[string]string{}
+				m.varIndex = -1
+				return m.nextVariable()
 			}
-		case "service_input":
+		case "local_bootstrap":
 			switch msg.String() {
 			case "enter":
-				m.state = "tag_input"
-				m.tagInput.Focus()
-				return m, textinput.Blink
+				bootstrap := strings.TrimSpace(m.localInput.Value())
+				if bootstrap != "" {
+					if _, err := emulator.ResolveBootstrap(bootstrap); err != nil {
+						m.err = err
+						return m, nil
+					}
+				}
+				m.localBootstrap = bootstrap
+				m.localInput.Blur()
+				m.err = nil
+				m.state = "payload_mode"
+				return m, nil
+			case "esc":
+				m.localInput.Blur()
+				m.err = nil
+				m.state = "payload_mode"
+				return m, nil
 			}
-		case "tag_input":
+		case "template_input":
 			switch msg.String() {
 			case "enter":
-				return m, m.invokeLambda
+				variable := m.template.Variables[m.varIndex]
+				m.varValues[variable.Name] = m.varInput.Value()
+				return m.nextVariable()
+			case "esc":
+				m.state = "payload_mode"
+				return m, nil
+			}
+		case "payload_editor":
+			switch msg.String() {
+			case "ctrl+s":
+				payloadJson, err := payload.Validate(m.payloadEditor.Value())
+				if err == nil {
+					err = m.checkPayload(payloadJson)
+				}
+				if err != nil {
+					m.err = err
+					return m, nil
+				}
+				m.payloadJson = payloadJson
+				return m, m.invokeCmd()
+			case "ctrl+k":
+				if m.schema != nil {
+					if missing := m.schema.MissingKeys(m.payloadEditor.Value()); len(missing) > 0 {
+						m.payloadEditor.InsertString(fmt.Sprintf("%q: ", missing[0]))
+					}
+				}
+				return m, nil
+			case "esc":
+				m.payloadEditor.Blur()
+				m.state = "payload_mode"
+				return m, nil
 			}
 		}
 	case fetchLambdaFunctionsMsg:
-		m.lambdaFunctions = msg
+		m.awsInterface = msg.awsInterface
+		m.lambdaFunctions = msg.functions
 		items := make([]list.Item, len(m.lambdaFunctions))
 		for i, fn := range m.lambdaFunctions {
-			items[i] = item{title: fn, desc: ""}
+			items[i] = item{title: fn.Name, desc: fn.Summary()}
 		}
 		m.list.SetItems(items)
 		m.state = "lambda_selection"
 		return m, nil
+	case functionDetailsMsg:
+		m.details = msg.details
+		m.mappings = msg.mappings
+		m.mappingIndex = 0
+		m.err = msg.err
+		m.state = "function_details"
+		return m, nil
+	case mappingUpdatedMsg:
+		m.err = msg.err
+		for i := range m.mappings {
+			if msg.mapping != nil && m.mappings[i].UUID == msg.mapping.UUID {
+				m.mappings[i] = *msg.mapping
+			}
+		}
+		return m, nil
+	case qualifiersMsg:
+		if msg.err != nil {
+			m.err = msg.err
+		}
+		items := make([]list.Item, len(msg.qualifiers))
+		for i, qualifier := range msg.qualifiers {
+			items[i] = item{title: qualifier.Name, desc: qualifier.Description}
+		}
+		m.qualifierList.SetItems(items)
+		m.state = "qualifier_selection"
+		return m, nil
+	case schemaLoadedMsg:
+		m.schema = msg.schema
+		if msg.err != nil {
+			m.err = msg.err
+		}
+		return m, nil
 	case lambdaInvokeResultMsg:
 		m.state = "result"
-		return m, tea.Quit
+		m.result = msg.result
+		m.invocation = msg.entry
+		m.err = msg.err
+		if m.err != nil {
+			return m, tea.Quit
+		}
+		return m, nil
+	case logsStartedMsg:
+		if m.state != "logs" {
+			if msg.cancel != nil {
+				msg.cancel()
+			}
+			return m, nil
+		}
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.logBatches = msg.batches
+		m.logCancel = msg.cancel
+		m.logGroup = msg.group
+		return m, waitForLogBatch(m.logBatches)
+	case logBatchMsg:
+		if msg.batches != m.logBatches {
+			return m, nil
+		}
+		m = m.addLogBatch(msg.batch)
+		return m, waitForLogBatch(m.logBatches)
+	case logsDoneMsg:
+		return m, nil
+	case streamStartedMsg:
+		if msg.err != nil {
+			m.state = "result"
+			m.err = msg.err
+			return m, tea.Quit
+		}
+		m.state = "streaming"
+		m.streamEvents = msg.events
+		m.streamCancel = msg.cancel
+		m.streamEntry = msg.entry
+		return m, waitForStreamEvent(m.streamEvents)
+	case streamEventMsg:
+		switch {
+		case msg.event.Err != nil:
+			m.err = msg.event.Err
+		case msg.event.Complete != nil:
+			m.streamComplete = msg.event.Complete
+		default:
+			m.streamOutput += string(msg.event.Chunk)
+			m.viewport.SetContent(m.streamOutput)
+			m.viewport.GotoBottom()
+		}
+		return m, waitForStreamEvent(m.streamEvents)
+	case streamDoneMsg:
+		m.streamEvents = nil
+		m.streamCancel()
+		m.streamCancel = nil
+		return m, nil
+	case deployProgressMsg:
+		m.deployLog = append(m.deployLog, fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), msg.message))
+		return m, waitForDeployEvent(m.deployEvents)
+	case deployDoneMsg:
+		m.deployResult = msg.result
+		m.err = msg.err
+		m.deployEvents = nil
+		return m, nil
+	case historyMsg:
+		if msg.err != nil {
+			m.err = msg.err
+			return m, nil
+		}
+		m.historyEntries = msg.entries
+		m.historyList.SetItems(historyItems(msg.entries))
+		m.state = "history"
+		return m, nil
 	}
 
 	var cmd tea.Cmd
-	m.list, cmd = m.list.Update(msg)
+	switch m.state {
+	case "profile_input":
+		m.profileInput, cmd = m.profileInput.Update(msg)
+	case "template_input":
+		m.varInput, cmd = m.varInput.Update(msg)
+	case "deploy_artifact", "deploy_alias":
+		m.deployInput, cmd = m.deployInput.Update(msg)
+	case "local_bootstrap":
+		m.localInput, cmd = m.localInput.Update(msg)
+	case "result_query":
+		m.queryInput, cmd = m.queryInput.Update(msg)
+	case "payload_editor":
+		m.payloadEditor, cmd = m.payloadEditor.Update(msg)
+	case "payload_mode":
+		m.templateList, cmd = m.templateList.Update(msg)
+	case "qualifier_selection":
+		m.qualifierList, cmd = m.qualifierList.Update(msg)
+	case "streaming", "logs":
+		m.viewport, cmd = m.viewport.Update(msg)
+	case "history":
+		m.historyList, cmd = m.historyList.Update(msg)
+	default:
+		m.list, cmd = m.list.Update(msg)
+	}
 	return m, cmd
 }
 
+func (m *model) loadTemplates() {
+	loaded, err := templates.LoadDefault()
+	if err != nil {
+		logger.Error("Failed to load payload templates:", err)
+		m.err = err
+		loaded = templates.Builtin
+	}
+	m.templates = templates.ForFunction(loaded, m.selectedLambda)
+
+	items := make([]list.Item, 0, len(m.templates)+1)
+	for _, template := range m.templates {
+		items = append(items, item{title: template.Name, desc: template.Description})
+	}
+	items = append(items, item{title: jsonEditorItem, desc: "Write the payload as raw JSON"})
+	m.templateList.SetItems(items)
+}
+
+// nextVariable advances to the next template variable, or renders and
+// invokes the template once every variable has been entered.
+func (m model) nextVariable() (tea.Model, tea.Cmd) {
+	m.varIndex++
+	if m.varIndex < len(m.template.Variables) {
+		variable := m.template.Variables[m.varIndex]
+		m.varInput.Reset()
+		m.varInput.Placeholder = variable.Default
+		m.varInput.Focus()
+		m.state = "template_input"
+		return m, textinput.Blink
+	}
+
+	payloadJson, err := m.template.Render(m.varValues)
+	if err == nil {
+		err = m.checkPayload(payloadJson)
+	}
+	if err != nil {
+		m.err = err
+		m.state = "payload_mode"
+		return m, nil
+	}
+	m.payloadJson = payloadJson
+	return m, m.invokeCmd()
+}
+
 func (m model) View() string {
+	return m.screen() + m.retryLine()
+}
+
+func (m model) screen() string {
 	switch m.state {
 	case "profile_input":
 		return fmt.Sprintf(
 			"Enter AWS SSO profile name:\n\n%s\n\n%s",
-			m.clusterInput.View(),
+			m.profileInput.View(),
 			"(press enter to confirm)",
 		)
 	case "lambda_selection":
 		return fmt.Sprintf(
-			"Select a Lambda function:\n\n%s\n\n%s",
+			"Select a Lambda function:\n\n%s%s\n\n%s",
 			m.list.View(),
-			"(press enter to select)",
+			errorLine(m.err),
+			"(press enter to select, d for details, u to deploy code, h for invocation history)",
 		)
-	case "cluster_input":
+	case "deploy_artifact":
 		return fmt.Sprintf(
-			"Enter cluster name:\n\n%s\n\n%s",
-			m.clusterInput.View(),
-			"(press enter to confirm)",
+			"Deploy code to '%s' from a zip file or container image URI:\n\n%s\n\n%s",
+			m.selectedLambda,
+			m.deployInput.View(),
+			"(press enter to confirm, esc to go back)",
 		)
-	case "service_input":
+	case "deploy_alias":
 		return fmt.Sprintf(
-			"Enter service name:\n\n%s\n\n%s",
-			m.serviceInput.View(),
-			"(press enter to confirm)",
+			"Publish a version and move this alias to it (optional):\n\n%s\n\n%s",
+			m.deployInput.View(),
+			"(press enter to deploy, esc to go back)",
 		)
-	case "tag_input":
+	case "deploying":
+		return m.deployView()
+	case "history":
 		return fmt.Sprintf(
-			"Enter tag name:\n\n%s\n\n%s",
-			m.tagInput.View(),
-			"(press enter to confirm)",
+			"Invocation history:\n\n%s\n\n%s",
+			m.historyList.View(),
+			"(press enter to edit and invoke, r to re-invoke as is, esc to go back)",
+		)
+	case "function_details":
+		if m.details == nil {
+			return fmt.Sprintf("Error: %v\n\n(press esc to go back)", m.err)
+		}
+		var b strings.Builder
+		b.WriteString(headerStyle.Render(m.details.Name) + "\n\n")
+		_ = output.WriteRows(&b, output.FunctionDetailRows(m.details))
+		if len(m.mappings) > 0 {
+			b.WriteString("\n" + headerStyle.Render("Event source mappings") + "\n\n")
+			for i, mapping := range m.mappings {
+				cursor := "  "
+				if i == m.mappingIndex {
+					cursor = "> "
+				}
+				b.WriteString(cursor + mapping.Summary() + "\n")
+			}
+			b.WriteString(errorLine(m.err))
+			if m.confirmToggle {
+				mapping := m.mappings[m.mappingIndex]
+				action := "Enable"
+				if mapping.Enabled() {
+					action = "Disable"
+				}
+				fmt.Fprintf(&b, "\n%s the mapping from %s? (y/N)", action, mapping.EventSourceArn)
+				return b.String()
+			}
+			b.WriteString("\n(press t to enable or disable the selected mapping, l to follow logs, esc to go back)")
+			return b.String()
+		}
+		b.WriteString("\n(press l to follow logs, esc to go back)")
+		return b.String()
+	case "qualifier_selection":
+		return fmt.Sprintf(
+			"Select a version or alias of '%s':\n\n%s%s\n\n%s",
+			m.selectedLambda,
+			m.qualifierList.View(),
+			errorLine(m.err),
+			"(press enter to select, esc to go back)",
+		)
+	case "payload_mode":
+		return fmt.Sprintf(
+			"Select a payload template for '%s':\n\n%s%s\n\n%s",
+			m.qualifiedLambda(),
+			m.templateList.View(),
+			errorLine(m.err),
+			fmt.Sprintf("(press enter to select, ctrl+t to toggle response streaming: %s, ctrl+l to run locally: %s)", onOff(m.stream), m.target()),
+		)
+	case "local_bootstrap":
+		return fmt.Sprintf(
+			"Run '%s' locally with this bootstrap:\n\n%s%s\n\n%s",
+			m.selectedLambda,
+			m.localInput.View(),
+			errorLine(m.err),
+			"(press enter to confirm, esc to go back)",
+		)
+	case "template_input":
+		variable := m.template.Variables[m.varIndex]
+		return fmt.Sprintf(
+			"%s (%d/%d):\n\n%s\n\n%s",
+			variable.Label(),
+			m.varIndex+1,
+			len(m.template.Variables),
+			m.varInput.View(),
+			"(press enter to confirm, esc to go back)",
+		)
+	case "payload_editor":
+		help := "(ctrl+s to invoke, esc to go back)"
+		if m.schema != nil {
+			help = "(ctrl+s to invoke, ctrl+k to insert the next schema key, esc to go back)"
+			if missing := m.schema.MissingKeys(m.payloadEditor.Value()); len(missing) > 0 {
+				help = fmt.Sprintf("Schema keys not set: %s\n%s", strings.Join(missing, ", "), help)
+			}
+		}
+		return fmt.Sprintf(
+			"Enter JSON payload:\n\n%s%s\n\n%s",
+			m.payloadEditor.View(),
+			errorLine(m.err),
+			help,
+		)
+	case "streaming":
+		return fmt.Sprintf("%s\n%s\n\n%s",
+			headerStyle.Render(fmt.Sprintf("Streaming response from '%s'", m.qualifiedLambda())),
+			m.viewport.View(),
+			m.streamStatus(),
 		)
 	case "result":
 		if m.err != nil {
-			return fmt.Sprintf("Error: %v", m.err)
+			return errorStyle.Render(fmt.Sprintf("Error: %v", m.err)) + "\n"
 		}
-		return fmt.Sprintf("Lambda function '%s' invoked successfully", m.selectedLambda)
+		return renderInvokeResult(m.qualifiedLambda(), m.result, m.responseOptions()) + "\n" + m.resultHelp() + "\n"
+	case "result_query":
+		return fmt.Sprintf("%s\n%s\n\n%s\n",
+			renderInvokeResult(m.qualifiedLambda(), m.result, m.responseOptions()),
+			m.queryInput.View(),
+			"(press enter to apply the query, esc to cancel)",
+		)
+	case "logs":
+		return m.logsView()
 	default:
 		return "Loading..."
 	}
 }
 
+func errorLine(err error) string {
+	if err == nil {
+		return ""
+	}
+	return fmt.Sprintf("\n\nError: %v", err)
+}
+
 func (m *model) fetchLambdaFunctions() tea.Msg {
 	awsInterface, err := awsinterface.NewAWSInterface(m.awsProfile)
 	if err != nil {
 		logger.Error("Failed to create AWS interface:", err)
 		return fetchLambdaFunctionsMsg{}
 	}
-	m.awsInterface = awsInterface
 
-	lambdaFunctions, err := m.awsInterface.ListLambdaFunctions()
+	lambdaFunctions, err := awsInterface.ListLambdaFunctions(awsinterface.FunctionFilter{})
 	if err != nil {
 		logger.Error("Failed to list Lambda functions:", err)
-		return fetchLambdaFunctionsMsg{}
+		return fetchLambdaFunctionsMsg{awsInterface: awsInterface}
 	}
-	return fetchLambdaFunctionsMsg(lambdaFunctions)
+	return fetchLambdaFunctionsMsg{functions: lambdaFunctions, awsInterface: awsInterface}
 }
 
-func (m *model) invokeLambda() tea.Msg {
-	payload := map[string]string{
-		"cluster": m.clusterInput.Value(),
-		"service": m.serviceInput.Value(),
-		"ecr_tag": m.tagInput.Value(),
+func (m *model) fetchDetails() tea.Msg {
+	details, err := m.awsInterface.DescribeFunction(m.selectedLambda, "")
+	if err != nil {
+		logger.Error("Failed to describe Lambda function:", err)
+		return functionDetailsMsg{err: err}
+	}
+	mappings, mappingsErr := m.awsInterface.ListEventSourceMappings(m.selectedLambda)
+	if mappingsErr != nil {
+		logger.Warn("Failed to list event source mappings:", mappingsErr)
+	}
+	return functionDetailsMsg{details: details, mappings: mappings}
+}
+
+// toggleMapping disables an enabled mapping and enables a disabled one.
+func (m *model) toggleMapping(mapping awsinterface.EventSourceMapping) tea.Cmd {
+	awsInterface := m.awsInterface
+	return func() tea.Msg {
+		updated, err := awsInterface.SetEventSourceMappingEnabled(mapping.UUID, !mapping.Enabled())
+		if err != nil {
+			logger.Error("Failed to update event source mapping:", err)
+		}
+		return mappingUpdatedMsg{mapping: updated, err: err}
 	}
+}
+
+func (m *model) fetchQualifiers() tea.Msg {
+	qualifiers, err := m.awsInterface.ListQualifiers(m.selectedLambda)
+	if err != nil {
+		logger.Error("Failed to list versions and aliases:", err)
+		return qualifiersMsg{qualifiers: []awsinterface.Qualifier{{Name: "$LATEST"}}, err: err}
+	}
+	return qualifiersMsg{qualifiers: qualifiers}
+}
+
+func (m model) qualifiedLambda() string {
+	if m.qualifier == "" || m.qualifier == "$LATEST" {
+		return m.selectedLambda
+	}
+	return m.selectedLambda + ":" + m.qualifier
+}
 
-	payloadJson, err := json.Marshal(payload)
+func (m *model) fetchSchema() tea.Msg {
+	payloadSchema, err := loadSchema(m.awsInterface, m.selectedLambda, "")
 	if err != nil {
-		logger.Error("Failed to marshal payload:", err)
+		logger.Error("Failed to load payload schema:", err)
+	}
+	return schemaLoadedMsg{schema: payloadSchema, err: err}
+}
+
+func (m *model) checkPayload(payloadJson []byte) error {
+	if m.schema == nil {
 		return nil
 	}
+	return m.schema.Check(m.selectedLambda, payloadJson)
+}
 
-	result, err := m.awsInterface.InvokeLambda(m.selectedLambda, payloadJson)
+func (m *model) invokeLambda() tea.Msg {
+	result, entry, err := history.Invoke(m.awsInterface, m.selectedLambda, m.payloadJson, awsinterface.InvokeOptions{
+		TailLogs:  true,
+		Qualifier: m.qualifier,
+	})
 	if err != nil {
 		logger.Error("Failed to invoke Lambda:", err)
 		return lambdaInvokeResultMsg{err: err}
 	}
 
-	logger.Info("Lambda invoked successfully. Result:", string(result))
-	return lambdaInvokeResultMsg{result: result}
+	logger.Info("Lambda invoked. Result:", string(result.Payload))
+	return lambdaInvokeResultMsg{result: result, entry: entry}
+}
+
+var (
+	headerStyle = lipgloss.NewStyle().Bold(true)
+	errorStyle  = lipgloss.NewStyle().Foreground(lipgloss.Color("9")).Bold(true)
+	logStyle    = lipgloss.NewStyle().Foreground(lipgloss.Color("8"))
+)
+
+func renderInvokeResult(functionName string, result *awsinterface.InvokeResult, response output.ResponseOptions) string {
+	var b strings.Builder
+	if result.Failed() {
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Lambda function '%s' failed: %s", functionName, result.FunctionError)))
+	} else {
+		b.WriteString(headerStyle.Render(fmt.Sprintf("Lambda function '%s' invoked successfully", functionName)))
+	}
+	b.WriteString("\n" + describeInvokeResult(result) + "\n")
+
+	if result.LogResult != "" {
+		b.WriteString("\n" + headerStyle.Render("Logs") + "\n")
+		b.WriteString(logStyle.Render(strings.TrimRight(result.LogResult, "\n")) + "\n")
+	}
+	if len(result.Payload) > 0 {
+		title := "Response"
+		if response.Query != "" {
+			title = fmt.Sprintf("Response, query %s", response.Query)
+		}
+		b.WriteString("\n" + headerStyle.Render(title) + "\n")
+		switch formatted, err := output.FormatResponse(result.Payload, response); {
+		case err != nil:
+			b.WriteString(errorStyle.Render(err.Error()) + "\n")
+		case result.Failed():
+			b.WriteString(errorStyle.Render(formatted) + "\n")
+		case response.Format == output.FormatJSON:
+			b.WriteString(output.Colorize(formatted) + "\n")
+		default:
+			b.WriteString(formatted + "\n")
+		}
+	}
+	return b.String()
+}
+
+func describeInvokeResult(result *awsinterface.InvokeResult) string {
+	description := fmt.Sprintf("%s, status %d", result.InvocationType, result.StatusCode)
+	if result.ExecutedVersion != "" {
+		description += ", version " + result.ExecutedVersion
+	}
+	if result.RequestID != "" {
+		description += ", request " + result.RequestID
+	}
+	return description
 }
 
-type fetchLambdaFunctionsMsg []string
+type fetchLambdaFunctionsMsg struct {
+	functions    []awsinterface.LambdaFunction
+	awsInterface *awsinterface.AWSInterface
+}
+type functionDetailsMsg struct {
+	details  *awsinterface.FunctionDetails
+	mappings []awsinterface.EventSourceMapping
+	err      error
+}
+type mappingUpdatedMsg struct {
+	mapping *awsinterface.EventSourceMapping
+	err     error
+}
+type qualifiersMsg struct {
+	qualifiers []awsinterface.Qualifier
+	err        error
+}
+type schemaLoadedMsg struct {
+	schema *schema.Schema
+	err    error
+}
 type lambdaInvokeResultMsg struct {
-	result []byte
+	result *awsinterface.InvokeResult
+	entry  history.Entry
 	err    error
 }
 
@@ -193,48 +931,292 @@ func (i item) Title() string       { return i.title }
 func (i item) Description() string { return i.desc }
 func (i item) FilterValue() string { return i.title }
 
-func ListLambdas(profile string) error {
-	awsInterface, err := awsinterface.NewAWSInterface(profile)
+// ListLambdas prints matching functions. With allRegions or regions it
+// searches those regions (every enabled one by default) and adds a REGION
+// column.
+func ListLambdas(session Session, filters []string, allRegions bool, regions []string) error {
+	filter, err := awsinterface.ParseFilter(filters)
 	if err != nil {
-		return fmt.Errorf("failed to create AWS interface: %v", err)
+		return err
 	}
 
-	lambdaFunctions, err := awsInterface.ListLambdaFunctions()
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+
+	multiRegion := allRegions || len(regions) > 0
+	var lambdaFunctions []awsinterface.LambdaFunction
+	if multiRegion {
+		lambdaFunctions, err = awsInterface.ListLambdaFunctionsInRegions(filter, awsInterface.Regions(regions))
+		var regionErrors awsinterface.RegionErrors
+		if errors.As(err, &regionErrors) {
+			fmt.Fprintf(os.Stderr, "Warning: some regions could not be listed: %v\n", regionErrors)
+			err = nil
+		}
+	} else {
+		lambdaFunctions, err = awsInterface.ListLambdaFunctions(filter)
+	}
 	if err != nil {
 		return fmt.Errorf("failed to list Lambda functions: %v", err)
 	}
 
-	fmt.Println("Available Lambda functions:")
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "NAME\tRUNTIME\tARCH\tMEMORY\tTIMEOUT\tPACKAGE\tHANDLER\tLAST MODIFIED\tDESCRIPTION"
+	if multiRegion {
+		header = "REGION\t" + header
+	}
+	fmt.Fprintln(writer, header)
 	for _, fn := range lambdaFunctions {
-		fmt.Println("-", fn)
+		if multiRegion {
+			fmt.Fprintf(writer, "%s\t", fn.Region)
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d MB\t%ds\t%s\t%s\t%s\t%s\n",
+			fn.Name, fn.Runtime, fn.Architecture, fn.MemorySize, fn.Timeout, fn.PackageType, fn.Handler, fn.LastModified, fn.Description)
+	}
+
+	return writer.Flush()
+}
+
+func DescribeLambda(session Session, lambdaName, format string) error {
+	if err := output.CheckFormat(format); err != nil {
+		return err
+	}
+	if lambdaName == "" {
+		return fmt.Errorf("no Lambda function given")
+	}
+
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+
+	functionName, qualifier := awsinterface.SplitQualifier(lambdaName)
+	details, err := awsInterface.DescribeFunction(functionName, qualifier)
+	if err != nil {
+		return err
+	}
+
+	if format == output.FormatText {
+		return output.WriteRows(os.Stdout, output.FunctionDetailRows(details))
+	}
+	return output.Write(os.Stdout, format, details)
+}
+
+func ListVersions(session Session, lambdaName string) error {
+	if lambdaName == "" {
+		return fmt.Errorf("no Lambda function given")
+	}
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+
+	qualifiers, err := awsInterface.ListQualifiers(lambdaName)
+	if err != nil {
+		return err
+	}
+
+	fmt.Printf("Versions and aliases of '%s':\n", lambdaName)
+	for _, qualifier := range qualifiers {
+		fmt.Printf("- %s: %s\n", qualifier.Name, qualifier.Description)
 	}
 
 	return nil
 }
 
-func ExecuteLambda(profile, lambdaName, cluster, service, tag string) error {
-	awsInterface, err := awsinterface.NewAWSInterface(profile)
+func ListTemplates() error {
+	loaded, err := templates.LoadDefault()
 	if err != nil {
-		return fmt.Errorf("failed to create AWS interface: %v", err)
+		return err
 	}
 
-	payload := map[string]string{
-		"cluster": cluster,
-		"service": service,
-		"ecr_tag": tag,
+	fmt.Println("Available payload templates:")
+	for _, template := range loaded {
+		line := "- " + template.Name
+		if template.Function != "" {
+			line += fmt.Sprintf(" (%s)", template.Function)
+		}
+		if template.Description != "" {
+			line += ": " + template.Description
+		}
+		fmt.Println(line)
+		for _, variable := range template.Variables {
+			fmt.Printf("    %s", variable.Name)
+			if variable.Default != "" {
+				fmt.Printf(" [default: %s]", variable.Default)
+			}
+			if variable.Required {
+				fmt.Print(" (required)")
+			}
+			fmt.Println()
+		}
 	}
 
-	payloadJson, err := json.Marshal(payload)
+	return nil
+}
+
+type LambdaOptions struct {
+	Template       string
+	Vars           map[string]string
+	Payload        payload.Options
+	Schema         string
+	SkipValidation bool
+	InvocationType string
+	TailLogs       bool
+	Qualifier      string
+	Stream         bool
+	// Region defaults to the one in the function's ARN, if it is one.
+	Region string
+	// Local runs a locally built handler instead of calling AWS.
+	Local *emulator.Options
+	// Response formats and queries the response. Without either, the
+	// outcome and raw response are printed on one line.
+	Response output.ResponseOptions
+}
+
+func ExecuteLambda(session Session, lambdaName string, opts LambdaOptions) error {
+	if opts.Template != "" {
+		loaded, err := templates.LoadDefault()
+		if err != nil {
+			return err
+		}
+		template, err := templates.Find(loaded, opts.Template)
+		if err != nil {
+			return err
+		}
+		if lambdaName == "" {
+			lambdaName = template.Function
+		}
+		opts.Payload.Base, err = template.Document(opts.Vars)
+		if err != nil {
+			return err
+		}
+	}
+	if opts.Response.Query != "" && opts.Response.Format == "" {
+		opts.Response.Format = output.FormatJSON
+	}
+	if opts.Response.Format != "" {
+		if err := output.CheckResponseFormat(opts.Response.Format); err != nil {
+			return err
+		}
+	}
+	if opts.Local != nil {
+		return executeLocal(lambdaName, opts)
+	}
+	if lambdaName == "" {
+		return fmt.Errorf("no Lambda function given and template does not name one")
+	}
+
+	region := opts.Region
+	if region == "" {
+		region = awsinterface.RegionFromARN(lambdaName)
+	}
+	lambdaName, qualifier := awsinterface.SplitQualifier(lambdaName)
+	if opts.Qualifier != "" {
+		if qualifier != "" && qualifier != opts.Qualifier {
+			return fmt.Errorf("conflicting qualifiers %q and %q", qualifier, opts.Qualifier)
+		}
+		qualifier = opts.Qualifier
+	}
+
+	invocationType, err := awsinterface.ParseInvocationType(opts.InvocationType)
 	if err != nil {
-		logger.Error("Failed to marshal payload:", err)
-		return nil
+		return err
 	}
 
-	result, err := awsInterface.InvokeLambda(lambdaName, payloadJson)
+	payloadJson, err := payload.Build(opts.Payload)
+	if err != nil {
+		return err
+	}
+	// A schema given on the command line is checked before logging in; one
+	// found through the function's tags needs the connection.
+	if !opts.SkipValidation && opts.Schema != "" {
+		payloadSchema, err := schema.Load(opts.Schema)
+		if err != nil {
+			return err
+		}
+		if err := payloadSchema.Check(lambdaName, payloadJson); err != nil {
+			return err
+		}
+	}
+
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+	awsInterface = awsInterface.ForRegion(region)
+
+	if !opts.SkipValidation && opts.Schema == "" {
+		payloadSchema, err := loadSchema(awsInterface, lambdaName, "")
+		if err != nil {
+			return err
+		}
+		if payloadSchema != nil {
+			if err := payloadSchema.Check(lambdaName, payloadJson); err != nil {
+				return err
+			}
+		}
+	}
+
+	invokeOptions := awsinterface.InvokeOptions{
+		InvocationType: invocationType,
+		TailLogs:       opts.TailLogs,
+		Qualifier:      qualifier,
+	}
+	if opts.Stream {
+		return streamLambda(awsInterface, lambdaName, payloadJson, invokeOptions, opts.Response)
+	}
+
+	result, err := history.InvokeLambda(awsInterface, lambdaName, payloadJson, invokeOptions)
 	if err != nil {
 		return fmt.Errorf("failed to invoke Lambda: %v", err)
 	}
+	return printInvokeResult(lambdaName, result, opts.Response)
+}
+
+// printInvokeResult writes the logs to stderr and the outcome to stdout,
+// and returns an error for a function error. With a response format, only
+// the formatted response goes to stdout, or to stderr for a function error.
+func printInvokeResult(lambdaName string, result *awsinterface.InvokeResult, response output.ResponseOptions) error {
+	if result.LogResult != "" {
+		fmt.Fprintf(os.Stderr, "Logs:\n%s\n", strings.TrimRight(result.LogResult, "\n"))
+	}
+	if response.Format != "" {
+		formatted, err := printableResponse(result.Payload, response)
+		if err != nil {
+			return err
+		}
+		if result.Failed() {
+			fmt.Fprintf(os.Stderr, "Lambda function '%s' returned a function error (%s)\n", lambdaName, describeInvokeResult(result))
+			if formatted != "" {
+				fmt.Fprintln(os.Stderr, formatted)
+			}
+			return fmt.Errorf("function error from '%s': %s", lambdaName, result.FunctionError)
+		}
+		fmt.Fprintf(os.Stderr, "Lambda function '%s' invoked successfully (%s)\n", lambdaName, describeInvokeResult(result))
+		if formatted != "" {
+			fmt.Println(formatted)
+		}
+		return nil
+	}
+	if result.Failed() {
+		fmt.Fprintf(os.Stderr, "Lambda function '%s' returned a function error (%s). Result: %s\n", lambdaName, describeInvokeResult(result), string(result.Payload))
+		return fmt.Errorf("function error from '%s': %s", lambdaName, result.FunctionError)
+	}
 
-	fmt.Printf("Lambda function '%s' invoked successfully. Result: %s\n", lambdaName, string(result))
+	fmt.Printf("Lambda function '%s' invoked successfully (%s). Result: %s\n", lambdaName, describeInvokeResult(result), string(result.Payload))
 	return nil
 }
+
+func loadSchema(awsInterface *awsinterface.AWSInterface, functionName, location string) (*schema.Schema, error) {
+	if location != "" {
+		return schema.Load(location)
+	}
+
+	tags, err := awsInterface.GetFunctionTags(functionName)
+	if err != nil {
+		logger.Warn("Failed to read function tags, checking local schemas only:", err)
+	}
+	return schema.ForFunction(functionName, tags)
+}
@@ -0,0 +1,105 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/output"
+	"fmt"
+	"os"
+	"strconv"
+)
+
+// ShowConcurrency prints the function's reserved and provisioned
+// concurrency and the account's unreserved pool.
+func ShowConcurrency(session Session, functionName, format string) error {
+	if err := output.CheckFormat(format); err != nil {
+		return err
+	}
+	awsInterface, err := concurrencyInterface(session, functionName)
+	if err != nil {
+		return err
+	}
+	concurrency, err := awsInterface.GetConcurrency(functionName)
+	if err != nil {
+		return err
+	}
+	if format == output.FormatText {
+		return output.WriteRows(os.Stdout, output.ConcurrencyRows(concurrency))
+	}
+	return output.Write(os.Stdout, format, concurrency)
+}
+
+// ReserveConcurrency reserves executions for the function, or removes the
+// reservation when executions is "none".
+func ReserveConcurrency(session Session, functionName, executions string) error {
+	awsInterface, err := concurrencyInterface(session, functionName)
+	if err != nil {
+		return err
+	}
+	if executions == "none" {
+		if err := awsInterface.RemoveReservedConcurrency(functionName); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "%s now uses the unreserved pool\n", functionName)
+		return nil
+	}
+
+	count, err := parseExecutions(executions)
+	if err != nil {
+		return err
+	}
+	if err := awsInterface.SetReservedConcurrency(functionName, count); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Reserved %d concurrent executions for %s\n", count, functionName)
+	return nil
+}
+
+// ProvisionConcurrency pre-warms executions for a version or alias, given
+// as function:qualifier or separately, or removes the configuration when
+// executions is "none".
+func ProvisionConcurrency(session Session, lambdaName, qualifier, executions string) error {
+	functionName, nameQualifier := awsinterface.SplitQualifier(lambdaName)
+	if qualifier == "" {
+		qualifier = nameQualifier
+	}
+	if qualifier == "" {
+		return fmt.Errorf("no version or alias given, use function:qualifier or --qualifier")
+	}
+	awsInterface, err := concurrencyInterface(session, functionName)
+	if err != nil {
+		return err
+	}
+	if executions == "none" {
+		if err := awsInterface.RemoveProvisionedConcurrency(functionName, qualifier); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Removed provisioned concurrency from %s:%s\n", functionName, qualifier)
+		return nil
+	}
+
+	count, err := parseExecutions(executions)
+	if err != nil {
+		return err
+	}
+	provisioned, err := awsInterface.SetProvisionedConcurrency(functionName, qualifier, count)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Requested %d provisioned executions for %s:%s, status %s\n", provisioned.Requested, functionName, qualifier, provisioned.Status)
+	return nil
+}
+
+func concurrencyInterface(session Session, functionName string) (*awsinterface.AWSInterface, error) {
+	if functionName == "" {
+		return nil, fmt.Errorf("no Lambda function given")
+	}
+	return session.Connect()
+}
+
+func parseExecutions(value string) (int32, error) {
+	count, err := strconv.ParseInt(value, 10, 32)
+	if err != nil || count < 0 {
+		return 0, fmt.Errorf("invalid number of executions %q, expected a non-negative number or none", value)
+	}
+	return int32(count), nil
+}
@@ -0,0 +1,121 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/output"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// DeployLambda deploys a zip or image to functionName, printing each step to
+// stderr and the result to stdout.
+func DeployLambda(session Session, functionName string, opts awsinterface.DeployOptions, format string) error {
+	if err := output.CheckFormat(format); err != nil {
+		return err
+	}
+	if functionName == "" {
+		return fmt.Errorf("no Lambda function given")
+	}
+
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+
+	opts.Progress = func(message string) {
+		fmt.Fprintf(os.Stderr, "%s %s\n", time.Now().Format("15:04:05"), message)
+	}
+	result, err := awsInterface.Deploy(functionName, opts)
+	if err != nil {
+		return err
+	}
+
+	if format == output.FormatText {
+		return output.WriteRows(os.Stdout, deployResultRows(result))
+	}
+	return output.Write(os.Stdout, format, result)
+}
+
+func deployResultRows(result *awsinterface.DeployResult) []output.Row {
+	rows := []output.Row{
+		{Label: "Function", Value: result.FunctionName},
+		{Label: "Previous SHA-256", Value: result.PreviousSha256},
+		{Label: "Code SHA-256", Value: result.CodeSha256},
+	}
+	if result.Version != "" {
+		rows = append(rows, output.Row{Label: "Version", Value: result.Version})
+	}
+	if result.Alias != "" {
+		rows = append(rows, output.Row{Label: "Alias", Value: result.Alias})
+	}
+	return rows
+}
+
+type deployProgressMsg struct {
+	message string
+}
+type deployDoneMsg struct {
+	result *awsinterface.DeployResult
+	err    error
+}
+
+// startDeploy runs the deployment in the background and feeds its progress
+// back to the model through deployEvents.
+func (m model) startDeploy(artifact, alias string) (model, tea.Cmd) {
+	opts := awsinterface.DeployOptions{Alias: alias, Publish: alias != ""}
+	if strings.HasSuffix(strings.ToLower(artifact), ".zip") {
+		opts.ZipFile = artifact
+	} else {
+		opts.ImageURI = artifact
+	}
+
+	events := make(chan tea.Msg)
+	opts.Progress = func(message string) {
+		events <- deployProgressMsg{message: message}
+	}
+	awsInterface := m.awsInterface
+	functionName := m.selectedLambda
+	go func() {
+		result, err := awsInterface.Deploy(functionName, opts)
+		events <- deployDoneMsg{result: result, err: err}
+		close(events)
+	}()
+
+	m.state = "deploying"
+	m.deployLog = nil
+	m.deployResult = nil
+	m.deployEvents = events
+	m.err = nil
+	return m, waitForDeployEvent(events)
+}
+
+func waitForDeployEvent(events <-chan tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		return <-events
+	}
+}
+
+func (m model) deployView() string {
+	var b strings.Builder
+	b.WriteString(headerStyle.Render(fmt.Sprintf("Deploying '%s'", m.selectedLambda)) + "\n\n")
+	for _, line := range m.deployLog {
+		b.WriteString(logStyle.Render(line) + "\n")
+	}
+	switch {
+	case m.err != nil:
+		b.WriteString("\n" + errorStyle.Render(fmt.Sprintf("Deployment failed: %v", m.err)) + "\n")
+	case m.deployResult != nil:
+		b.WriteString("\n")
+		_ = output.WriteRows(&b, deployResultRows(m.deployResult))
+	default:
+		b.WriteString("\nWorking...\n")
+	}
+	if m.deployEvents == nil {
+		b.WriteString("\n(press esc to go back, q to quit)")
+	}
+	return b.String()
+}
@@ -0,0 +1,86 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/configdiff"
+	"aws_utility/pkg/output"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+type DiffOptions struct {
+	Values      bool
+	ShowSecrets bool
+	Ignore      []string
+	Format      string
+}
+
+// DiffFunctions compares the configuration of two functions, given as
+// [ACCOUNT/ROLE/]FUNCTION[:QUALIFIER], and reports whether they differ.
+// Targets without an account are read with the session's account and role.
+func DiffFunctions(session Session, leftSpec, rightSpec string, opts DiffOptions) (bool, error) {
+	if err := output.CheckFormat(opts.Format); err != nil {
+		return false, err
+	}
+	left, err := configdiff.ParseTarget(leftSpec)
+	if err != nil {
+		return false, err
+	}
+	right, err := configdiff.ParseTarget(rightSpec)
+	if err != nil {
+		return false, err
+	}
+
+	awsInterface, err := login(session.StartURL)
+	if err != nil {
+		return false, err
+	}
+	leftInterface, err := targetInterface(awsInterface, session, left)
+	if err != nil {
+		return false, err
+	}
+	rightInterface, err := targetInterface(awsInterface, session, right)
+	if err != nil {
+		return false, err
+	}
+
+	report, err := configdiff.Diff(leftInterface, left, rightInterface, right, configdiff.Options{Values: opts.Values, Ignore: opts.Ignore})
+	if err != nil {
+		return false, err
+	}
+	if !opts.ShowSecrets {
+		report.Differences = configdiff.Masked(report.Differences)
+	}
+
+	if opts.Format != output.FormatText {
+		return len(report.Differences) > 0, output.Write(os.Stdout, opts.Format, report)
+	}
+	if len(report.Differences) == 0 {
+		fmt.Printf("%s and %s have the same configuration\n", left, right)
+		return false, nil
+	}
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(writer, "KEY\t%s\t%s\n", left, right)
+	for _, difference := range report.Differences {
+		fmt.Fprintf(writer, "%s\t%s\t%s\n", difference.Key, orMissing(difference.Left, difference.Kind == configdiff.KindOnlyRight), orMissing(difference.Right, difference.Kind == configdiff.KindOnlyLeft))
+	}
+	return true, writer.Flush()
+}
+
+func targetInterface(awsInterface *awsinterface.AWSInterface, session Session, target configdiff.Target) (*awsinterface.AWSInterface, error) {
+	if target.AccountID == "" {
+		if session.AccountID == "" || session.RoleName == "" {
+			return nil, fmt.Errorf("%s has no account, give it as ACCOUNT/ROLE/FUNCTION or pass --account and --role", target)
+		}
+		return awsInterface.ForRole(session.AccountID, session.RoleName)
+	}
+	return awsInterface.ForRole(target.AccountID, target.RoleName)
+}
+
+func orMissing(value string, missing bool) string {
+	if missing {
+		return "(missing)"
+	}
+	return value
+}
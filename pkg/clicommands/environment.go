@@ -0,0 +1,155 @@
+package clicommands
+
+import (
+	"aws_utility/pkg/envvars"
+	"aws_utility/pkg/output"
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+type EnvOptions struct {
+	ShowSecrets bool
+	// Yes applies the changes without asking; DryRun only prints them.
+	Yes    bool
+	DryRun bool
+	// Replace makes an import drop variables missing from the file.
+	Replace bool
+}
+
+// ShowEnvironment prints functionName's environment variables, masking the
+// values of keys that look secret.
+func ShowEnvironment(session Session, functionName, format string, showSecrets bool) error {
+	if err := output.CheckFormat(format); err != nil {
+		return err
+	}
+	if functionName == "" {
+		return fmt.Errorf("no Lambda function given")
+	}
+
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+	environment, err := awsInterface.GetEnvironment(functionName)
+	if err != nil {
+		return err
+	}
+
+	variables := make(map[string]string, len(environment.Variables))
+	for key, value := range environment.Variables {
+		variables[key] = envvars.Display(key, value, showSecrets)
+	}
+	if format != output.FormatText {
+		return output.Write(os.Stdout, format, variables)
+	}
+
+	keys := make([]string, 0, len(variables))
+	for key := range variables {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	rows := make([]output.Row, len(keys))
+	for i, key := range keys {
+		rows[i] = output.Row{Label: key, Value: variables[key]}
+	}
+	return output.WriteRows(os.Stdout, rows)
+}
+
+// SetEnvironment sets KEY=VALUE assignments on functionName.
+func SetEnvironment(session Session, functionName string, assignments []string, opts EnvOptions) error {
+	set, err := envvars.ParseAssignments(assignments)
+	if err != nil {
+		return err
+	}
+	if len(set) == 0 {
+		return fmt.Errorf("no KEY=VALUE assignments given")
+	}
+	return updateEnvironment(session, functionName, opts, func(current map[string]string) map[string]string {
+		return envvars.Merge(current, set, nil)
+	})
+}
+
+// UnsetEnvironment removes keys from functionName's environment.
+func UnsetEnvironment(session Session, functionName string, keys []string, opts EnvOptions) error {
+	if len(keys) == 0 {
+		return fmt.Errorf("no variable names given")
+	}
+	return updateEnvironment(session, functionName, opts, func(current map[string]string) map[string]string {
+		return envvars.Merge(current, nil, keys)
+	})
+}
+
+// ImportEnvironment merges a dotenv file into functionName's environment,
+// or replaces it entirely with opts.Replace.
+func ImportEnvironment(session Session, functionName, path string, opts EnvOptions) error {
+	if path == "" {
+		return fmt.Errorf("no dotenv file given")
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("failed to open dotenv file: %v", err)
+	}
+	defer file.Close()
+	imported, err := envvars.ParseDotenv(file)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %v", path, err)
+	}
+
+	return updateEnvironment(session, functionName, opts, func(current map[string]string) map[string]string {
+		if opts.Replace {
+			return imported
+		}
+		return envvars.Merge(current, imported, nil)
+	})
+}
+
+// updateEnvironment reads the current environment, previews the changes
+// change makes to it and, once confirmed, writes them back at the revision
+// that was read.
+func updateEnvironment(session Session, functionName string, opts EnvOptions, change func(map[string]string) map[string]string) error {
+	if functionName == "" {
+		return fmt.Errorf("no Lambda function given")
+	}
+
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+	environment, err := awsInterface.GetEnvironment(functionName)
+	if err != nil {
+		return err
+	}
+
+	updated := change(environment.Variables)
+	changes := envvars.Diff(environment.Variables, updated)
+	if len(changes) == 0 {
+		fmt.Fprintln(os.Stderr, "No changes.")
+		return nil
+	}
+	fmt.Printf("Changes to the environment of %s:\n", functionName)
+	for _, change := range changes {
+		fmt.Println("  " + change.Format(opts.ShowSecrets))
+	}
+	if opts.DryRun {
+		return nil
+	}
+	if !opts.Yes && !confirm("Apply these changes?") {
+		return fmt.Errorf("aborted")
+	}
+
+	if err := awsInterface.UpdateEnvironment(functionName, updated, environment.RevisionID); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Updated %d variable(s) on %s\n", len(changes), functionName)
+	return nil
+}
+
+func confirm(question string) bool {
+	fmt.Fprintf(os.Stderr, "%s [y/N] ", question)
+	answer, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes"
+}
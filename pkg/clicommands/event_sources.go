@@ -0,0 +1,56 @@
+package clicommands
+
+import (
+	"aws_utility/pkg/output"
+	"fmt"
+	"os"
+	"text/tabwriter"
+)
+
+// ListEventSources prints the function's event source mappings.
+func ListEventSources(session Session, functionName, format string) error {
+	if err := output.CheckFormat(format); err != nil {
+		return err
+	}
+	if functionName == "" {
+		return fmt.Errorf("no Lambda function given")
+	}
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+
+	mappings, err := awsInterface.ListEventSourceMappings(functionName)
+	if err != nil {
+		return err
+	}
+	if format != output.FormatText {
+		return output.Write(os.Stdout, format, mappings)
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "UUID\tSOURCE\tSTATE\tBATCH SIZE\tLAST RESULT\tLAST MODIFIED\tEVENT SOURCE")
+	for _, mapping := range mappings {
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%d\t%s\t%s\t%s\n",
+			mapping.UUID, mapping.Source, mapping.State, mapping.BatchSize, mapping.LastProcessingResult, mapping.LastModified, mapping.EventSourceArn)
+	}
+	return writer.Flush()
+}
+
+// SetEventSourceEnabled pauses or resumes the mapping with the given UUID.
+func SetEventSourceEnabled(session Session, uuid string, enabled bool) error {
+	if uuid == "" {
+		return fmt.Errorf("no event source mapping UUID given")
+	}
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+
+	mapping, err := awsInterface.SetEventSourceMappingEnabled(uuid, enabled)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "%s is now %s\n", mapping.UUID, mapping.State)
+	return nil
+}
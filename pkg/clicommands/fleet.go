@@ -0,0 +1,128 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/fleet"
+	"aws_utility/pkg/output"
+	"aws_utility/pkg/payload"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+)
+
+type FleetOptions struct {
+	Selector       fleet.Selector
+	RoleName       string
+	Concurrency    int
+	Payload        payload.Options
+	InvocationType string
+	Qualifier      string
+	Format         string
+}
+
+// RunFleet invokes functionName in every selected account and prints one
+// result per account. It fails if any account did not succeed.
+func RunFleet(profile, functionName string, opts FleetOptions) error {
+	if err := output.CheckFormat(opts.Format); err != nil {
+		return err
+	}
+	if functionName == "" {
+		return fmt.Errorf("no Lambda function given")
+	}
+	if opts.RoleName == "" {
+		return fmt.Errorf("a role name is required")
+	}
+
+	functionName, qualifier := awsinterface.SplitQualifier(functionName)
+	if opts.Qualifier != "" {
+		if qualifier != "" && qualifier != opts.Qualifier {
+			return fmt.Errorf("conflicting qualifiers %q and %q", qualifier, opts.Qualifier)
+		}
+		qualifier = opts.Qualifier
+	}
+	invocationType, err := awsinterface.ParseInvocationType(opts.InvocationType)
+	if err != nil {
+		return err
+	}
+	payloadJson, err := payload.Build(opts.Payload)
+	if err != nil {
+		return err
+	}
+
+	awsInterface, err := login(profile)
+	if err != nil {
+		return err
+	}
+	accessible, err := awsInterface.ListAccounts()
+	if err != nil {
+		return err
+	}
+	accounts, err := opts.Selector.Select(accessible)
+	if err != nil {
+		return err
+	}
+
+	finished := 0
+	results := fleet.Run(awsInterface, accounts, functionName, payloadJson, fleet.Options{
+		RoleName:    opts.RoleName,
+		Concurrency: opts.Concurrency,
+		Invoke: awsinterface.InvokeOptions{
+			InvocationType: invocationType,
+			Qualifier:      qualifier,
+		},
+		OnResult: func(result fleet.Result) {
+			finished++
+			fmt.Fprintf(os.Stderr, "[%d/%d] %s (%s): %s (%s)\n", finished, len(accounts), result.AccountName, result.AccountID, result.Status, result.Duration())
+		},
+	})
+
+	if opts.Format == output.FormatText {
+		err = writeFleetSummary(results)
+	} else {
+		err = output.Write(os.Stdout, opts.Format, results)
+	}
+	if err != nil {
+		return err
+	}
+
+	failed := 0
+	for _, result := range results {
+		if !result.Succeeded() {
+			failed++
+		}
+	}
+	if failed > 0 {
+		return fmt.Errorf("%d of %d accounts did not succeed", failed, len(results))
+	}
+	return nil
+}
+
+func writeFleetSummary(results []fleet.Result) error {
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ACCOUNT\tNAME\tSTATUS\tVERSION\tDURATION\tRESPONSE OR ERROR")
+	for _, result := range results {
+		detail := string(result.Response)
+		if result.FunctionError != "" {
+			detail = result.FunctionError + ": " + detail
+		}
+		if result.Error != "" {
+			detail = result.Error
+		}
+		fmt.Fprintf(writer, "%s\t%s\t%s\t%s\t%s\t%s\n",
+			result.AccountID, result.AccountName, result.Status, result.ExecutedVersion, result.Duration(), detail)
+	}
+	if err := writer.Flush(); err != nil {
+		return err
+	}
+
+	counts := fleet.Summarize(results)
+	statuses := make([]string, 0, len(counts))
+	for status, count := range counts {
+		statuses = append(statuses, fmt.Sprintf("%d %s", count, status))
+	}
+	sort.Strings(statuses)
+	fmt.Printf("\n%d accounts: %s\n", len(results), strings.Join(statuses, ", "))
+	return nil
+}
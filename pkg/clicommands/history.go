@@ -0,0 +1,192 @@
+package clicommands
+
+import (
+	"aws_utility/pkg/history"
+	"aws_utility/pkg/output"
+	"aws_utility/pkg/payload"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type historyMsg struct {
+	entries []history.Entry
+	err     error
+}
+
+func fetchHistory() tea.Msg {
+	entries, err := history.Load()
+	return historyMsg{entries: entries, err: err}
+}
+
+// historyItems lists entries newest first; each item's title starts with
+// the entry ID so selectedHistoryEntry can map it back.
+func historyItems(entries []history.Entry) []list.Item {
+	items := make([]list.Item, 0, len(entries))
+	for i := len(entries) - 1; i >= 0; i-- {
+		entry := entries[i]
+		items = append(items, item{
+			title: fmt.Sprintf("#%d %s", entry.ID, entry.QualifiedFunction()),
+			desc:  describeHistoryEntry(entry),
+		})
+	}
+	return items
+}
+
+func describeHistoryEntry(entry history.Entry) string {
+	parts := []string{entry.Timestamp.Local().Format("2006-01-02 15:04:05"), entry.Status, entry.Duration().String()}
+	if entry.AccountID != "" {
+		parts = append(parts, entry.AccountID+"/"+entry.RoleName)
+	}
+	return strings.Join(parts, " · ")
+}
+
+func (m model) selectedHistoryEntry() (history.Entry, bool) {
+	i, ok := m.historyList.SelectedItem().(item)
+	if !ok {
+		return history.Entry{}, false
+	}
+	id, _, _ := strings.Cut(strings.TrimPrefix(i.title, "#"), " ")
+	entry, err := history.Find(m.historyEntries, id)
+	if err != nil {
+		return history.Entry{}, false
+	}
+	return *entry, true
+}
+
+// loadHistoryEntry targets the entry's function and qualifier and either
+// opens its payload in the editor or re-invokes it unchanged.
+func (m model) loadHistoryEntry(entry history.Entry, invokeNow bool) (tea.Model, tea.Cmd) {
+	m.selectedLambda = entry.Function
+	m.qualifier = entry.Qualifier
+	m.stream = entry.Streamed
+	m.schema = nil
+	m.err = nil
+	m.loadTemplates()
+	m.payloadEditor.SetValue(payload.Indent([]byte(entry.Payload)))
+
+	if invokeNow {
+		m.payloadJson = []byte(entry.Payload)
+		return m, m.invokeCmd()
+	}
+	m.state = "payload_editor"
+	return m, tea.Batch(m.payloadEditor.Focus(), m.fetchSchema)
+}
+
+func ListHistory(functionName string, limit int) error {
+	entries, err := history.Load()
+	if err != nil {
+		return err
+	}
+
+	writer := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "ID\tTIME\tFUNCTION\tSTATUS\tDURATION\tACCOUNT\tROLE\tREGION")
+	shown := 0
+	for i := len(entries) - 1; i >= 0 && (limit <= 0 || shown < limit); i-- {
+		entry := entries[i]
+		if functionName != "" && entry.Function != functionName {
+			continue
+		}
+		fmt.Fprintf(writer, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			entry.ID, entry.Timestamp.Local().Format("2006-01-02 15:04:05"), entry.QualifiedFunction(), entry.Status,
+			entry.Duration(), entry.AccountID, entry.RoleName, entry.Region)
+		shown++
+	}
+	return writer.Flush()
+}
+
+func ShowHistory(id, format string) error {
+	if err := output.CheckFormat(format); err != nil {
+		return err
+	}
+
+	entries, err := history.Load()
+	if err != nil {
+		return err
+	}
+	entry, err := history.Find(entries, id)
+	if err != nil {
+		return err
+	}
+
+	if format != output.FormatText {
+		return output.Write(os.Stdout, format, entry)
+	}
+
+	rows := []output.Row{
+		{Label: "ID", Value: strconv.Itoa(entry.ID)},
+		{Label: "Time", Value: entry.Timestamp.Local().Format("2006-01-02 15:04:05 MST")},
+		{Label: "Start URL", Value: entry.StartURL},
+		{Label: "Account", Value: entry.AccountID},
+		{Label: "Role", Value: entry.RoleName},
+		{Label: "Region", Value: entry.Region},
+		{Label: "Function", Value: entry.QualifiedFunction()},
+		{Label: "Invocation type", Value: entry.InvocationType},
+		{Label: "Status", Value: entry.Status},
+		{Label: "Status code", Value: strconv.Itoa(int(entry.StatusCode))},
+		{Label: "Duration", Value: entry.Duration().String()},
+		{Label: "Function error", Value: entry.FunctionError},
+		{Label: "Error", Value: entry.Error},
+	}
+	if entry.Streamed {
+		rows = append(rows, output.Row{Label: "Streamed", Value: "yes"})
+	}
+	var nonEmpty []output.Row
+	for _, row := range rows {
+		if row.Value != "" {
+			nonEmpty = append(nonEmpty, row)
+		}
+	}
+	if err := output.WriteRows(os.Stdout, nonEmpty); err != nil {
+		return err
+	}
+
+	fmt.Printf("\nPayload:\n%s\n", payload.Indent([]byte(entry.Payload)))
+	if entry.Response != "" {
+		fmt.Printf("\nResponse:\n%s\n", payload.Indent([]byte(entry.Response)))
+	}
+	return nil
+}
+
+// ReplayHistory re-invokes a recorded entry as the account and role, and in
+// the region, it was recorded with, falling back to the session's for
+// entries that do not record them. opts.Payload is applied on top of the
+// recorded payload, and an empty qualifier or invocation type falls back to
+// the recorded one.
+func ReplayHistory(session Session, id string, opts LambdaOptions) error {
+	entries, err := history.Load()
+	if err != nil {
+		return err
+	}
+	entry, err := history.Find(entries, id)
+	if err != nil {
+		return err
+	}
+
+	opts.Payload.Base, err = payload.Parse([]byte(entry.Payload))
+	if err != nil {
+		return fmt.Errorf("history entry %d: %v", entry.ID, err)
+	}
+	if opts.Qualifier == "" {
+		opts.Qualifier = entry.Qualifier
+	}
+	if opts.InvocationType == "" {
+		opts.InvocationType = entry.InvocationType
+	}
+	if opts.Region == "" {
+		opts.Region = entry.Region
+	}
+	if entry.StartURL != "" {
+		session.StartURL = entry.StartURL
+	}
+	if entry.AccountID != "" && entry.RoleName != "" {
+		session.AccountID = entry.AccountID
+		session.RoleName = entry.RoleName
+	}
+	return ExecuteLambda(session, entry.Function, opts)
+}
@@ -0,0 +1,142 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/fleet"
+	"aws_utility/pkg/inventory"
+	"fmt"
+	"io"
+	"os"
+)
+
+type InventoryOptions struct {
+	// Selector picks accounts to walk with RoleName; when it selects nothing
+	// only the session's account is exported.
+	Selector   fleet.Selector
+	RoleName   string
+	Regions    []string
+	AllRegions bool
+	Filters    []string
+	Format     string
+	Columns    []string
+	SortBy     string
+	OutputFile string
+}
+
+// ExportInventory writes every matching function in the selected accounts
+// and regions, one account at a time, as CSV, JSON, NDJSON or Markdown.
+// Records are sorted within each account, not across accounts.
+func ExportInventory(session Session, opts InventoryOptions) error {
+	columns, err := inventory.ParseColumns(opts.Columns)
+	if err != nil {
+		return err
+	}
+	less, err := inventory.SortFunc(opts.SortBy)
+	if err != nil {
+		return err
+	}
+	filter, err := awsinterface.ParseFilter(opts.Filters)
+	if err != nil {
+		return err
+	}
+
+	var out io.Writer = os.Stdout
+	if opts.OutputFile != "" && opts.OutputFile != "-" {
+		file, err := os.Create(opts.OutputFile)
+		if err != nil {
+			return fmt.Errorf("failed to create %s: %v", opts.OutputFile, err)
+		}
+		defer file.Close()
+		out = file
+	}
+	writer, err := inventory.NewWriter(out, opts.Format, columns)
+	if err != nil {
+		return err
+	}
+
+	sources, err := inventorySources(session, opts)
+	if err != nil {
+		return err
+	}
+
+	count := 0
+	accounts := map[string]bool{}
+	err = inventory.Walk(sources, inventory.Options{
+		Filter:   filter,
+		WithTags: inventory.NeedsTags(columns, opts.SortBy),
+		Less:     less,
+		Warn: func(err error) {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+		},
+	}, func(record inventory.Record) error {
+		count++
+		accounts[record.AccountID] = true
+		return writer.Write(record)
+	})
+	if closeErr := writer.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Exported %d function(s) from %d account(s)\n", count, len(accounts))
+	return nil
+}
+
+// inventorySources lists the accounts to walk. Each one assumes the role
+// and looks up its regions only when Walk reaches it.
+func inventorySources(session Session, opts InventoryOptions) ([]inventory.Source, error) {
+	selector := opts.Selector
+	if !selector.All && len(selector.AccountIDs) == 0 && selector.NameRegex == "" {
+		return []inventory.Source{{
+			AccountID: session.AccountID,
+			Open: func() (*awsinterface.AWSInterface, []string, error) {
+				awsInterface, err := session.Connect()
+				if err != nil {
+					return nil, nil, err
+				}
+				return awsInterface, inventoryRegions(awsInterface, opts), nil
+			},
+		}}, nil
+	}
+
+	if opts.RoleName == "" {
+		return nil, fmt.Errorf("a role name is required to read other accounts")
+	}
+	awsInterface, err := login(session.StartURL)
+	if err != nil {
+		return nil, err
+	}
+	accessible, err := awsInterface.ListAccounts()
+	if err != nil {
+		return nil, err
+	}
+	accounts, err := selector.Select(accessible)
+	if err != nil {
+		return nil, err
+	}
+
+	sources := make([]inventory.Source, len(accounts))
+	for i, account := range accounts {
+		accountID := account.AccountID
+		sources[i] = inventory.Source{
+			AccountID:   accountID,
+			AccountName: account.AccountName,
+			Open: func() (*awsinterface.AWSInterface, []string, error) {
+				accountInterface, err := awsInterface.ForRole(accountID, opts.RoleName)
+				if err != nil {
+					return nil, nil, err
+				}
+				return accountInterface, inventoryRegions(accountInterface, opts), nil
+			},
+		}
+	}
+	return sources, nil
+}
+
+func inventoryRegions(awsInterface *awsinterface.AWSInterface, opts InventoryOptions) []string {
+	if opts.AllRegions || len(opts.Regions) > 0 {
+		return awsInterface.Regions(opts.Regions)
+	}
+	return []string{awsInterface.Region()}
+}
@@ -0,0 +1,106 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/emulator"
+	"aws_utility/pkg/envvars"
+	"aws_utility/pkg/logger"
+	"aws_utility/pkg/payload"
+	"aws_utility/pkg/schema"
+	"fmt"
+	"os"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// executeLocal runs the payload through a locally built handler. Only
+// local schemas are checked, so nothing is sent to AWS.
+func executeLocal(lambdaName string, opts LambdaOptions) error {
+	if opts.Stream {
+		return fmt.Errorf("response streaming is not supported for local invocations")
+	}
+	invocationType, err := awsinterface.ParseInvocationType(opts.InvocationType)
+	if err != nil {
+		return err
+	}
+	lambdaName, _ = awsinterface.SplitQualifier(lambdaName)
+
+	payloadJson, err := payload.Build(opts.Payload)
+	if err != nil {
+		return err
+	}
+	if !opts.SkipValidation {
+		var payloadSchema *schema.Schema
+		if opts.Schema != "" {
+			payloadSchema, err = schema.Load(opts.Schema)
+		} else if lambdaName != "" {
+			payloadSchema, err = schema.ForFunction(lambdaName, nil)
+		}
+		if err != nil {
+			return err
+		}
+		if payloadSchema != nil {
+			if err := payloadSchema.Check(lambdaName, payloadJson); err != nil {
+				return err
+			}
+		}
+	}
+
+	local := *opts.Local
+	local.InvocationType = invocationType
+	if local.Region == "" {
+		local.Region = opts.Region
+	}
+	result, err := emulator.Invoke(lambdaName, payloadJson, local)
+	if err != nil {
+		return fmt.Errorf("failed to invoke local handler: %v", err)
+	}
+	if lambdaName == "" {
+		lambdaName = local.Bootstrap
+	}
+	return printInvokeResult(lambdaName, result, opts.Response)
+}
+
+// LocalEnvironment merges variables from a .env file with KEY=VALUE
+// assignments, which take precedence.
+func LocalEnvironment(envFile string, assignments []string) (map[string]string, error) {
+	env := map[string]string{}
+	if envFile != "" {
+		file, err := os.Open(envFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open %s: %v", envFile, err)
+		}
+		defer file.Close()
+		if env, err = envvars.ParseDotenv(file); err != nil {
+			return nil, err
+		}
+	}
+	vars, err := envvars.ParseAssignments(assignments)
+	if err != nil {
+		return nil, err
+	}
+	for key, value := range vars {
+		env[key] = value
+	}
+	return env, nil
+}
+
+// invokeLocal runs the payload through the bootstrap chosen in the TUI.
+// Local runs are not recorded in the invocation history.
+func (m *model) invokeLocal() tea.Msg {
+	result, err := emulator.Invoke(m.selectedLambda, m.payloadJson, emulator.Options{Bootstrap: m.localBootstrap})
+	if err != nil {
+		logger.Error("Failed to invoke local handler:", err)
+		return lambdaInvokeResultMsg{err: err}
+	}
+	logger.Info("Local handler invoked. Result:", string(result.Payload))
+	return lambdaInvokeResultMsg{result: result}
+}
+
+// target describes where invocations go, for the payload mode screen.
+func (m model) target() string {
+	if m.localBootstrap == "" {
+		return "off"
+	}
+	return m.localBootstrap
+}
@@ -0,0 +1,298 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/history"
+	"aws_utility/pkg/logger"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+const (
+	// defaultLogWindow is how far back logs are read when no start is given
+	// and not following.
+	defaultLogWindow = 10 * time.Minute
+	// maxLogLines bounds the lines kept by the logs view when following a
+	// busy function.
+	maxLogLines = 2000
+)
+
+type LogsOptions struct {
+	Region   string
+	LogGroup string
+	// Since and Until take a duration before now, such as 15m, or a time.
+	// Since defaults to 10 minutes ago, or to now when following.
+	Since         string
+	Until         string
+	FilterPattern string
+	RequestID     string
+	// Invocation is a history ID, or "last", whose function and request ID
+	// are used.
+	Invocation string
+	Follow     bool
+	Interval   time.Duration
+	Limit      int
+	// Format is text, or json for one object per line.
+	Format string
+}
+
+// ShowLogs prints the function's CloudWatch log events, and with Follow
+// keeps printing new ones until interrupted.
+func ShowLogs(session Session, lambdaName string, opts LogsOptions) error {
+	if opts.Format != "text" && opts.Format != "json" {
+		return fmt.Errorf("invalid logs format %q, expected text or json", opts.Format)
+	}
+
+	now := time.Now()
+	query := awsinterface.LogQuery{
+		LogGroup:      opts.LogGroup,
+		FilterPattern: opts.FilterPattern,
+		RequestID:     opts.RequestID,
+	}
+	var err error
+	if query.Start, err = parseLogTime(opts.Since, now); err != nil {
+		return err
+	}
+	if query.End, err = parseLogTime(opts.Until, now); err != nil {
+		return err
+	}
+
+	region := opts.Region
+	if opts.Invocation != "" {
+		entry, err := findInvocation(opts.Invocation)
+		if err != nil {
+			return err
+		}
+		if entry.RequestID == "" {
+			return fmt.Errorf("history entry %d has no request ID", entry.ID)
+		}
+		if lambdaName == "" {
+			lambdaName = entry.Function
+		}
+		if region == "" {
+			region = entry.Region
+		}
+		invocation := entry.LogQuery()
+		query.RequestID = invocation.RequestID
+		if opts.Since == "" {
+			query.Start = invocation.Start
+		}
+	}
+	if query.Start.IsZero() && !opts.Follow {
+		query.Start = now.Add(-defaultLogWindow)
+	}
+	if lambdaName == "" && query.LogGroup == "" {
+		return fmt.Errorf("no Lambda function or log group given")
+	}
+	if region == "" {
+		region = awsinterface.RegionFromARN(lambdaName)
+	}
+
+	awsInterface, err := session.Connect()
+	if err != nil {
+		return err
+	}
+	awsInterface = awsInterface.ForRegion(region)
+	if query.LogGroup == "" {
+		if query.LogGroup, err = awsInterface.FunctionLogGroup(lambdaName); err != nil {
+			return err
+		}
+	}
+
+	if !opts.Follow {
+		events, err := awsInterface.FilterLogs(query, opts.Limit)
+		if err != nil {
+			return fmt.Errorf("failed to read %s: %v", query.LogGroup, err)
+		}
+		for _, event := range events {
+			if err := printLogEvent(event, opts.Format); err != nil {
+				return err
+			}
+		}
+		if len(events) == 0 {
+			fmt.Fprintf(os.Stderr, "No log events in %s\n", query.LogGroup)
+		}
+		return nil
+	}
+
+	if !query.End.IsZero() {
+		return fmt.Errorf("--until cannot be used with --follow")
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 2 * time.Second
+	}
+	fmt.Fprintf(os.Stderr, "Following %s (press ctrl+c to stop)\n", query.LogGroup)
+	for batch := range awsInterface.FollowLogs(context.Background(), query, interval) {
+		if batch.Err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", batch.Err)
+		}
+		for _, event := range batch.Events {
+			if err := printLogEvent(event, opts.Format); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func findInvocation(id string) (*history.Entry, error) {
+	entries, err := history.Load()
+	if err != nil {
+		return nil, err
+	}
+	if id == "last" {
+		return history.Last(entries)
+	}
+	return history.Find(entries, id)
+}
+
+func printLogEvent(event awsinterface.LogEvent, format string) error {
+	if format == "json" {
+		line, err := json.Marshal(event)
+		if err != nil {
+			return err
+		}
+		_, err = fmt.Println(string(line))
+		return err
+	}
+	_, err := fmt.Println(event.Line())
+	return err
+}
+
+// parseLogTime reads a duration before now (15m, 2h), an RFC 3339 time or
+// a local "2006-01-02 15:04" time. An empty value gives the zero time.
+func parseLogTime(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if ago, err := time.ParseDuration(value); err == nil {
+		return now.Add(-ago), nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04", value, time.Local); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q, expected a duration such as 15m, an RFC 3339 time or YYYY-MM-DD HH:MM", value)
+}
+
+type logsStartedMsg struct {
+	batches <-chan awsinterface.LogBatch
+	cancel  context.CancelFunc
+	group   string
+	err     error
+}
+type logBatchMsg struct {
+	// batches tells batches of an earlier, closed view apart.
+	batches <-chan awsinterface.LogBatch
+	batch   awsinterface.LogBatch
+}
+type logsDoneMsg struct{}
+
+// followLogs resolves the function's log group and starts following it.
+func (m model) followLogs(query awsinterface.LogQuery) tea.Cmd {
+	awsInterface, functionName := m.awsInterface, m.selectedLambda
+	return func() tea.Msg {
+		group, err := awsInterface.FunctionLogGroup(functionName)
+		if err != nil {
+			logger.Error("Failed to find the function's log group:", err)
+			return logsStartedMsg{err: err}
+		}
+		query.LogGroup = group
+		ctx, cancel := context.WithCancel(context.Background())
+		return logsStartedMsg{batches: awsInterface.FollowLogs(ctx, query, 2*time.Second), cancel: cancel, group: group}
+	}
+}
+
+func waitForLogBatch(batches <-chan awsinterface.LogBatch) tea.Cmd {
+	return func() tea.Msg {
+		batch, ok := <-batches
+		if !ok {
+			return logsDoneMsg{}
+		}
+		return logBatchMsg{batches: batches, batch: batch}
+	}
+}
+
+// openLogs switches to the log viewport, remembering the screen to go back
+// to.
+func (m model) openLogs(query awsinterface.LogQuery) (tea.Model, tea.Cmd) {
+	m.logReturn = m.state
+	m.logQuery = query
+	m.logGroup = ""
+	m.logLines = nil
+	m.err = nil
+	m.viewport.SetContent("")
+	m.state = "logs"
+	return m, m.followLogs(query)
+}
+
+func (m model) closeLogs() model {
+	if m.logCancel != nil {
+		m.logCancel()
+		m.logCancel = nil
+	}
+	m.logBatches = nil
+	m.err = nil
+	m.state = m.logReturn
+	if m.state == "streaming" {
+		// The stream and the logs share the viewport.
+		m.viewport.SetContent(m.streamOutput)
+		m.viewport.GotoBottom()
+	}
+	return m
+}
+
+func (m model) addLogBatch(batch awsinterface.LogBatch) model {
+	if batch.Err != nil {
+		logger.Warn("Failed to read logs:", batch.Err)
+		m.err = batch.Err
+	} else {
+		m.err = nil
+	}
+	if len(batch.Events) == 0 {
+		return m
+	}
+	atBottom := m.viewport.AtBottom() || len(m.logLines) == 0
+	for _, event := range batch.Events {
+		m.logLines = append(m.logLines, event.Line())
+	}
+	if len(m.logLines) > maxLogLines {
+		m.logLines = append([]string(nil), m.logLines[len(m.logLines)-maxLogLines:]...)
+	}
+	m.viewport.SetContent(strings.Join(m.logLines, "\n"))
+	if atBottom {
+		m.viewport.GotoBottom()
+	}
+	return m
+}
+
+func (m model) logsView() string {
+	title := fmt.Sprintf("Logs of '%s'", m.selectedLambda)
+	if m.logQuery.RequestID != "" {
+		title = fmt.Sprintf("Logs of request %s", m.logQuery.RequestID)
+	}
+	status := "Waiting for events..."
+	switch {
+	case m.logGroup == "" && m.err == nil:
+		status = "Finding the log group..."
+	case len(m.logLines) > 0:
+		status = fmt.Sprintf("%d event(s) from %s, following", len(m.logLines), m.logGroup)
+	}
+	return fmt.Sprintf("%s\n%s\n\n%s%s\n%s",
+		headerStyle.Render(title),
+		m.viewport.View(),
+		status,
+		errorLine(m.err),
+		"(arrows or pgup/pgdn to scroll, esc to go back, q to quit)",
+	)
+}
@@ -0,0 +1,48 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+)
+
+// ApplyPolicy overrides settings of the policy file for this run. A zero
+// maxAttempts and a nil requestsPerSecond keep the file's settings; a rate
+// of zero turns the limit off.
+func ApplyPolicy(maxAttempts int, requestsPerSecond *float64) error {
+	if maxAttempts == 0 && requestsPerSecond == nil {
+		return nil
+	}
+	policy := awsinterface.CurrentPolicy()
+	if maxAttempts != 0 {
+		policy.MaxAttempts = maxAttempts
+	}
+	if requestsPerSecond != nil {
+		policy.RequestsPerSecond = *requestsPerSecond
+	}
+	if err := awsinterface.SetPolicy(policy); err != nil {
+		return fmt.Errorf("invalid policy flags: %v", err)
+	}
+	return nil
+}
+
+// ShowPolicy prints the retry, timeout and rate limit policy in effect.
+func ShowPolicy() error {
+	path, err := awsinterface.PolicyPath()
+	if err != nil {
+		return err
+	}
+	if _, err := os.Stat(path); err != nil {
+		fmt.Fprintf(os.Stderr, "No policy file at %s, using the defaults\n", path)
+	} else {
+		fmt.Fprintf(os.Stderr, "Policy from %s\n", path)
+	}
+	encoder := yaml.NewEncoder(os.Stdout)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(awsinterface.CurrentPolicy()); err != nil {
+		return fmt.Errorf("failed to print policy: %v", err)
+	}
+	return encoder.Close()
+}
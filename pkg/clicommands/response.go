@@ -0,0 +1,57 @@
+package clicommands
+
+import (
+	"aws_utility/pkg/output"
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// formatResponse renders a response for the terminal, highlighting JSON.
+func formatResponse(payload []byte, opts output.ResponseOptions) (string, error) {
+	formatted, err := output.FormatResponse(payload, opts)
+	if err != nil {
+		return "", err
+	}
+	if opts.Format == output.FormatJSON {
+		formatted = output.Colorize(formatted)
+	}
+	return formatted, nil
+}
+
+// printableResponse is formatResponse for the CLI commands. When a query
+// fails because the response is not JSON, the response is written to stderr
+// as it is so that it is not lost.
+func printableResponse(payload []byte, opts output.ResponseOptions) (string, error) {
+	formatted, err := formatResponse(payload, opts)
+	if err != nil && opts.Query != "" && !json.Valid(payload) {
+		fmt.Fprintf(os.Stderr, "Response:\n%s\n", string(payload))
+	}
+	return formatted, err
+}
+
+func (m model) responseOptions() output.ResponseOptions {
+	format := m.resultFormat
+	if format == "" {
+		format = output.FormatJSON
+	}
+	return output.ResponseOptions{Format: format, Query: m.resultQuery}
+}
+
+// nextResponseFormat cycles through output.ResponseFormats.
+func nextResponseFormat(format string) string {
+	for i, f := range output.ResponseFormats {
+		if f == format {
+			return output.ResponseFormats[(i+1)%len(output.ResponseFormats)]
+		}
+	}
+	return output.ResponseFormats[1]
+}
+
+func (m model) resultHelp() string {
+	help := fmt.Sprintf("(press f to change the format: %s, / to query the response", m.responseOptions().Format)
+	if m.invocation.RequestID != "" {
+		help += ", l for this invocation's logs"
+	}
+	return help + ", q to quit)"
+}
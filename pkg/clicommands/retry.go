@@ -0,0 +1,57 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// retryNoticeDuration is how long a retry stays on screen after the last
+// one.
+const retryNoticeDuration = 10 * time.Second
+
+var retryStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("11"))
+
+type retryMsg struct {
+	retry awsinterface.Retry
+	at    time.Time
+}
+type retryExpiredMsg struct {
+	at time.Time
+}
+
+// watchRetries forwards the retries of AWS calls to the TUI. Retries that
+// come faster than the TUI reads them are dropped, the next one replacing
+// them on screen anyway.
+func watchRetries() <-chan awsinterface.Retry {
+	retries := make(chan awsinterface.Retry, 8)
+	awsinterface.OnRetry(func(retry awsinterface.Retry) {
+		select {
+		case retries <- retry:
+		default:
+		}
+	})
+	return retries
+}
+
+func waitForRetry(retries <-chan awsinterface.Retry) tea.Cmd {
+	return func() tea.Msg {
+		return retryMsg{retry: <-retries, at: time.Now()}
+	}
+}
+
+func (m model) showRetry(msg retryMsg) (tea.Model, tea.Cmd) {
+	m.lastRetry = &msg.retry
+	m.lastRetryAt = msg.at
+	expire := tea.Tick(retryNoticeDuration, func(time.Time) tea.Msg { return retryExpiredMsg{at: msg.at} })
+	return m, tea.Batch(waitForRetry(m.retries), expire)
+}
+
+func (m model) retryLine() string {
+	if m.lastRetry == nil {
+		return ""
+	}
+	return "\n" + retryStyle.Render(m.lastRetry.String())
+}
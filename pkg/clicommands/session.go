@@ -0,0 +1,59 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"fmt"
+	"os"
+)
+
+const (
+	AccountEnvVariable = "AWS_UTILITY_ACCOUNT"
+	RoleEnvVariable    = "AWS_UTILITY_ROLE"
+)
+
+// Session is who CLI commands act as: the SSO start URL to log in to and
+// the account and role to assume there.
+type Session struct {
+	StartURL  string
+	AccountID string
+	RoleName  string
+}
+
+// Connect logs in and assumes the session's role, returning an interface
+// that can make Lambda calls.
+func (s Session) Connect() (*awsinterface.AWSInterface, error) {
+	if s.AccountID == "" || s.RoleName == "" {
+		return nil, fmt.Errorf("an account and role are required, pass them with --account and --role or set $%s and $%s", AccountEnvVariable, RoleEnvVariable)
+	}
+	awsInterface, err := login(s.StartURL)
+	if err != nil {
+		return nil, err
+	}
+	return awsInterface.ForRole(s.AccountID, s.RoleName)
+}
+
+// login runs the SSO device authorization flow on the terminal: it prints
+// the verification URL and code to stderr and waits for the user.
+func login(startURL string) (*awsinterface.AWSInterface, error) {
+	if startURL == "" {
+		return nil, fmt.Errorf("an SSO start URL is required, pass it with --profile")
+	}
+
+	awsInterface, err := awsinterface.NewAWSInterface(startURL)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create AWS interface: %v", err)
+	}
+	if err := awsInterface.RegisterClient(); err != nil {
+		return nil, err
+	}
+	authInfo, err := awsInterface.StartAuthentication()
+	if err != nil {
+		return nil, err
+	}
+
+	fmt.Fprintf(os.Stderr, "Please visit this URL to complete authentication:\n%s\n\nAnd enter this code: %s\n\n", authInfo.VerificationURIComplete, authInfo.UserCode)
+	if err := awsInterface.PollForToken(authInfo); err != nil {
+		return nil, err
+	}
+	return awsInterface, nil
+}
@@ -0,0 +1,159 @@
+package clicommands
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/history"
+	"aws_utility/pkg/logger"
+	"aws_utility/pkg/output"
+	"context"
+	"fmt"
+	"os"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+type streamStartedMsg struct {
+	events <-chan awsinterface.StreamEvent
+	cancel context.CancelFunc
+	entry  history.Entry
+	err    error
+}
+type streamEventMsg struct {
+	event awsinterface.StreamEvent
+}
+type streamDoneMsg struct{}
+
+// invokeCmd picks a local, buffered or response-streaming invocation
+// depending on the toggles in the payload mode screen.
+func (m model) invokeCmd() tea.Cmd {
+	if m.localBootstrap != "" {
+		return m.invokeLocal
+	}
+	if m.stream {
+		return m.invokeLambdaStream
+	}
+	return m.invokeLambda
+}
+
+func (m *model) invokeLambdaStream() tea.Msg {
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, entry, err := history.InvokeStream(ctx, m.awsInterface, m.selectedLambda, m.payloadJson, awsinterface.InvokeOptions{
+		TailLogs:  true,
+		Qualifier: m.qualifier,
+	})
+	if err != nil {
+		cancel()
+		logger.Error("Failed to invoke Lambda with response streaming:", err)
+		return streamStartedMsg{err: err}
+	}
+	return streamStartedMsg{events: stream.Events, cancel: cancel, entry: entry}
+}
+
+func waitForStreamEvent(events <-chan awsinterface.StreamEvent) tea.Cmd {
+	return func() tea.Msg {
+		event, ok := <-events
+		if !ok {
+			return streamDoneMsg{}
+		}
+		return streamEventMsg{event: event}
+	}
+}
+
+func (m model) streamStatus() string {
+	var b strings.Builder
+	switch {
+	case m.err != nil:
+		b.WriteString(errorLine(m.err))
+	case m.streamComplete != nil && m.streamComplete.Failed():
+		b.WriteString(errorStyle.Render(fmt.Sprintf("Stream failed: %s", describeStreamError(m.streamComplete))))
+	case m.streamComplete != nil:
+		b.WriteString(headerStyle.Render("Stream complete"))
+	default:
+		b.WriteString("Receiving...")
+	}
+	if m.streamComplete != nil && m.streamComplete.LogResult != "" {
+		b.WriteString("\n\n" + headerStyle.Render("Logs") + "\n")
+		b.WriteString(logStyle.Render(strings.TrimRight(m.streamComplete.LogResult, "\n")))
+	}
+	if m.streamEvents == nil && m.streamEntry.RequestID != "" {
+		b.WriteString("\n(press l for this invocation's logs, q to quit)")
+	} else {
+		b.WriteString("\n(press q to quit)")
+	}
+	return b.String()
+}
+
+func describeStreamError(complete *awsinterface.StreamComplete) string {
+	if complete.ErrorDetails == "" {
+		return complete.ErrorCode
+	}
+	return fmt.Sprintf("%s: %s", complete.ErrorCode, complete.ErrorDetails)
+}
+
+// streamLambda writes response chunks to stdout as they arrive and the tail
+// logs to stderr once the stream completes. With a response format the
+// response is printed formatted once complete instead.
+func streamLambda(awsInterface *awsinterface.AWSInterface, lambdaName string, payloadJson []byte, opts awsinterface.InvokeOptions, format output.ResponseOptions) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	stream, _, err := history.InvokeStream(ctx, awsInterface, lambdaName, payloadJson, opts)
+	if err != nil {
+		return err
+	}
+
+	// The stream is drained to the end even after an error, so that it is
+	// recorded in the history before returning.
+	var response []byte
+	var complete *awsinterface.StreamComplete
+	for event := range stream.Events {
+		if err != nil {
+			continue
+		}
+		if event.Err != nil {
+			err = event.Err
+			cancel()
+			continue
+		}
+		if event.Complete != nil {
+			complete = event.Complete
+			continue
+		}
+		response = append(response, event.Chunk...)
+		if format.Format != "" {
+			continue
+		}
+		if _, err = os.Stdout.Write(event.Chunk); err != nil {
+			cancel()
+		}
+	}
+	if err != nil {
+		return err
+	}
+	if format.Format != "" {
+		formatted, err := printableResponse(response, format)
+		if err != nil {
+			return err
+		}
+		fmt.Print(formatted)
+	}
+	fmt.Println()
+
+	if complete == nil {
+		return fmt.Errorf("response stream from '%s' ended without completing", lambdaName)
+	}
+	if complete.LogResult != "" {
+		fmt.Fprintln(os.Stderr, strings.TrimRight(complete.LogResult, "\n"))
+	}
+	if complete.Failed() {
+		return fmt.Errorf("function error from '%s': %s", lambdaName, describeStreamError(complete))
+	}
+	return nil
+}
+
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
+}
@@ -0,0 +1,251 @@
+package configdiff
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/envvars"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	KindChanged   = "changed"
+	KindOnlyLeft  = "only_left"
+	KindOnlyRight = "only_right"
+)
+
+// Target names a function, optionally in another account:
+// [ACCOUNT/ROLE/]FUNCTION[:QUALIFIER]. FUNCTION may be an ARN.
+type Target struct {
+	AccountID string `json:"account_id,omitempty" yaml:"account_id,omitempty"`
+	RoleName  string `json:"role_name,omitempty" yaml:"role_name,omitempty"`
+	Function  string `json:"function" yaml:"function"`
+	Qualifier string `json:"qualifier,omitempty" yaml:"qualifier,omitempty"`
+}
+
+func ParseTarget(spec string) (Target, error) {
+	var target Target
+	name := spec
+	if !strings.HasPrefix(spec, "arn:") {
+		parts := strings.Split(spec, "/")
+		switch len(parts) {
+		case 1:
+		case 3:
+			target.AccountID, target.RoleName, name = parts[0], parts[1], parts[2]
+		default:
+			return Target{}, fmt.Errorf("invalid target %q, expected [ACCOUNT/ROLE/]FUNCTION[:QUALIFIER]", spec)
+		}
+	}
+	target.Function, target.Qualifier = awsinterface.SplitQualifier(name)
+	if target.Function == "" {
+		return Target{}, fmt.Errorf("invalid target %q, no function given", spec)
+	}
+	return target, nil
+}
+
+func (t Target) String() string {
+	name := t.Function
+	if t.Qualifier != "" {
+		name += ":" + t.Qualifier
+	}
+	if t.AccountID != "" {
+		return fmt.Sprintf("%s/%s/%s", t.AccountID, t.RoleName, name)
+	}
+	return name
+}
+
+type Options struct {
+	// Values compares environment variable values, not just keys.
+	Values bool
+	// Ignore skips keys starting with any of these prefixes, e.g. "tag.".
+	Ignore []string
+}
+
+// Collect flattens the function's configuration into comparable keys such
+// as "memory_size", "environment.LOG_LEVEL" or "alias.live".
+func Collect(awsInterface *awsinterface.AWSInterface, target Target, opts Options) (map[string]string, error) {
+	awsInterface = awsInterface.ForRegion(awsinterface.RegionFromARN(target.Function))
+	details, err := awsInterface.DescribeFunction(target.Function, target.Qualifier)
+	if err != nil {
+		return nil, err
+	}
+
+	config := map[string]string{
+		"runtime":      details.Runtime,
+		"architecture": details.Architecture,
+		"memory_size":  fmt.Sprintf("%d", details.MemorySize),
+		"timeout":      fmt.Sprintf("%d", details.Timeout),
+		"handler":      details.Handler,
+		"package_type": details.PackageType,
+		"description":  details.Description,
+		"role":         roleName(details.Role),
+		"code_sha256":  details.CodeSha256,
+		"image_uri":    details.ImageURI,
+		"dead_letter":  details.DeadLetterTarget,
+	}
+	for _, layer := range details.Layers {
+		name, version := layerName(layer.ARN)
+		config["layer."+name] = version
+	}
+	if details.VpcConfig != nil {
+		config["vpc.id"] = details.VpcConfig.VpcID
+		config["vpc.subnets"] = strings.Join(sorted(details.VpcConfig.SubnetIDs), ",")
+		config["vpc.security_groups"] = strings.Join(sorted(details.VpcConfig.SecurityGroupIDs), ",")
+	}
+	for key, value := range details.Tags {
+		config["tag."+key] = value
+	}
+
+	if opts.Values {
+		qualified := target.Function
+		if target.Qualifier != "" {
+			qualified += ":" + target.Qualifier
+		}
+		environment, err := awsInterface.GetEnvironment(qualified)
+		if err != nil {
+			return nil, err
+		}
+		for key, value := range environment.Variables {
+			config["environment."+key] = value
+		}
+	} else {
+		for _, key := range details.EnvironmentKeys {
+			config["environment."+key] = "(set)"
+		}
+	}
+
+	concurrency, err := awsInterface.GetConcurrency(target.Function)
+	if err != nil {
+		return nil, err
+	}
+	config["reserved_concurrency"] = "none"
+	if concurrency.Reserved != nil {
+		config["reserved_concurrency"] = fmt.Sprintf("%d", *concurrency.Reserved)
+	}
+	for _, provisioned := range concurrency.Provisioned {
+		config["provisioned_concurrency."+provisioned.Qualifier] = fmt.Sprintf("%d", provisioned.Requested)
+	}
+
+	aliases, err := awsInterface.ListAliases(target.Function)
+	if err != nil {
+		return nil, err
+	}
+	for _, alias := range aliases {
+		config["alias."+alias.Name] = alias.Routing()
+	}
+
+	for key, value := range config {
+		if value == "" || ignored(key, opts.Ignore) {
+			delete(config, key)
+		}
+	}
+	return config, nil
+}
+
+// layerName splits a layer version ARN into its name and version, so the
+// same layer in two accounts or regions compares by version only.
+func layerName(arn string) (string, string) {
+	parts := strings.Split(arn, ":")
+	if len(parts) != 8 {
+		return arn, ""
+	}
+	return parts[6], parts[7]
+}
+
+// roleName drops the account from a role ARN, which always differs
+// between accounts.
+func roleName(arn string) string {
+	if i := strings.Index(arn, ":role/"); i >= 0 {
+		return arn[i+len(":role/"):]
+	}
+	return arn
+}
+
+func sorted(values []string) []string {
+	values = append([]string(nil), values...)
+	sort.Strings(values)
+	return values
+}
+
+func ignored(key string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(key, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+type Difference struct {
+	Key   string `json:"key" yaml:"key"`
+	Kind  string `json:"kind" yaml:"kind"`
+	Left  string `json:"left,omitempty" yaml:"left,omitempty"`
+	Right string `json:"right,omitempty" yaml:"right,omitempty"`
+}
+
+// Compare lists the keys whose values differ, sorted by key.
+func Compare(left, right map[string]string) []Difference {
+	var differences []Difference
+	for key, leftValue := range left {
+		rightValue, ok := right[key]
+		switch {
+		case !ok:
+			differences = append(differences, Difference{Key: key, Kind: KindOnlyLeft, Left: leftValue})
+		case leftValue != rightValue:
+			differences = append(differences, Difference{Key: key, Kind: KindChanged, Left: leftValue, Right: rightValue})
+		}
+	}
+	for key, rightValue := range right {
+		if _, ok := left[key]; !ok {
+			differences = append(differences, Difference{Key: key, Kind: KindOnlyRight, Right: rightValue})
+		}
+	}
+	sort.Slice(differences, func(i, j int) bool { return differences[i].Key < differences[j].Key })
+	return differences
+}
+
+// Masked returns differences with the values of secret-looking environment
+// variables replaced by a short hash, which still shows whether a value
+// matches the one in another function without revealing it.
+func Masked(differences []Difference) []Difference {
+	masked := make([]Difference, len(differences))
+	for i, difference := range differences {
+		if key, ok := strings.CutPrefix(difference.Key, "environment."); ok && envvars.IsSecret(key) {
+			difference.Left = hashValue(difference.Left)
+			difference.Right = hashValue(difference.Right)
+		}
+		masked[i] = difference
+	}
+	return masked
+}
+
+func hashValue(value string) string {
+	if value == "" || value == "(set)" {
+		return value
+	}
+	sum := sha256.Sum256([]byte(value))
+	return "sha256:" + hex.EncodeToString(sum[:])[:12]
+}
+
+// Report is the result of comparing two targets.
+type Report struct {
+	Left        Target       `json:"left" yaml:"left"`
+	Right       Target       `json:"right" yaml:"right"`
+	Differences []Difference `json:"differences" yaml:"differences"`
+}
+
+// Diff collects both targets, each through its own interface, and compares
+// them.
+func Diff(leftInterface *awsinterface.AWSInterface, left Target, rightInterface *awsinterface.AWSInterface, right Target, opts Options) (*Report, error) {
+	leftConfig, err := Collect(leftInterface, left, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", left, err)
+	}
+	rightConfig, err := Collect(rightInterface, right, opts)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", right, err)
+	}
+	return &Report{Left: left, Right: right, Differences: Compare(leftConfig, rightConfig)}, nil
+}
@@ -0,0 +1,104 @@
+package configdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Target
+		wantErr bool
+	}{
+		{spec: "orders", want: Target{Function: "orders"}},
+		{spec: "orders:live", want: Target{Function: "orders", Qualifier: "live"}},
+		{spec: "123456789012/Admin/orders:3", want: Target{AccountID: "123456789012", RoleName: "Admin", Function: "orders", Qualifier: "3"}},
+		{
+			spec: "arn:aws:lambda:eu-west-1:123456789012:function:orders:live",
+			want: Target{Function: "arn:aws:lambda:eu-west-1:123456789012:function:orders", Qualifier: "live"},
+		},
+		{spec: "123456789012/orders", wantErr: true},
+		{spec: "a/b/c/d", wantErr: true},
+		{spec: "", wantErr: true},
+	}
+	for _, test := range tests {
+		got, err := ParseTarget(test.spec)
+		if (err != nil) != test.wantErr {
+			t.Errorf("ParseTarget(%q) error = %v", test.spec, err)
+			continue
+		}
+		if test.wantErr {
+			continue
+		}
+		if got != test.want {
+			t.Errorf("ParseTarget(%q) = %+v, want %+v", test.spec, got, test.want)
+		}
+		if !strings.HasPrefix(test.spec, "arn:") && got.String() != test.spec {
+			t.Errorf("ParseTarget(%q).String() = %q", test.spec, got.String())
+		}
+	}
+}
+
+// TestCompareAcrossAccounts compares what Collect reports for the same
+// function in two accounts, masked as the diff command prints it.
+func TestCompareAcrossAccounts(t *testing.T) {
+	prodLayer, prodLayerVersion := layerName("arn:aws:lambda:eu-west-1:111111111111:layer:shared:7")
+	devLayer, devLayerVersion := layerName("arn:aws:lambda:eu-west-1:222222222222:layer:shared:9")
+	prod := map[string]string{
+		"memory_size":             "1024",
+		"role":                    roleName("arn:aws:iam::111111111111:role/orders"),
+		"layer." + prodLayer:      prodLayerVersion,
+		"environment.DB_PASSWORD": "hunter2",
+		"environment.API_TOKEN":   "(set)",
+		"alias.live":              "3",
+	}
+	dev := map[string]string{
+		"memory_size":             "256",
+		"role":                    roleName("arn:aws:iam::222222222222:role/orders"),
+		"layer." + devLayer:       devLayerVersion,
+		"environment.DB_PASSWORD": "hunter3",
+		"environment.LOG_LEVEL":   "debug",
+	}
+
+	differences := Masked(Compare(prod, dev))
+	var keys []string
+	for _, difference := range differences {
+		keys = append(keys, difference.Key+" "+difference.Kind)
+	}
+	want := []string{
+		"alias.live only_left",
+		"environment.API_TOKEN only_left",
+		"environment.DB_PASSWORD changed",
+		"environment.LOG_LEVEL only_right",
+		"layer.shared changed",
+		"memory_size changed",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Errorf("Compare() = %q, want %q", keys, want)
+	}
+
+	for _, difference := range differences {
+		switch difference.Key {
+		case "environment.DB_PASSWORD":
+			if !strings.HasPrefix(difference.Left, "sha256:") || difference.Left == difference.Right || strings.Contains(difference.Left+difference.Right, "hunter") {
+				t.Errorf("password difference = %+v, want distinct hashes", difference)
+			}
+		case "environment.API_TOKEN":
+			if difference.Left != "(set)" {
+				t.Errorf("token difference = %+v, want (set) kept", difference)
+			}
+		case "environment.LOG_LEVEL":
+			if difference.Right != "debug" {
+				t.Errorf("log level difference = %+v, want the value shown", difference)
+			}
+		}
+	}
+	if again := Masked(Compare(prod, dev)); !reflect.DeepEqual(again, differences) {
+		t.Error("Masked() hashes are not stable between runs")
+	}
+	if same := Compare(prod, prod); len(same) != 0 {
+		t.Errorf("Compare() of equal configurations = %+v", same)
+	}
+}
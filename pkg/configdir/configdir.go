@@ -0,0 +1,22 @@
+package configdir
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const AppName = "aws_utility"
+
+// Path returns $envVariable when it is set, otherwise the named
+// subdirectory of aws_utility's user config directory.
+func Path(envVariable string, elem ...string) (string, error) {
+	if dir := os.Getenv(envVariable); dir != "" {
+		return dir, nil
+	}
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate user config directory: %v", err)
+	}
+	return filepath.Join(append([]string{configDir, AppName}, elem...)...), nil
+}
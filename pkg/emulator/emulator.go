@@ -0,0 +1,299 @@
+package emulator
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+const (
+	BootstrapEnvVariable = "AWS_UTILITY_LOCAL_BOOTSTRAP"
+	DefaultTimeout       = 30 * time.Second
+	DefaultMemorySize    = 128
+
+	runtimeAPIPrefix = "/2018-06-01/runtime"
+	// functionErrorUnhandled is what Lambda reports for errors raised by the
+	// runtime or the handler.
+	functionErrorUnhandled = "Unhandled"
+)
+
+type Options struct {
+	// Bootstrap is the handler executable built for provided.al2, or a
+	// directory containing one named bootstrap.
+	Bootstrap      string
+	Handler        string
+	Env            map[string]string
+	Timeout        time.Duration
+	MemorySize     int32
+	Region         string
+	InvocationType types.InvocationType
+}
+
+// ResolveBootstrap returns the executable to run for path, which may be the
+// bootstrap itself or the directory it was built into.
+func ResolveBootstrap(path string) (string, error) {
+	if path == "" {
+		return "", fmt.Errorf("no bootstrap given, pass the path of a locally built handler")
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to find bootstrap: %v", err)
+	}
+	if info.IsDir() {
+		path = filepath.Join(path, "bootstrap")
+		if info, err = os.Stat(path); err != nil {
+			return "", fmt.Errorf("failed to find bootstrap: %v", err)
+		}
+	}
+	if info.Mode()&0111 == 0 {
+		return "", fmt.Errorf("bootstrap %s is not executable", path)
+	}
+	return filepath.Abs(path)
+}
+
+// Invoke runs the bootstrap against a local Runtime API serving a single
+// invocation with payload, and returns the outcome as InvokeLambda would.
+// The runtime's output becomes the log result, framed by START, END and
+// REPORT lines like in CloudWatch.
+func Invoke(functionName string, payload []byte, opts Options) (*awsinterface.InvokeResult, error) {
+	bootstrap, err := ResolveBootstrap(opts.Bootstrap)
+	if err != nil {
+		return nil, err
+	}
+	if functionName == "" {
+		functionName = "local"
+	}
+	invocationType := opts.InvocationType
+	if invocationType == "" {
+		invocationType = types.InvocationTypeRequestResponse
+	}
+	timeout := opts.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	region := opts.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+
+	result := &awsinterface.InvokeResult{
+		InvocationType:  invocationType,
+		ExecutedVersion: "$LATEST",
+		RequestID:       newRequestID(),
+	}
+	if invocationType == types.InvocationTypeDryRun {
+		result.StatusCode = http.StatusNoContent
+		return result, nil
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, fmt.Errorf("failed to start the runtime API: %v", err)
+	}
+	api := &runtimeAPI{
+		requestID:   result.RequestID,
+		functionARN: fmt.Sprintf("arn:aws:lambda:%s:000000000000:function:%s", region, functionName),
+		payload:     payload,
+		deadline:    time.Now().Add(timeout),
+		done:        make(chan outcome, 1),
+		stop:        make(chan struct{}),
+	}
+	server := &http.Server{Handler: api}
+	go server.Serve(listener)
+	defer server.Close()
+	defer close(api.stop)
+
+	var logs bytes.Buffer
+	fmt.Fprintf(&logs, "START RequestId: %s Version: $LATEST\n", result.RequestID)
+
+	cmd := exec.Command(bootstrap)
+	cmd.Dir = filepath.Dir(bootstrap)
+	cmd.Env = environment(functionName, region, listener.Addr().String(), bootstrap, opts)
+	cmd.Stdout = &logs
+	cmd.Stderr = &logs
+	// Children of a bootstrap script may keep the output open after it is
+	// killed.
+	cmd.WaitDelay = time.Second
+
+	start := time.Now()
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %v", bootstrap, err)
+	}
+	exited := make(chan error, 1)
+	go func() { exited <- cmd.Wait() }()
+
+	var response outcome
+	running := true
+	select {
+	case response = <-api.done:
+	case err := <-exited:
+		running = false
+		// The runtime may have posted its response just before exiting.
+		select {
+		case response = <-api.done:
+		default:
+			response = errorOutcome("Runtime.ExitError", fmt.Sprintf("Runtime exited without providing a reason: %v", exitReason(err)))
+		}
+	case <-time.After(timeout):
+		response = errorOutcome("Sandbox.Timedout", fmt.Sprintf("Task timed out after %.2f seconds", timeout.Seconds()))
+	}
+	duration := time.Since(start)
+	if running {
+		cmd.Process.Kill()
+		<-exited
+	}
+
+	fmt.Fprintf(&logs, "END RequestId: %s\nREPORT RequestId: %s\tDuration: %.2f ms\tMemory Size: %d MB\n",
+		result.RequestID, result.RequestID, float64(duration.Microseconds())/1000, memorySize(opts))
+
+	result.StatusCode = http.StatusOK
+	result.FunctionError = response.functionError
+	result.LogResult = logs.String()
+	result.Payload = response.payload
+	if invocationType == types.InvocationTypeEvent {
+		result.StatusCode = http.StatusAccepted
+		result.Payload = nil
+	}
+	return result, nil
+}
+
+func environment(functionName, region, runtimeAPI, bootstrap string, opts Options) []string {
+	vars := map[string]string{
+		"PATH":                            os.Getenv("PATH"),
+		"HOME":                            os.Getenv("HOME"),
+		"TZ":                              ":UTC",
+		"AWS_LAMBDA_RUNTIME_API":          runtimeAPI,
+		"AWS_LAMBDA_FUNCTION_NAME":        functionName,
+		"AWS_LAMBDA_FUNCTION_VERSION":     "$LATEST",
+		"AWS_LAMBDA_FUNCTION_MEMORY_SIZE": strconv.Itoa(int(memorySize(opts))),
+		"AWS_LAMBDA_LOG_GROUP_NAME":       "/aws/lambda/" + functionName,
+		"AWS_LAMBDA_LOG_STREAM_NAME":      "local",
+		"AWS_REGION":                      region,
+		"AWS_DEFAULT_REGION":              region,
+		"LAMBDA_TASK_ROOT":                filepath.Dir(bootstrap),
+		"_HANDLER":                        opts.Handler,
+	}
+	for key, value := range opts.Env {
+		vars[key] = value
+	}
+	env := make([]string, 0, len(vars))
+	for key, value := range vars {
+		env = append(env, key+"="+value)
+	}
+	return env
+}
+
+func memorySize(opts Options) int32 {
+	if opts.MemorySize <= 0 {
+		return DefaultMemorySize
+	}
+	return opts.MemorySize
+}
+
+func exitReason(err error) string {
+	if err == nil {
+		return "exit status 0"
+	}
+	return err.Error()
+}
+
+// newRequestID returns a random ID in the UUID format Lambda uses.
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	id := hex.EncodeToString(b)
+	return strings.Join([]string{id[:8], id[8:12], id[12:16], id[16:20], id[20:]}, "-")
+}
+
+type outcome struct {
+	payload       []byte
+	functionError string
+}
+
+func errorOutcome(errorType, message string) outcome {
+	payload, _ := json.Marshal(map[string]string{"errorType": errorType, "errorMessage": message})
+	return outcome{payload: payload, functionError: functionErrorUnhandled}
+}
+
+// runtimeAPI serves the Lambda Runtime API for one invocation. Later calls
+// to next block until the emulator shuts down, as the runtime is stopped
+// once it has responded.
+type runtimeAPI struct {
+	requestID   string
+	functionARN string
+	payload     []byte
+	deadline    time.Time
+
+	mu        sync.Mutex
+	delivered bool
+	done      chan outcome
+	stop      chan struct{}
+}
+
+func (s *runtimeAPI) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path := strings.TrimPrefix(r.URL.Path, runtimeAPIPrefix)
+	switch {
+	case r.Method == http.MethodGet && path == "/invocation/next":
+		s.next(w, r)
+	case r.Method == http.MethodPost && path == "/invocation/"+s.requestID+"/response":
+		s.finish(w, r, "")
+	case r.Method == http.MethodPost && path == "/invocation/"+s.requestID+"/error",
+		r.Method == http.MethodPost && path == "/init/error":
+		s.finish(w, r, functionErrorUnhandled)
+	default:
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprintf(w, `{"errorType":"InvalidRequestID","errorMessage":"unknown runtime API call %s %s"}`, r.Method, r.URL.Path)
+	}
+}
+
+func (s *runtimeAPI) next(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	first := !s.delivered
+	s.delivered = true
+	s.mu.Unlock()
+	if !first {
+		select {
+		case <-s.stop:
+		case <-r.Context().Done():
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Lambda-Runtime-Aws-Request-Id", s.requestID)
+	w.Header().Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(s.deadline.UnixMilli(), 10))
+	w.Header().Set("Lambda-Runtime-Invoked-Function-Arn", s.functionARN)
+	w.Header().Set("Lambda-Runtime-Trace-Id", "Root=1-00000000-000000000000000000000000;Sampled=0")
+	w.Write(s.payload)
+}
+
+func (s *runtimeAPI) finish(w http.ResponseWriter, r *http.Request, functionError string) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	select {
+	case s.done <- outcome{payload: body, functionError: functionError}:
+	default:
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusAccepted)
+	w.Write([]byte(`{"status":"OK"}`))
+}
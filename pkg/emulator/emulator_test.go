@@ -0,0 +1,150 @@
+package emulator
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/lambda/types"
+)
+
+// handlerEnvVariable makes the test binary act as a bootstrap, handling one
+// invocation the way the value names.
+const handlerEnvVariable = "EMULATOR_TEST_HANDLER"
+
+func TestMain(m *testing.M) {
+	if behaviour := os.Getenv(handlerEnvVariable); behaviour != "" {
+		os.Exit(handle(behaviour))
+	}
+	os.Exit(m.Run())
+}
+
+// handle is a minimal custom runtime: it fetches the next invocation and
+// answers it through the Runtime API like a provided.al2 handler would.
+func handle(behaviour string) int {
+	api := "http://" + os.Getenv("AWS_LAMBDA_RUNTIME_API") + runtimeAPIPrefix
+	next, err := http.Get(api + "/invocation/next")
+	if err != nil {
+		fmt.Println(err)
+		return 1
+	}
+	payload, _ := io.ReadAll(next.Body)
+	requestID := next.Header.Get("Lambda-Runtime-Aws-Request-Id")
+	fmt.Printf("handling %s in %s with %s\n", payload, os.Getenv("AWS_LAMBDA_FUNCTION_NAME"), os.Getenv("_HANDLER"))
+
+	switch behaviour {
+	case "echo":
+		http.Post(api+"/invocation/"+requestID+"/response", "application/json", strings.NewReader(`{"echo":`+string(payload)+`}`))
+	case "raise":
+		http.Post(api+"/invocation/"+requestID+"/error", "application/json", strings.NewReader(`{"errorType":"Boom","errorMessage":"failed"}`))
+	case "crash":
+		return 3
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	return 0
+}
+
+// bootstrapDir puts the test binary where a build would leave a handler.
+func bootstrapDir(t *testing.T) string {
+	t.Helper()
+	executable, err := os.Executable()
+	if err != nil {
+		t.Skip("cannot find the test binary:", err)
+	}
+	dir := t.TempDir()
+	if err := os.Symlink(executable, filepath.Join(dir, "bootstrap")); err != nil {
+		t.Skip("cannot link the test binary:", err)
+	}
+	return dir
+}
+
+func TestInvoke(t *testing.T) {
+	result, err := Invoke("orders", []byte(`{"n":1}`), Options{
+		Bootstrap: bootstrapDir(t),
+		Handler:   "main",
+		Env:       map[string]string{handlerEnvVariable: "echo"},
+	})
+	if err != nil {
+		t.Fatalf("Invoke() error = %v", err)
+	}
+	if result.StatusCode != 200 || result.FunctionError != "" || string(result.Payload) != `{"echo":{"n":1}}` {
+		t.Errorf("Invoke() = %d %q %s", result.StatusCode, result.FunctionError, result.Payload)
+	}
+	logs := strings.Split(strings.TrimSpace(result.LogResult), "\n")
+	if !strings.HasPrefix(logs[0], "START RequestId: "+result.RequestID) || !strings.HasPrefix(logs[len(logs)-1], "REPORT RequestId: "+result.RequestID) {
+		t.Errorf("Invoke() logs are not framed like CloudWatch: %q", result.LogResult)
+	}
+	if !strings.Contains(result.LogResult, `handling {"n":1} in orders with main`) {
+		t.Errorf("Invoke() logs = %q, want the handler output", result.LogResult)
+	}
+}
+
+func TestInvokeReportsHandlerFailures(t *testing.T) {
+	bootstrap := bootstrapDir(t)
+	for behaviour, want := range map[string]string{
+		"raise": `{"errorType":"Boom","errorMessage":"failed"}`,
+		"crash": "Runtime.ExitError",
+		"hang":  "Task timed out after 0.50 seconds",
+	} {
+		result, err := Invoke("orders", []byte(`{}`), Options{
+			Bootstrap: bootstrap,
+			Env:       map[string]string{handlerEnvVariable: behaviour},
+			Timeout:   500 * time.Millisecond,
+		})
+		if err != nil {
+			t.Fatalf("Invoke(%s) error = %v", behaviour, err)
+		}
+		if result.FunctionError != "Unhandled" || !strings.Contains(string(result.Payload), want) {
+			t.Errorf("Invoke(%s) = %q %s, want an unhandled error with %s", behaviour, result.FunctionError, result.Payload, want)
+		}
+	}
+}
+
+func TestInvokeWithoutResponse(t *testing.T) {
+	bootstrap := bootstrapDir(t)
+	for invocationType, want := range map[types.InvocationType]int32{
+		types.InvocationTypeEvent:  202,
+		types.InvocationTypeDryRun: 204,
+	} {
+		result, err := Invoke("orders", []byte(`{}`), Options{
+			Bootstrap:      bootstrap,
+			Env:            map[string]string{handlerEnvVariable: "echo"},
+			InvocationType: invocationType,
+		})
+		if err != nil {
+			t.Fatalf("Invoke(%s) error = %v", invocationType, err)
+		}
+		if result.StatusCode != want || len(result.Payload) != 0 {
+			t.Errorf("Invoke(%s) = %d %s, want %d without a payload", invocationType, result.StatusCode, result.Payload, want)
+		}
+	}
+}
+
+func TestResolveBootstrap(t *testing.T) {
+	dir := bootstrapDir(t)
+	resolved, err := ResolveBootstrap(dir)
+	if err != nil || resolved != filepath.Join(dir, "bootstrap") {
+		t.Errorf("ResolveBootstrap(dir) = %q, %v, want the bootstrap inside it", resolved, err)
+	}
+
+	notExecutable := filepath.Join(t.TempDir(), "bootstrap")
+	if err := os.WriteFile(notExecutable, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for path, want := range map[string]string{
+		"":                            "no bootstrap given",
+		t.TempDir():                   "failed to find bootstrap",
+		notExecutable:                 "is not executable",
+		filepath.Join(dir, "missing"): "failed to find bootstrap",
+	} {
+		if _, err := ResolveBootstrap(path); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ResolveBootstrap(%q) error = %v, want %q", path, err, want)
+		}
+	}
+}
@@ -0,0 +1,189 @@
+package envvars
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+const (
+	ChangeAdd    = "add"
+	ChangeUpdate = "update"
+	ChangeRemove = "remove"
+
+	masked = "********"
+)
+
+var secretKeyPattern = regexp.MustCompile(`(?i)(secret|passw(or)?d|token|api_?key|private|credential|auth|signature|session)`)
+
+// IsSecret reports whether key looks like it holds a credential.
+func IsSecret(key string) bool {
+	return secretKeyPattern.MatchString(key)
+}
+
+// Display returns value, or a mask if key looks secret and showSecrets is
+// false.
+func Display(key, value string, showSecrets bool) string {
+	if !showSecrets && IsSecret(key) {
+		return masked
+	}
+	return value
+}
+
+type Change struct {
+	Key      string
+	Kind     string
+	OldValue string
+	NewValue string
+}
+
+func (c Change) Format(showSecrets bool) string {
+	oldValue := Display(c.Key, c.OldValue, showSecrets)
+	newValue := Display(c.Key, c.NewValue, showSecrets)
+	switch c.Kind {
+	case ChangeAdd:
+		return fmt.Sprintf("+ %s=%s", c.Key, newValue)
+	case ChangeRemove:
+		return fmt.Sprintf("- %s=%s", c.Key, oldValue)
+	}
+	return fmt.Sprintf("~ %s: %s → %s", c.Key, oldValue, newValue)
+}
+
+// Diff lists the changes that turn current into updated, sorted by key.
+func Diff(current, updated map[string]string) []Change {
+	var changes []Change
+	for key, newValue := range updated {
+		oldValue, ok := current[key]
+		switch {
+		case !ok:
+			changes = append(changes, Change{Key: key, Kind: ChangeAdd, NewValue: newValue})
+		case oldValue != newValue:
+			changes = append(changes, Change{Key: key, Kind: ChangeUpdate, OldValue: oldValue, NewValue: newValue})
+		}
+	}
+	for key, oldValue := range current {
+		if _, ok := updated[key]; !ok {
+			changes = append(changes, Change{Key: key, Kind: ChangeRemove, OldValue: oldValue})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// Merge returns a copy of current with set applied and unset removed.
+func Merge(current, set map[string]string, unset []string) map[string]string {
+	merged := make(map[string]string, len(current)+len(set))
+	for key, value := range current {
+		merged[key] = value
+	}
+	for key, value := range set {
+		merged[key] = value
+	}
+	for _, key := range unset {
+		delete(merged, key)
+	}
+	return merged
+}
+
+var keyPattern = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9_]*$`)
+
+func CheckKey(key string) error {
+	if !keyPattern.MatchString(key) {
+		return fmt.Errorf("invalid environment variable name %q", key)
+	}
+	return nil
+}
+
+// ParseAssignments turns KEY=VALUE arguments into a map.
+func ParseAssignments(assignments []string) (map[string]string, error) {
+	vars := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		key, value, ok := strings.Cut(assignment, "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid assignment %q, expected KEY=VALUE", assignment)
+		}
+		if err := CheckKey(key); err != nil {
+			return nil, err
+		}
+		vars[key] = value
+	}
+	return vars, nil
+}
+
+// ParseDotenv reads KEY=VALUE lines. Blank lines, # comments and a leading
+// "export " are ignored; values may be single-quoted (literal) or
+// double-quoted (with \n, \t, \" and \\ escapes).
+func ParseDotenv(r io.Reader) (map[string]string, error) {
+	vars := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return nil, fmt.Errorf("line %d: expected KEY=VALUE", lineNumber)
+		}
+		key = strings.TrimSpace(key)
+		if err := CheckKey(key); err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+
+		value, err := unquote(strings.TrimSpace(value))
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %v", lineNumber, err)
+		}
+		vars[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return vars, nil
+}
+
+func unquote(value string) (string, error) {
+	if value == "" {
+		return "", nil
+	}
+	switch value[0] {
+	case '\'':
+		end := strings.IndexByte(value[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated single-quoted value")
+		}
+		return value[1 : end+1], nil
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(value); i++ {
+			c := value[i]
+			switch {
+			case c == '"':
+				return b.String(), nil
+			case c == '\\' && i+1 < len(value):
+				i++
+				switch value[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(value[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated double-quoted value")
+	}
+	// Unquoted values end at an inline comment.
+	if i := strings.Index(value, " #"); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	return value, nil
+}
@@ -0,0 +1,103 @@
+package envvars
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPreview(t *testing.T) {
+	current := map[string]string{"LOG_LEVEL": "info", "DB_PASSWORD": "hunter2", "OLD_FLAG": "1", "REGION": "eu-west-1"}
+	set, err := ParseAssignments([]string{"LOG_LEVEL=debug", "DB_PASSWORD=hunter3", "FEATURE_X=on", "REGION=eu-west-1"})
+	if err != nil {
+		t.Fatalf("ParseAssignments() error = %v", err)
+	}
+
+	updated := Merge(current, set, []string{"OLD_FLAG", "NEVER_SET"})
+	var lines []string
+	for _, change := range Diff(current, updated) {
+		lines = append(lines, change.Format(false))
+	}
+	want := []string{
+		"~ DB_PASSWORD: ******** → ********",
+		"+ FEATURE_X=on",
+		"~ LOG_LEVEL: info → debug",
+		"- OLD_FLAG=1",
+	}
+	if !reflect.DeepEqual(lines, want) {
+		t.Errorf("preview = %q, want %q", lines, want)
+	}
+	if current["LOG_LEVEL"] != "info" || current["OLD_FLAG"] != "1" {
+		t.Errorf("Merge() modified the current variables: %v", current)
+	}
+
+	revealed := Diff(current, updated)[0].Format(true)
+	if revealed != "~ DB_PASSWORD: hunter2 → hunter3" {
+		t.Errorf("Format(showSecrets) = %q", revealed)
+	}
+	if changes := Diff(current, current); len(changes) != 0 {
+		t.Errorf("Diff() of unchanged variables = %+v", changes)
+	}
+}
+
+func TestIsSecret(t *testing.T) {
+	for key, want := range map[string]bool{
+		"DB_PASSWORD":   true,
+		"api_key":       true,
+		"GITHUB_TOKEN":  true,
+		"AUTH_HEADER":   true,
+		"LOG_LEVEL":     false,
+		"TABLE_NAME":    false,
+		"KEYSPACE_NAME": false,
+	} {
+		if got := IsSecret(key); got != want {
+			t.Errorf("IsSecret(%s) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestParseAssignmentsRejectsInvalidNames(t *testing.T) {
+	for _, assignment := range []string{"A", "1A=x", "A-B=x", "=x"} {
+		if _, err := ParseAssignments([]string{assignment}); err == nil {
+			t.Errorf("ParseAssignments(%q) error = nil, want an error", assignment)
+		}
+	}
+}
+
+func TestParseDotenv(t *testing.T) {
+	vars, err := ParseDotenv(strings.NewReader(`# database
+PLAIN=value
+export EXPORTED = spaced
+COMMENTED=value # note
+SINGLE='literal \n #'
+DOUBLE="line\nnext \"quoted\" \\"
+EMPTY=
+`))
+	if err != nil {
+		t.Fatalf("ParseDotenv() error = %v", err)
+	}
+	want := map[string]string{
+		"PLAIN":     "value",
+		"EXPORTED":  "spaced",
+		"COMMENTED": "value",
+		"SINGLE":    `literal \n #`,
+		"DOUBLE":    "line\nnext \"quoted\" \\",
+		"EMPTY":     "",
+	}
+	if !reflect.DeepEqual(vars, want) {
+		t.Errorf("ParseDotenv() = %q, want %q", vars, want)
+	}
+}
+
+func TestParseDotenvErrors(t *testing.T) {
+	for input, want := range map[string]string{
+		"A=1\nNOPE\n": "line 2",
+		"1A=x":        "invalid environment variable name",
+		"A='x":        "unterminated single-quoted",
+		`A="x`:        "unterminated double-quoted",
+	} {
+		if _, err := ParseDotenv(strings.NewReader(input)); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ParseDotenv(%q) error = %v, want %q", input, err, want)
+		}
+	}
+}
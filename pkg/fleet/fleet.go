@@ -0,0 +1,171 @@
+package fleet
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/history"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Selector picks the accounts to invoke in: explicit IDs, account names
+// matching NameRegex, or every account the SSO session can access.
+type Selector struct {
+	AccountIDs []string
+	NameRegex  string
+	All        bool
+}
+
+// Select returns the accessible accounts the selector matches, sorted by
+// name. Unknown account IDs are an error rather than silently skipped.
+func (s Selector) Select(accounts []awsinterface.Account) ([]awsinterface.Account, error) {
+	if !s.All && len(s.AccountIDs) == 0 && s.NameRegex == "" {
+		return nil, fmt.Errorf("no accounts selected: give account IDs, a name regex or all")
+	}
+
+	var nameRegex *regexp.Regexp
+	if s.NameRegex != "" {
+		var err error
+		nameRegex, err = regexp.Compile(s.NameRegex)
+		if err != nil {
+			return nil, fmt.Errorf("invalid account name regex: %v", err)
+		}
+	}
+
+	byID := make(map[string]awsinterface.Account, len(accounts))
+	for _, account := range accounts {
+		byID[account.AccountID] = account
+	}
+
+	selected := map[string]awsinterface.Account{}
+	for _, id := range s.AccountIDs {
+		account, ok := byID[id]
+		if !ok {
+			return nil, fmt.Errorf("account %s is not accessible with this SSO session", id)
+		}
+		selected[id] = account
+	}
+	for _, account := range accounts {
+		if s.All || (nameRegex != nil && nameRegex.MatchString(account.AccountName)) {
+			selected[account.AccountID] = account
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no accessible account matches the selector")
+	}
+
+	matched := make([]awsinterface.Account, 0, len(selected))
+	for _, account := range selected {
+		matched = append(matched, account)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].AccountName < matched[j].AccountName })
+	return matched, nil
+}
+
+type Options struct {
+	RoleName    string
+	Concurrency int
+	Invoke      awsinterface.InvokeOptions
+	// OnResult, if set, is called as each account finishes.
+	OnResult func(Result)
+}
+
+type Result struct {
+	AccountID       string          `json:"account_id" yaml:"account_id"`
+	AccountName     string          `json:"account_name" yaml:"account_name"`
+	Status          string          `json:"status" yaml:"status"`
+	StatusCode      int32           `json:"status_code,omitempty" yaml:"status_code,omitempty"`
+	ExecutedVersion string          `json:"executed_version,omitempty" yaml:"executed_version,omitempty"`
+	DurationMs      int64           `json:"duration_ms" yaml:"duration_ms"`
+	FunctionError   string          `json:"function_error,omitempty" yaml:"function_error,omitempty"`
+	Error           string          `json:"error,omitempty" yaml:"error,omitempty"`
+	Response        json.RawMessage `json:"response,omitempty" yaml:"-"`
+}
+
+func (r Result) Duration() time.Duration {
+	return time.Duration(r.DurationMs) * time.Millisecond
+}
+
+func (r Result) Succeeded() bool {
+	return r.Status == history.StatusSuccess
+}
+
+// Run assumes opts.RoleName in every account and invokes functionName there,
+// with at most opts.Concurrency accounts in flight. Results are returned in
+// account order; a failure in one account never stops the others.
+func Run(base *awsinterface.AWSInterface, accounts []awsinterface.Account, functionName string, payloadJson []byte, opts Options) []Result {
+	concurrency := opts.Concurrency
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	results := make([]Result, len(accounts))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, concurrency)
+	for i, account := range accounts {
+		wg.Add(1)
+		slots <- struct{}{}
+		go func(i int, account awsinterface.Account) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result := invokeInAccount(base, account, functionName, payloadJson, opts)
+			mu.Lock()
+			results[i] = result
+			if opts.OnResult != nil {
+				opts.OnResult(result)
+			}
+			mu.Unlock()
+		}(i, account)
+	}
+	wg.Wait()
+	return results
+}
+
+func invokeInAccount(base *awsinterface.AWSInterface, account awsinterface.Account, functionName string, payloadJson []byte, opts Options) (result Result) {
+	result = Result{AccountID: account.AccountID, AccountName: account.AccountName}
+	started := time.Now()
+	defer func() { result.DurationMs = time.Since(started).Milliseconds() }()
+
+	target, err := base.ForRole(account.AccountID, opts.RoleName)
+	if err != nil {
+		result.Status = history.StatusFailed
+		result.Error = err.Error()
+		return result
+	}
+
+	invokeResult, err := history.InvokeLambda(target, functionName, payloadJson, opts.Invoke)
+	switch {
+	case err != nil:
+		result.Status = history.StatusFailed
+		result.Error = err.Error()
+		return result
+	case invokeResult.Failed():
+		result.Status = history.StatusFunctionError
+		result.FunctionError = invokeResult.FunctionError
+	default:
+		result.Status = history.StatusSuccess
+	}
+
+	result.StatusCode = invokeResult.StatusCode
+	result.ExecutedVersion = invokeResult.ExecutedVersion
+	if json.Valid(invokeResult.Payload) {
+		result.Response = invokeResult.Payload
+	} else if len(invokeResult.Payload) > 0 {
+		result.Response, _ = json.Marshal(string(invokeResult.Payload))
+	}
+	return result
+}
+
+// Summarize counts results by status, e.g. {"success": 8, "failed": 1}.
+func Summarize(results []Result) map[string]int {
+	counts := map[string]int{}
+	for _, result := range results {
+		counts[result.Status]++
+	}
+	return counts
+}
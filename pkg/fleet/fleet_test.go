@@ -0,0 +1,160 @@
+package fleet
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/history"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+)
+
+var accounts = []awsinterface.Account{
+	{AccountID: "111111111111", AccountName: "prod-eu"},
+	{AccountID: "222222222222", AccountName: "dev"},
+	{AccountID: "333333333333", AccountName: "prod-us"},
+}
+
+func ids(accounts []awsinterface.Account) string {
+	var s []string
+	for _, account := range accounts {
+		s = append(s, account.AccountID)
+	}
+	return strings.Join(s, ",")
+}
+
+func TestSelect(t *testing.T) {
+	tests := []struct {
+		selector Selector
+		want     string
+		wantErr  string
+	}{
+		{selector: Selector{All: true}, want: "222222222222,111111111111,333333333333"},
+		{selector: Selector{NameRegex: "^prod-"}, want: "111111111111,333333333333"},
+		{selector: Selector{AccountIDs: []string{"333333333333"}, NameRegex: "dev"}, want: "222222222222,333333333333"},
+		{selector: Selector{AccountIDs: []string{"111111111111", "111111111111"}}, want: "111111111111"},
+		{selector: Selector{}, wantErr: "no accounts selected"},
+		{selector: Selector{AccountIDs: []string{"444444444444"}}, wantErr: "not accessible"},
+		{selector: Selector{NameRegex: "staging"}, wantErr: "no accessible account"},
+		{selector: Selector{NameRegex: "("}, wantErr: "invalid account name regex"},
+	}
+	for _, test := range tests {
+		selected, err := test.selector.Select(accounts)
+		if test.wantErr != "" {
+			if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+				t.Errorf("Select(%+v) error = %v, want %q", test.selector, err, test.wantErr)
+			}
+			continue
+		}
+		if err != nil || ids(selected) != test.want {
+			t.Errorf("Select(%+v) = %s, %v, want %s", test.selector, ids(selected), err, test.want)
+		}
+	}
+}
+
+// fakeAWS serves the SSO role credentials and Lambda invoke calls Run makes.
+// Invocations in the account "222222222222" report a function error, and
+// every invocation waits for release.
+type fakeAWS struct {
+	release chan struct{}
+
+	mu       sync.Mutex
+	inFlight int
+	peak     int
+}
+
+func (f *fakeAWS) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/federation/credentials" {
+		if r.URL.Query().Get("role_name") != "Deployer" {
+			w.WriteHeader(http.StatusForbidden)
+			fmt.Fprint(w, `{"message": "no such role"}`)
+			return
+		}
+		fmt.Fprintf(w, `{"roleCredentials": {"accessKeyId": "AKID%s", "secretAccessKey": "secret", "sessionToken": "token", "expiration": 0}}`, r.URL.Query().Get("account_id"))
+		return
+	}
+
+	f.mu.Lock()
+	f.inFlight++
+	if f.inFlight > f.peak {
+		f.peak = f.inFlight
+	}
+	f.mu.Unlock()
+	<-f.release
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+
+	w.Header().Set("X-Amz-Executed-Version", "7")
+	if strings.Contains(r.Header.Get("Authorization"), "AKID222222222222") {
+		w.Header().Set("X-Amz-Function-Error", "Unhandled")
+	}
+	fmt.Fprint(w, `{"ok": true}`)
+}
+
+func TestRun(t *testing.T) {
+	t.Setenv(history.DirEnvVariable, t.TempDir())
+	fake := &fakeAWS{release: make(chan struct{})}
+	server := httptest.NewServer(fake)
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+
+	base, err := awsinterface.NewAWSInterface("https://example.awsapps.com/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	many := make([]awsinterface.Account, 7)
+	for i := range many {
+		many[i] = accounts[i%len(accounts)]
+	}
+	var reported int
+	done := make(chan []Result)
+	go func() {
+		done <- Run(base, many, "orders", []byte(`{}`), Options{
+			RoleName:    "Deployer",
+			Concurrency: 2,
+			OnResult:    func(Result) { reported++ },
+		})
+	}()
+	for range many {
+		fake.release <- struct{}{}
+	}
+	results := <-done
+
+	if fake.peak > 2 {
+		t.Errorf("%d accounts were invoked at once, want at most 2", fake.peak)
+	}
+	if reported != len(many) {
+		t.Errorf("OnResult called %d times, want %d", reported, len(many))
+	}
+	for i, result := range results {
+		want := history.StatusSuccess
+		if result.AccountID == "222222222222" {
+			want = history.StatusFunctionError
+		}
+		if result.AccountID != many[i].AccountID || result.Status != want || result.ExecutedVersion != "7" {
+			t.Errorf("result %d = %+v, want %s in %s", i, result, want, many[i].AccountID)
+		}
+	}
+	if got := Summarize(results); got[history.StatusSuccess] != 5 || got[history.StatusFunctionError] != 2 {
+		t.Errorf("Summarize() = %v", got)
+	}
+}
+
+func TestRunReportsAccountsWithoutTheRole(t *testing.T) {
+	server := httptest.NewServer(&fakeAWS{})
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+
+	base, err := awsinterface.NewAWSInterface("https://example.awsapps.com/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	results := Run(base, accounts[:1], "orders", []byte(`{}`), Options{RoleName: "Missing"})
+	if len(results) != 1 || results[0].Status != history.StatusFailed || !strings.Contains(results[0].Error, "account 111111111111") {
+		t.Errorf("Run() = %+v, want the account to fail", results)
+	}
+}
@@ -0,0 +1,454 @@
+package history
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/configdir"
+	"aws_utility/pkg/logger"
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	DirEnvVariable = "AWS_UTILITY_HISTORY_DIR"
+	fileName       = "history.jsonl"
+
+	// maxEntries is how many entries the history keeps; older ones are
+	// dropped once it grows a tenth past that.
+	maxEntries = 1000
+	// staleLock is the age after which a lock file is taken to be left over
+	// from a process that died holding it.
+	staleLock = 10 * time.Second
+
+	StatusSuccess       = "success"
+	StatusFunctionError = "function_error"
+	StatusFailed        = "failed"
+)
+
+type Entry struct {
+	ID        int       `json:"id" yaml:"id"`
+	Timestamp time.Time `json:"timestamp" yaml:"timestamp"`
+
+	awsinterface.Identity `yaml:",inline"`
+
+	Function       string `json:"function" yaml:"function"`
+	Qualifier      string `json:"qualifier,omitempty" yaml:"qualifier,omitempty"`
+	InvocationType string `json:"invocation_type" yaml:"invocation_type"`
+	Streamed       bool   `json:"streamed,omitempty" yaml:"streamed,omitempty"`
+	Payload        string `json:"payload" yaml:"payload"`
+
+	Status        string `json:"status" yaml:"status"`
+	StatusCode    int32  `json:"status_code,omitempty" yaml:"status_code,omitempty"`
+	RequestID     string `json:"request_id,omitempty" yaml:"request_id,omitempty"`
+	DurationMs    int64  `json:"duration_ms" yaml:"duration_ms"`
+	Response      string `json:"response,omitempty" yaml:"response,omitempty"`
+	FunctionError string `json:"function_error,omitempty" yaml:"function_error,omitempty"`
+	Error         string `json:"error,omitempty" yaml:"error,omitempty"`
+}
+
+// QualifiedFunction returns "function:qualifier", or just the function name
+// when $LATEST was invoked.
+func (e Entry) QualifiedFunction() string {
+	if e.Qualifier == "" {
+		return e.Function
+	}
+	return e.Function + ":" + e.Qualifier
+}
+
+func (e Entry) Duration() time.Duration {
+	return time.Duration(e.DurationMs) * time.Millisecond
+}
+
+// LogQuery matches the events the invocation logged.
+func (e Entry) LogQuery() awsinterface.LogQuery {
+	return awsinterface.LogQuery{
+		RequestID: e.RequestID,
+		// Allow for clock skew between this machine and Lambda.
+		Start: e.Timestamp.Add(-time.Minute),
+	}
+}
+
+// Start begins an entry for an invocation that is about to be made.
+func Start(identity awsinterface.Identity, function, qualifier, invocationType string, payloadJson []byte) Entry {
+	if invocationType == "" {
+		invocationType = "RequestResponse"
+	}
+	return Entry{
+		Timestamp:      time.Now(),
+		Identity:       identity,
+		Function:       function,
+		Qualifier:      qualifier,
+		InvocationType: invocationType,
+		Payload:        string(payloadJson),
+	}
+}
+
+// Finish fills in the outcome of a buffered invocation.
+func (e *Entry) Finish(result *awsinterface.InvokeResult, err error) {
+	e.DurationMs = time.Since(e.Timestamp).Milliseconds()
+	switch {
+	case err != nil:
+		e.Status = StatusFailed
+		e.Error = err.Error()
+	case result.Failed():
+		e.Status = StatusFunctionError
+		e.FunctionError = result.FunctionError
+	default:
+		e.Status = StatusSuccess
+	}
+	if result != nil {
+		e.StatusCode = result.StatusCode
+		e.RequestID = result.RequestID
+		e.Response = string(result.Payload)
+	}
+}
+
+// FinishStream fills in the outcome of a response-streaming invocation from
+// the concatenated chunks and the final InvokeComplete event.
+func (e *Entry) FinishStream(statusCode int32, response []byte, complete *awsinterface.StreamComplete, err error) {
+	e.DurationMs = time.Since(e.Timestamp).Milliseconds()
+	e.Streamed = true
+	e.StatusCode = statusCode
+	e.Response = string(response)
+	switch {
+	case err != nil:
+		e.Status = StatusFailed
+		e.Error = err.Error()
+	case complete != nil && complete.Failed():
+		e.Status = StatusFunctionError
+		e.FunctionError = complete.ErrorCode
+		if complete.ErrorDetails != "" {
+			e.FunctionError += ": " + complete.ErrorDetails
+		}
+	default:
+		e.Status = StatusSuccess
+	}
+}
+
+func Dir() (string, error) {
+	return configdir.Path(DirEnvVariable, "history")
+}
+
+func Path() (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, fileName), nil
+}
+
+// mu serialises appends from concurrent invocations in the same process;
+// the lock file does the same across processes.
+var mu sync.Mutex
+
+// Append assigns the entry the next ID and adds it to the history file,
+// dropping the oldest entries beyond maxEntries.
+func Append(entry Entry) (Entry, error) {
+	mu.Lock()
+	defer mu.Unlock()
+
+	path, err := Path()
+	if err != nil {
+		return entry, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return entry, fmt.Errorf("failed to create history directory: %v", err)
+	}
+	unlock, err := lock(path)
+	if err != nil {
+		return entry, err
+	}
+	defer unlock()
+
+	first, last, err := edgeIDs(path)
+	if err != nil {
+		return entry, err
+	}
+	entry.ID = last + 1
+
+	line, err := json.Marshal(entry)
+	if err != nil {
+		return entry, fmt.Errorf("failed to encode history entry: %v", err)
+	}
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return entry, fmt.Errorf("failed to open history file: %v", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return entry, fmt.Errorf("failed to write history entry: %v", err)
+	}
+
+	if first > 0 && entry.ID-first+1 > maxEntries+maxEntries/10 {
+		if err := trim(path); err != nil {
+			logger.Warn("Failed to trim invocation history:", err)
+		}
+	}
+	return entry, nil
+}
+
+// lock creates the lock file next to the history file, waiting while
+// another process holds it.
+func lock(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(2 * staleLock)
+	for {
+		file, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o600)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("failed to lock history file: %v", err)
+		}
+		if info, err := os.Stat(lockPath); err == nil && time.Since(info.ModTime()) > staleLock {
+			os.Remove(lockPath)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("history file is locked by %s", lockPath)
+		}
+		time.Sleep(20 * time.Millisecond)
+	}
+}
+
+// edgeIDs returns the IDs of the first and last entries, reading only the
+// ends of the file. Both are zero for an empty or missing history.
+func edgeIDs(path string) (int, int, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return 0, 0, nil
+	}
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open history file: %v", err)
+	}
+	defer file.Close()
+
+	var first struct{ ID int }
+	if err := json.NewDecoder(file).Decode(&first); err != nil {
+		if err == io.EOF {
+			return 0, 0, nil
+		}
+		return 0, 0, fmt.Errorf("failed to parse history file %s: %v", path, err)
+	}
+
+	info, err := file.Stat()
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read history file: %v", err)
+	}
+	line, err := lastLine(file, info.Size())
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to read history file: %v", err)
+	}
+	var last struct{ ID int }
+	if err := json.Unmarshal(line, &last); err != nil {
+		return 0, 0, fmt.Errorf("failed to parse the last entry of history file %s: %v", path, err)
+	}
+	return first.ID, last.ID, nil
+}
+
+// lastLine reads backwards from the end of the file to its last non-empty
+// line.
+func lastLine(file *os.File, size int64) ([]byte, error) {
+	const chunkSize = 64 * 1024
+	var tail []byte
+	for end := size; end > 0; {
+		start := max(end-chunkSize, 0)
+		chunk := make([]byte, end-start)
+		if _, err := file.ReadAt(chunk, start); err != nil {
+			return nil, err
+		}
+		tail = append(chunk, tail...)
+		end = start
+
+		trimmed := bytes.TrimRight(tail, "\n")
+		if i := bytes.LastIndexByte(trimmed, '\n'); i >= 0 {
+			return trimmed[i+1:], nil
+		}
+		if end == 0 {
+			return trimmed, nil
+		}
+	}
+	return nil, nil
+}
+
+// trim rewrites the history file with only its newest maxEntries entries.
+func trim(path string) error {
+	entries, err := load(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) > maxEntries {
+		entries = entries[len(entries)-maxEntries:]
+	}
+
+	temp, err := os.CreateTemp(filepath.Dir(path), fileName+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(temp.Name())
+	writer := bufio.NewWriter(temp)
+	for _, entry := range entries {
+		line, err := json.Marshal(entry)
+		if err != nil {
+			temp.Close()
+			return err
+		}
+		writer.Write(append(line, '\n'))
+	}
+	if err := writer.Flush(); err != nil {
+		temp.Close()
+		return err
+	}
+	if err := temp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(temp.Name(), path)
+}
+
+// Record appends the entry and logs, rather than returns, any failure so
+// that history problems never fail an invocation. It returns the entry with
+// its ID when it was recorded.
+func Record(entry Entry) Entry {
+	recorded, err := Append(entry)
+	if err != nil {
+		logger.Error("Failed to record invocation history:", err)
+	}
+	return recorded
+}
+
+// InvokeLambda invokes through awsInterface and records the invocation.
+func InvokeLambda(awsInterface *awsinterface.AWSInterface, functionName string, payloadJson []byte, opts awsinterface.InvokeOptions) (*awsinterface.InvokeResult, error) {
+	result, _, err := Invoke(awsInterface, functionName, payloadJson, opts)
+	return result, err
+}
+
+// Invoke is InvokeLambda returning the recorded entry as well.
+func Invoke(awsInterface *awsinterface.AWSInterface, functionName string, payloadJson []byte, opts awsinterface.InvokeOptions) (*awsinterface.InvokeResult, Entry, error) {
+	entry := Start(awsInterface.Identity(), functionName, opts.Qualifier, string(opts.InvocationType), payloadJson)
+	result, err := awsInterface.InvokeLambda(functionName, payloadJson, opts)
+	entry.Finish(result, err)
+	return result, Record(entry), err
+}
+
+// InvokeStream starts a response-streaming invocation through awsInterface
+// and records it once the stream ends, before its Events channel, which
+// passes the events on, is closed. The entry returned is the one started,
+// without the outcome.
+func InvokeStream(ctx context.Context, awsInterface *awsinterface.AWSInterface, functionName string, payloadJson []byte, opts awsinterface.InvokeOptions) (*awsinterface.InvokeStream, Entry, error) {
+	entry := Start(awsInterface.Identity(), functionName, opts.Qualifier, string(opts.InvocationType), payloadJson)
+	stream, err := awsInterface.InvokeLambdaStream(ctx, functionName, payloadJson, opts)
+	if err != nil {
+		entry.FinishStream(0, nil, nil, err)
+		return nil, Record(entry), err
+	}
+	entry.StatusCode = stream.StatusCode
+	entry.RequestID = stream.RequestID
+	return recordStream(ctx, stream, entry), entry, nil
+}
+
+// recordStream passes on the events of stream and records entry with the
+// outcome once they end, before closing the returned stream's Events.
+func recordStream(ctx context.Context, stream *awsinterface.InvokeStream, entry Entry) *awsinterface.InvokeStream {
+	events := make(chan awsinterface.StreamEvent)
+	recorded := *stream
+	recorded.Events = events
+	go func() {
+		defer close(events)
+		var response []byte
+		var complete *awsinterface.StreamComplete
+		var streamErr error
+		for event := range stream.Events {
+			switch {
+			case event.Err != nil:
+				streamErr = event.Err
+			case event.Complete != nil:
+				complete = event.Complete
+			default:
+				response = append(response, event.Chunk...)
+			}
+			select {
+			case events <- event:
+			case <-ctx.Done():
+				streamErr = ctx.Err()
+			}
+		}
+		if streamErr == nil && complete == nil && ctx.Err() != nil {
+			streamErr = ctx.Err()
+		}
+		entry.FinishStream(stream.StatusCode, response, complete, streamErr)
+		Record(entry)
+	}()
+	return &recorded
+}
+
+// Load returns every recorded entry, oldest first. A missing history file
+// is not an error.
+func Load() ([]Entry, error) {
+	path, err := Path()
+	if err != nil {
+		return nil, err
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	return load(path)
+}
+
+func load(path string) ([]Entry, error) {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to open history file: %v", err)
+	}
+	defer file.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		if len(scanner.Bytes()) == 0 {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("failed to parse history file %s line %d: %v", path, lineNumber, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read history file: %v", err)
+	}
+	return entries, nil
+}
+
+// Last returns the most recent entry.
+func Last(entries []Entry) (*Entry, error) {
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("the invocation history is empty")
+	}
+	return &entries[len(entries)-1], nil
+}
+
+// Find looks up an entry by the ID shown in `history list`.
+func Find(entries []Entry, id string) (*Entry, error) {
+	n, err := strconv.Atoi(id)
+	if err != nil {
+		return nil, fmt.Errorf("invalid history ID %q", id)
+	}
+	for i := range entries {
+		if entries[i].ID == n {
+			return &entries[i], nil
+		}
+	}
+	return nil, fmt.Errorf("history entry %d not found", n)
+}
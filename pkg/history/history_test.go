@@ -0,0 +1,231 @@
+package history
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func useTempDir(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	t.Setenv(DirEnvVariable, dir)
+	return dir
+}
+
+func TestAppendAndFind(t *testing.T) {
+	useTempDir(t)
+
+	for _, function := range []string{"orders", "users", "billing"} {
+		entry := Start(awsinterface.Identity{AccountID: "111111111111"}, function, "", "", []byte(`{}`))
+		entry.Finish(&awsinterface.InvokeResult{StatusCode: 200, Payload: []byte(`"ok"`)}, nil)
+		if _, err := Append(entry); err != nil {
+			t.Fatalf("Append() error = %v", err)
+		}
+	}
+
+	entries, err := Load()
+	if err != nil {
+		t.Fatalf("Load() error = %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("Load() = %d entries, want 3", len(entries))
+	}
+	for i, entry := range entries {
+		if entry.ID != i+1 {
+			t.Errorf("entry %d has ID %d", i, entry.ID)
+		}
+	}
+
+	entry, err := Find(entries, "2")
+	if err != nil || entry.Function != "users" || entry.InvocationType != "RequestResponse" || entry.Status != StatusSuccess {
+		t.Errorf("Find(2) = %+v, %v", entry, err)
+	}
+	if _, err := Find(entries, "4"); err == nil || !strings.Contains(err.Error(), "not found") {
+		t.Errorf("Find(4) error = %v, want not found", err)
+	}
+	if _, err := Find(entries, "last"); err == nil || !strings.Contains(err.Error(), "invalid history ID") {
+		t.Errorf("Find(last) error = %v, want an invalid ID", err)
+	}
+
+	last, err := Last(entries)
+	if err != nil || last.ID != 3 || last.Function != "billing" {
+		t.Errorf("Last() = %+v, %v, want billing", last, err)
+	}
+	if _, err := Last(nil); err == nil {
+		t.Error("Last() of an empty history error = nil, want an error")
+	}
+}
+
+func TestLoadWithoutHistory(t *testing.T) {
+	useTempDir(t)
+	entries, err := Load()
+	if err != nil || len(entries) != 0 {
+		t.Errorf("Load() = %v, %v, want no entries", entries, err)
+	}
+}
+
+func TestLoadNamesCorruptLine(t *testing.T) {
+	dir := useTempDir(t)
+	if err := os.WriteFile(filepath.Join(dir, fileName), []byte("{\"id\":1}\n\n{broken\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(); err == nil || !strings.Contains(err.Error(), "line 3") {
+		t.Errorf("Load() error = %v, want the corrupt line", err)
+	}
+}
+
+func TestFinish(t *testing.T) {
+	tests := []struct {
+		result     *awsinterface.InvokeResult
+		err        error
+		wantStatus string
+	}{
+		{result: &awsinterface.InvokeResult{StatusCode: 200}, wantStatus: StatusSuccess},
+		{result: &awsinterface.InvokeResult{StatusCode: 200, FunctionError: "Unhandled"}, wantStatus: StatusFunctionError},
+		{err: errors.New("throttled"), wantStatus: StatusFailed},
+	}
+	for _, test := range tests {
+		entry := Start(awsinterface.Identity{}, "orders", "live", "Event", nil)
+		entry.Finish(test.result, test.err)
+		if entry.Status != test.wantStatus {
+			t.Errorf("Finish(%+v, %v) status = %s, want %s", test.result, test.err, entry.Status, test.wantStatus)
+		}
+	}
+
+	entry := Start(awsinterface.Identity{}, "orders", "live", "", nil)
+	entry.FinishStream(200, []byte("chunk"), &awsinterface.StreamComplete{ErrorCode: "Boom", ErrorDetails: "failed"}, nil)
+	if !entry.Streamed || entry.Status != StatusFunctionError || entry.FunctionError != "Boom: failed" || entry.Response != "chunk" {
+		t.Errorf("FinishStream() = %+v", entry)
+	}
+	if got := entry.QualifiedFunction(); got != "orders:live" {
+		t.Errorf("QualifiedFunction() = %s", got)
+	}
+}
+
+func TestAppendTrimsOldEntries(t *testing.T) {
+	dir := useTempDir(t)
+	var lines bytes.Buffer
+	for id := 1; id < maxEntries+maxEntries/10; id++ {
+		fmt.Fprintf(&lines, "{\"id\":%d,\"function\":\"orders\"}\n", id)
+	}
+	if err := os.WriteFile(filepath.Join(dir, fileName), lines.Bytes(), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	// The history may grow to 1100 entries before it is trimmed to 1000.
+	if _, err := Append(Start(awsinterface.Identity{}, "orders", "", "", nil)); err != nil {
+		t.Fatal(err)
+	}
+	if entries, _ := Load(); len(entries) != 1100 {
+		t.Fatalf("history has %d entries, want 1100 before trimming", len(entries))
+	}
+	entry, err := Append(Start(awsinterface.Identity{}, "users", "", "", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != maxEntries || entries[0].ID != 102 || entry.ID != 1101 || entries[len(entries)-1].Function != "users" {
+		t.Errorf("trimmed history has %d entries from %d to %d, want 1000 from 102 to 1101", len(entries), entries[0].ID, entries[len(entries)-1].ID)
+	}
+}
+
+func TestAppendWaitsForTheLock(t *testing.T) {
+	dir := useTempDir(t)
+	lockPath := filepath.Join(dir, fileName+".lock")
+	if err := os.WriteFile(lockPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	done := make(chan error)
+	go func() {
+		_, err := Append(Start(awsinterface.Identity{}, "orders", "", "", nil))
+		done <- err
+	}()
+	select {
+	case err := <-done:
+		t.Fatalf("Append() returned %v while another process held the lock", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	os.Remove(lockPath)
+	if err := <-done; err != nil {
+		t.Fatalf("Append() error = %v", err)
+	}
+	if _, err := os.Stat(lockPath); !os.IsNotExist(err) {
+		t.Errorf("lock file left behind: %v", err)
+	}
+
+	// A lock left by a process that died is taken over.
+	if err := os.WriteFile(lockPath, nil, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	old := time.Now().Add(-time.Minute)
+	os.Chtimes(lockPath, old, old)
+	if _, err := Append(Start(awsinterface.Identity{}, "orders", "", "", nil)); err != nil {
+		t.Errorf("Append() with a stale lock error = %v", err)
+	}
+}
+
+// streamOf returns a stream that sends events and then ends.
+func streamOf(events ...awsinterface.StreamEvent) *awsinterface.InvokeStream {
+	ch := make(chan awsinterface.StreamEvent, len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	close(ch)
+	return &awsinterface.InvokeStream{StatusCode: 200, RequestID: "req-1", Events: ch}
+}
+
+func TestRecordStream(t *testing.T) {
+	useTempDir(t)
+	stream := recordStream(context.Background(), streamOf(
+		awsinterface.StreamEvent{Chunk: []byte(`{"part":`)},
+		awsinterface.StreamEvent{Chunk: []byte(`1}`)},
+		awsinterface.StreamEvent{Complete: &awsinterface.StreamComplete{}},
+	), Start(awsinterface.Identity{}, "orders", "live", "", []byte(`{}`)))
+
+	var received int
+	for range stream.Events {
+		received++
+	}
+	if received != 3 {
+		t.Errorf("stream passed on %d events, want 3", received)
+	}
+	// The entry is recorded before Events is closed.
+	entries, err := Load()
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("Load() = %+v, %v, want the streamed invocation", entries, err)
+	}
+	if entry := entries[0]; !entry.Streamed || entry.Status != StatusSuccess || entry.Response != `{"part":1}` || entry.StatusCode != 200 {
+		t.Errorf("recorded entry = %+v", entry)
+	}
+}
+
+func TestRecordStreamStopsWhenCancelled(t *testing.T) {
+	useTempDir(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := recordStream(ctx, streamOf(
+		awsinterface.StreamEvent{Chunk: []byte("first")},
+		awsinterface.StreamEvent{Chunk: []byte("second")},
+	), Start(awsinterface.Identity{}, "orders", "", "", nil))
+
+	// Nobody reads the events after the first, as when the user goes back.
+	<-stream.Events
+	cancel()
+	for range stream.Events {
+	}
+	entries, err := Load()
+	if err != nil || len(entries) != 1 || entries[0].Status != StatusFailed || !strings.Contains(entries[0].Error, "canceled") {
+		t.Errorf("Load() = %+v, %v, want the invocation recorded as cancelled", entries, err)
+	}
+}
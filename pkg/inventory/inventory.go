@@ -0,0 +1,209 @@
+package inventory
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Record is one function in the inventory, with the account it was found in.
+type Record struct {
+	AccountID   string
+	AccountName string
+	Function    awsinterface.LambdaFunction
+	Tags        map[string]string
+}
+
+// Column extracts one field of a record. Columns named "tag:<key>" give
+// the value of a single tag.
+type Column struct {
+	Name  string
+	value func(Record) string
+	// numeric columns sort by value rather than alphabetically.
+	numeric bool
+}
+
+var columns = map[string]Column{
+	"account_id":    {value: func(r Record) string { return r.AccountID }},
+	"account_name":  {value: func(r Record) string { return r.AccountName }},
+	"region":        {value: func(r Record) string { return r.Function.Region }},
+	"name":          {value: func(r Record) string { return r.Function.Name }},
+	"arn":           {value: func(r Record) string { return r.Function.ARN }},
+	"runtime":       {value: func(r Record) string { return r.Function.Runtime }},
+	"architecture":  {value: func(r Record) string { return r.Function.Architecture }},
+	"memory_size":   {value: func(r Record) string { return fmt.Sprintf("%d", r.Function.MemorySize) }, numeric: true},
+	"timeout":       {value: func(r Record) string { return fmt.Sprintf("%d", r.Function.Timeout) }, numeric: true},
+	"package_type":  {value: func(r Record) string { return r.Function.PackageType }},
+	"handler":       {value: func(r Record) string { return r.Function.Handler }},
+	"last_modified": {value: func(r Record) string { return r.Function.LastModified }},
+	"description":   {value: func(r Record) string { return r.Function.Description }},
+	"tags":          {value: func(r Record) string { return formatTags(r.Tags) }},
+}
+
+var DefaultColumns = []string{"account_id", "account_name", "region", "name", "runtime", "memory_size", "timeout", "last_modified", "tags"}
+
+// ColumnNames lists the built-in columns, for help texts.
+func ColumnNames() []string {
+	names := make([]string, 0, len(columns))
+	for name := range columns {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// ParseColumns reads column names, each of which may be a comma-separated
+// list.
+func ParseColumns(names []string) ([]Column, error) {
+	if len(names) == 0 {
+		names = DefaultColumns
+	}
+	var parsed []Column
+	for _, list := range names {
+		for _, name := range strings.Split(list, ",") {
+			column, err := parseColumn(strings.TrimSpace(name))
+			if err != nil {
+				return nil, err
+			}
+			parsed = append(parsed, column)
+		}
+	}
+	return parsed, nil
+}
+
+func parseColumn(name string) (Column, error) {
+	if key, ok := strings.CutPrefix(name, "tag:"); ok && key != "" {
+		return Column{Name: name, value: func(r Record) string { return r.Tags[key] }}, nil
+	}
+	column, ok := columns[name]
+	if !ok {
+		return Column{}, fmt.Errorf("unknown column %q, expected one of %s or tag:<key>", name, strings.Join(ColumnNames(), ", "))
+	}
+	column.Name = name
+	return column, nil
+}
+
+// NeedsTags reports whether any column reads tags, which costs one extra
+// call per function.
+func NeedsTags(columns []Column, sortBy string) bool {
+	for _, column := range columns {
+		if column.Name == "tags" || strings.HasPrefix(column.Name, "tag:") {
+			return true
+		}
+	}
+	return strings.HasPrefix(strings.TrimPrefix(sortBy, "-"), "tag")
+}
+
+func (c Column) Value(record Record) string {
+	return c.value(record)
+}
+
+func formatTags(tags map[string]string) string {
+	keys := make([]string, 0, len(tags))
+	for key := range tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	pairs := make([]string, len(keys))
+	for i, key := range keys {
+		pairs[i] = key + "=" + tags[key]
+	}
+	return strings.Join(pairs, ";")
+}
+
+// SortFunc orders records by a column; a leading "-" sorts descending.
+func SortFunc(sortBy string) (func(a, b Record) bool, error) {
+	descending := strings.HasPrefix(sortBy, "-")
+	column, err := parseColumn(strings.TrimPrefix(sortBy, "-"))
+	if err != nil {
+		return nil, err
+	}
+	less := func(a, b Record) bool {
+		left, right := column.Value(a), column.Value(b)
+		if column.numeric && len(left) != len(right) {
+			return len(left) < len(right)
+		}
+		return left < right
+	}
+	if descending {
+		return func(a, b Record) bool { return less(b, a) }, nil
+	}
+	return less, nil
+}
+
+// Source is where functions are collected from: an account and the way to
+// reach it. Open is only called when the account's turn comes, so the
+// credentials it returns are fresh however long earlier accounts took.
+type Source struct {
+	AccountID   string
+	AccountName string
+	// Open returns an interface for the account and the regions to search
+	// in it.
+	Open func() (*awsinterface.AWSInterface, []string, error)
+}
+
+type Options struct {
+	Filter   awsinterface.FunctionFilter
+	WithTags bool
+	Less     func(a, b Record) bool
+	// Warn reports a region or function that could not be read; the export
+	// goes on without it.
+	Warn func(error)
+}
+
+// Walk lists the functions of each source in turn and passes them to emit,
+// sorted within the source. Only one account is held in memory at a time,
+// so the order applies within each account rather than across them. A
+// source that cannot be opened is reported to Warn and skipped.
+func Walk(sources []Source, opts Options, emit func(Record) error) error {
+	warn := opts.Warn
+	if warn == nil {
+		warn = func(error) {}
+	}
+
+	for _, source := range sources {
+		awsInterface, regions, err := source.Open()
+		if err != nil {
+			warn(fmt.Errorf("skipping account %s (%s): %v", source.AccountName, source.AccountID, err))
+			continue
+		}
+		functions, err := awsInterface.ListLambdaFunctionsInRegions(opts.Filter, regions)
+		var regionErrors awsinterface.RegionErrors
+		if errors.As(err, &regionErrors) {
+			warn(fmt.Errorf("account %s: %v", source.AccountID, regionErrors))
+		} else if err != nil {
+			return fmt.Errorf("account %s: %v", source.AccountID, err)
+		}
+
+		// One client per region, not per function.
+		regional := map[string]*awsinterface.AWSInterface{}
+		records := make([]Record, len(functions))
+		for i, function := range functions {
+			records[i] = Record{AccountID: source.AccountID, AccountName: source.AccountName, Function: function}
+			if opts.WithTags {
+				client, ok := regional[function.Region]
+				if !ok {
+					client = awsInterface.ForRegion(function.Region)
+					regional[function.Region] = client
+				}
+				tags, err := client.GetFunctionTags(function.Name)
+				if err != nil {
+					warn(fmt.Errorf("account %s, %s: %v", source.AccountID, function.Name, err))
+				}
+				records[i].Tags = tags
+			}
+		}
+		if opts.Less != nil {
+			sort.SliceStable(records, func(i, j int) bool { return opts.Less(records[i], records[j]) })
+		}
+
+		for _, record := range records {
+			if err := emit(record); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
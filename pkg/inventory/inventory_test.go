@@ -0,0 +1,153 @@
+package inventory
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/history"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// fakeLambda lists two functions per region and tags each with its region.
+// Requests signed for ap-south-1 are denied.
+func fakeLambda(w http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/federation/credentials" {
+		fmt.Fprint(w, `{"roleCredentials": {"accessKeyId": "a", "secretAccessKey": "b", "sessionToken": "c", "expiration": 0}}`)
+		return
+	}
+	region := regexp.MustCompile(`/\d{8}/([a-z0-9-]+)/lambda/`).FindStringSubmatch(r.Header.Get("Authorization"))[1]
+	if region == "ap-south-1" {
+		w.WriteHeader(http.StatusForbidden)
+		fmt.Fprint(w, `{"message": "denied"}`)
+		return
+	}
+
+	arn := func(name string) string {
+		return fmt.Sprintf("arn:aws:lambda:%s:111111111111:function:%s", region, name)
+	}
+	if name, ok := strings.CutPrefix(r.URL.Path, "/2015-03-31/functions/"); ok && name != "" {
+		fmt.Fprintf(w, `{"Configuration": {"FunctionName": %q, "FunctionArn": %q}, "Tags": {"team": %q}}`, name, arn(name), region)
+		return
+	}
+	fmt.Fprintf(w, `{"Functions": [{"FunctionName": "api", "FunctionArn": %q, "MemorySize": 128}, {"FunctionName": "orders", "FunctionArn": %q, "MemorySize": 1024}]}`, arn("api"), arn("orders"))
+}
+
+func TestExport(t *testing.T) {
+	t.Setenv(history.DirEnvVariable, t.TempDir())
+	server := httptest.NewServer(http.HandlerFunc(fakeLambda))
+	defer server.Close()
+	t.Setenv("AWS_ENDPOINT_URL", server.URL)
+
+	base, err := awsinterface.NewAWSInterface("https://example.awsapps.com/start")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sources := []Source{
+		{
+			AccountID:   "222222222222",
+			AccountName: "dev",
+			Open: func() (*awsinterface.AWSInterface, []string, error) {
+				return nil, nil, errors.New("access denied")
+			},
+		},
+		{
+			AccountID:   "111111111111",
+			AccountName: "prod",
+			Open: func() (*awsinterface.AWSInterface, []string, error) {
+				awsInterface, err := base.ForRole("111111111111", "Admin")
+				return awsInterface, []string{"eu-west-1", "ap-south-1", "us-east-1"}, err
+			},
+		},
+	}
+
+	columns, err := ParseColumns([]string{"account_name,region,name", "memory_size", "tag:team"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	less, err := SortFunc("-memory_size")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out strings.Builder
+	writer, err := NewWriter(&out, FormatCSV, columns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var warnings []string
+	opts := Options{
+		WithTags: NeedsTags(columns, "-memory_size"),
+		Less:     less,
+		Warn:     func(err error) { warnings = append(warnings, err.Error()) },
+	}
+	if err := Walk(sources, opts, writer.Write); err != nil {
+		t.Fatalf("Walk() error = %v", err)
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	want := `account_name,region,name,memory_size,tag:team
+prod,eu-west-1,orders,1024,eu-west-1
+prod,us-east-1,orders,1024,us-east-1
+prod,eu-west-1,api,128,eu-west-1
+prod,us-east-1,api,128,us-east-1
+`
+	if out.String() != want {
+		t.Errorf("export =\n%s\nwant\n%s", out.String(), want)
+	}
+	if len(warnings) != 2 || !strings.Contains(warnings[0], "skipping account dev (222222222222): access denied") || !strings.Contains(warnings[1], "ap-south-1") {
+		t.Errorf("warnings = %q", warnings)
+	}
+}
+
+func TestWriters(t *testing.T) {
+	columns, err := ParseColumns([]string{"name,description"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := Record{Function: awsinterface.LambdaFunction{Name: "orders", Description: "a|b\nc"}}
+
+	for format, want := range map[string]string{
+		FormatJSON:     "[\n  {\"name\":\"orders\",\"description\":\"a|b\\nc\"}\n]\n",
+		FormatNDJSON:   "{\"name\":\"orders\",\"description\":\"a|b\\nc\"}\n",
+		FormatMarkdown: "| name | description |\n| --- | --- |\n| orders | a\\|b c |\n",
+	} {
+		var out strings.Builder
+		writer, err := NewWriter(&out, format, columns)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Write(record); err != nil {
+			t.Fatal(err)
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != want {
+			t.Errorf("%s export = %q, want %q", format, out.String(), want)
+		}
+	}
+
+	// An export that found nothing is still a valid document.
+	var empty strings.Builder
+	writer, _ := NewWriter(&empty, FormatJSON, columns)
+	writer.Close()
+	if empty.String() != "[]\n" {
+		t.Errorf("empty json export = %q", empty.String())
+	}
+	if _, err := NewWriter(&empty, "xml", columns); err == nil {
+		t.Error("NewWriter(xml) error = nil, want an error")
+	}
+}
+
+func TestParseColumnsRejectsUnknownNames(t *testing.T) {
+	for _, name := range []string{"size", "tag:", "name,,region"} {
+		if _, err := ParseColumns([]string{name}); err == nil {
+			t.Errorf("ParseColumns(%q) error = nil, want an error", name)
+		}
+	}
+}
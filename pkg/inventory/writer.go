@@ -0,0 +1,163 @@
+package inventory
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	FormatCSV      = "csv"
+	FormatJSON     = "json"
+	FormatNDJSON   = "ndjson"
+	FormatMarkdown = "markdown"
+)
+
+// Writer writes records as they arrive; Close finishes the document.
+type Writer interface {
+	Write(Record) error
+	Close() error
+}
+
+func NewWriter(w io.Writer, format string, columns []Column) (Writer, error) {
+	switch format {
+	case FormatCSV:
+		return &csvWriter{writer: csv.NewWriter(w), columns: columns}, nil
+	case FormatJSON, FormatNDJSON:
+		return &jsonWriter{w: w, columns: columns, array: format == FormatJSON}, nil
+	case FormatMarkdown:
+		return &markdownWriter{w: w, columns: columns}, nil
+	}
+	return nil, fmt.Errorf("invalid inventory format %q, expected csv, json, ndjson or markdown", format)
+}
+
+func values(columns []Column, record Record) []string {
+	row := make([]string, len(columns))
+	for i, column := range columns {
+		row[i] = column.Value(record)
+	}
+	return row
+}
+
+func names(columns []Column) []string {
+	header := make([]string, len(columns))
+	for i, column := range columns {
+		header[i] = column.Name
+	}
+	return header
+}
+
+type csvWriter struct {
+	writer  *csv.Writer
+	columns []Column
+	started bool
+}
+
+func (c *csvWriter) Write(record Record) error {
+	if !c.started {
+		c.started = true
+		if err := c.writer.Write(names(c.columns)); err != nil {
+			return err
+		}
+	}
+	if err := c.writer.Write(values(c.columns, record)); err != nil {
+		return err
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+func (c *csvWriter) Close() error {
+	if !c.started {
+		c.started = true
+		if err := c.writer.Write(names(c.columns)); err != nil {
+			return err
+		}
+	}
+	c.writer.Flush()
+	return c.writer.Error()
+}
+
+// jsonWriter writes one object per record, either one per line or as the
+// elements of an array written incrementally.
+type jsonWriter struct {
+	w       io.Writer
+	columns []Column
+	array   bool
+	count   int
+}
+
+func (j *jsonWriter) Write(record Record) error {
+	// Built by hand so keys keep the column order.
+	fields := make([]string, len(j.columns))
+	for i, column := range j.columns {
+		key, _ := json.Marshal(column.Name)
+		value, _ := json.Marshal(column.Value(record))
+		fields[i] = fmt.Sprintf("%s:%s", key, value)
+	}
+	object := "{" + strings.Join(fields, ",") + "}"
+
+	var err error
+	switch {
+	case !j.array:
+		_, err = fmt.Fprintln(j.w, object)
+	case j.count == 0:
+		_, err = fmt.Fprintf(j.w, "[\n  %s", object)
+	default:
+		_, err = fmt.Fprintf(j.w, ",\n  %s", object)
+	}
+	j.count++
+	return err
+}
+
+func (j *jsonWriter) Close() error {
+	if !j.array {
+		return nil
+	}
+	if j.count == 0 {
+		_, err := fmt.Fprintln(j.w, "[]")
+		return err
+	}
+	_, err := fmt.Fprintln(j.w, "\n]")
+	return err
+}
+
+type markdownWriter struct {
+	w       io.Writer
+	columns []Column
+	started bool
+}
+
+func (m *markdownWriter) header() error {
+	m.started = true
+	separators := make([]string, len(m.columns))
+	for i := range separators {
+		separators[i] = "---"
+	}
+	_, err := fmt.Fprintf(m.w, "| %s |\n| %s |\n", strings.Join(names(m.columns), " | "), strings.Join(separators, " | "))
+	return err
+}
+
+func (m *markdownWriter) Write(record Record) error {
+	if !m.started {
+		if err := m.header(); err != nil {
+			return err
+		}
+	}
+	row := values(m.columns, record)
+	for i, value := range row {
+		value = strings.ReplaceAll(value, "|", "\\|")
+		row[i] = strings.ReplaceAll(value, "\n", " ")
+	}
+	_, err := fmt.Fprintf(m.w, "| %s |\n", strings.Join(row, " | "))
+	return err
+}
+
+func (m *markdownWriter) Close() error {
+	if !m.started {
+		return m.header()
+	}
+	return nil
+}
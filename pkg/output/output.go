@@ -0,0 +1,158 @@
+package output
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatText = "text"
+	FormatJSON = "json"
+	FormatYAML = "yaml"
+)
+
+type Row struct {
+	Label string
+	Value string
+}
+
+func CheckFormat(format string) error {
+	switch format {
+	case FormatText, FormatJSON, FormatYAML:
+		return nil
+	}
+	return fmt.Errorf("invalid output format %q, expected text, json or yaml", format)
+}
+
+// Write encodes v as indented JSON or YAML.
+func Write(w io.Writer, format string, v interface{}) error {
+	switch format {
+	case FormatJSON:
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		return encoder.Encode(v)
+	case FormatYAML:
+		encoder := yaml.NewEncoder(w)
+		encoder.SetIndent(2)
+		if err := encoder.Encode(v); err != nil {
+			return err
+		}
+		return encoder.Close()
+	}
+	return fmt.Errorf("unsupported output format %q", format)
+}
+
+func WriteRows(w io.Writer, rows []Row) error {
+	writer := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, row := range rows {
+		fmt.Fprintf(writer, "%s:\t%s\n", row.Label, row.Value)
+	}
+	return writer.Flush()
+}
+
+// FunctionDetailRows flattens FunctionDetails into label/value pairs for
+// the text output and the detail views. Empty values are skipped.
+func FunctionDetailRows(details *awsinterface.FunctionDetails) []Row {
+	rows := []Row{
+		{"Name", details.Name},
+		{"ARN", details.ARN},
+		{"Version", details.Version},
+		{"Description", details.Description},
+		{"State", joinNonEmpty(" — ", details.State, details.StateReason)},
+		{"Last update", joinNonEmpty(" — ", details.LastUpdateStatus, details.LastUpdateStatusReason)},
+		{"Last modified", details.LastModified},
+		{"Package type", details.PackageType},
+		{"Runtime", details.Runtime},
+		{"Handler", details.Handler},
+		{"Image", details.ImageURI},
+		{"Architecture", details.Architecture},
+		{"Memory", fmt.Sprintf("%d MB", details.MemorySize)},
+		{"Timeout", fmt.Sprintf("%d s", details.Timeout)},
+		{"Role", details.Role},
+		{"Code SHA-256", details.CodeSha256},
+		{"Code size", fmt.Sprintf("%d bytes", details.CodeSize)},
+		{"Revision", details.RevisionID},
+	}
+
+	if details.ReservedConcurrency != nil {
+		rows = append(rows, Row{"Reserved concurrency", fmt.Sprintf("%d", *details.ReservedConcurrency)})
+	} else {
+		rows = append(rows, Row{"Reserved concurrency", "unreserved"})
+	}
+	rows = append(rows, Row{"Dead-letter target", details.DeadLetterTarget})
+	rows = append(rows, Row{"Environment keys", strings.Join(details.EnvironmentKeys, ", ")})
+
+	if vpc := details.VpcConfig; vpc != nil {
+		rows = append(rows,
+			Row{"VPC", vpc.VpcID},
+			Row{"Subnets", strings.Join(vpc.SubnetIDs, ", ")},
+			Row{"Security groups", strings.Join(vpc.SecurityGroupIDs, ", ")},
+		)
+	}
+	for i, layer := range details.Layers {
+		rows = append(rows, Row{fmt.Sprintf("Layer %d", i+1), fmt.Sprintf("%s (%d bytes)", layer.ARN, layer.CodeSize)})
+	}
+
+	keys := make([]string, 0, len(details.Tags))
+	for key := range details.Tags {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		rows = append(rows, Row{"Tag " + key, details.Tags[key]})
+	}
+
+	nonEmpty := rows[:0]
+	for _, row := range rows {
+		if row.Value != "" {
+			nonEmpty = append(nonEmpty, row)
+		}
+	}
+	return nonEmpty
+}
+
+func joinNonEmpty(separator string, values ...string) string {
+	var parts []string
+	for _, value := range values {
+		if value != "" {
+			parts = append(parts, value)
+		}
+	}
+	return strings.Join(parts, separator)
+}
+
+// ConcurrencyRows lists the function's reserved concurrency, the account's
+// pool and one row per provisioned version or alias.
+func ConcurrencyRows(concurrency *awsinterface.Concurrency) []Row {
+	reserved := "none (uses the unreserved pool)"
+	if concurrency.Reserved != nil {
+		reserved = fmt.Sprintf("%d", *concurrency.Reserved)
+		if *concurrency.Reserved == 0 {
+			reserved += " (throttled)"
+		}
+	}
+	rows := []Row{
+		{Label: "Function", Value: concurrency.FunctionName},
+		{Label: "Reserved concurrency", Value: reserved},
+		{Label: "Account limit", Value: fmt.Sprintf("%d", concurrency.AccountLimit)},
+		{Label: "Unreserved concurrency", Value: fmt.Sprintf("%d", concurrency.UnreservedConcurrency)},
+	}
+	if len(concurrency.Provisioned) == 0 {
+		return append(rows, Row{Label: "Provisioned", Value: "none"})
+	}
+	for _, provisioned := range concurrency.Provisioned {
+		value := fmt.Sprintf("%d/%d allocated, %d available, %s", provisioned.Allocated, provisioned.Requested, provisioned.Available, provisioned.Status)
+		if provisioned.StatusReason != "" {
+			value += ": " + provisioned.StatusReason
+		}
+		rows = append(rows, Row{Label: "Provisioned " + provisioned.Qualifier, Value: value})
+	}
+	return rows
+}
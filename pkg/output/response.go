@@ -0,0 +1,347 @@
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jmespath/go-jmespath"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	FormatTable = "table"
+	// FormatRaw prints the response as the function returned it, or a
+	// query's result as compact JSON with strings unquoted.
+	FormatRaw = "raw"
+)
+
+// ResponseFormats are the formats of invocation responses, in the order the
+// UIs cycle through them.
+var ResponseFormats = []string{FormatJSON, FormatYAML, FormatTable, FormatRaw}
+
+func CheckResponseFormat(format string) error {
+	for _, f := range ResponseFormats {
+		if format == f {
+			return nil
+		}
+	}
+	return fmt.Errorf("invalid response format %q, expected json, yaml, table or raw", format)
+}
+
+type ResponseOptions struct {
+	Format string
+	// Query is a JMESPath expression selecting part of a JSON response,
+	// such as items[?status=='failed'].id.
+	Query string
+}
+
+// FormatResponse renders an invocation's response payload. Payloads that
+// are not JSON are returned as they are, unless a query is given.
+func FormatResponse(payload []byte, opts ResponseOptions) (string, error) {
+	data := payload
+	query := strings.TrimSpace(opts.Query)
+	if query != "" {
+		decoder := json.NewDecoder(bytes.NewReader(payload))
+		decoder.UseNumber()
+		var doc interface{}
+		if err := decoder.Decode(&doc); err != nil {
+			return "", fmt.Errorf("cannot query a response that is not JSON: %v", err)
+		}
+		if !json.Valid(payload) {
+			return "", fmt.Errorf("cannot query a response that is not JSON: trailing data after the document")
+		}
+		result, err := jmespath.Search(query, numbers(doc))
+		if err != nil {
+			return "", fmt.Errorf("invalid query %q: %v", query, err)
+		}
+		if data, err = marshalCompact(result); err != nil {
+			return "", fmt.Errorf("failed to encode query result: %v", err)
+		}
+	}
+	if !json.Valid(data) {
+		return string(payload), nil
+	}
+
+	switch opts.Format {
+	case FormatJSON:
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", "  "); err != nil {
+			return "", err
+		}
+		return indented.String(), nil
+	case FormatYAML:
+		node, err := parseNode(data)
+		if err != nil {
+			return "", err
+		}
+		return encodeYAML(node)
+	case FormatTable:
+		node, err := parseNode(data)
+		if err != nil {
+			return "", err
+		}
+		return table(node)
+	case FormatRaw, "":
+		if query != "" {
+			var s string
+			if json.Unmarshal(data, &s) == nil {
+				return s, nil
+			}
+		}
+		return string(data), nil
+	}
+	return "", CheckResponseFormat(opts.Format)
+}
+
+// maxExactInt bounds the integers that a float64 holds exactly.
+const maxExactInt = 1 << 53
+
+// numbers turns the json.Numbers of a document decoded with UseNumber into
+// float64s, which JMESPath compares and computes with. Integers a float64
+// cannot hold exactly, such as large IDs, are left as they are so they come
+// out of the query unchanged.
+func numbers(v interface{}) interface{} {
+	switch v := v.(type) {
+	case json.Number:
+		if !strings.ContainsAny(v.String(), ".eE") {
+			if i, err := v.Int64(); err != nil || i > maxExactInt || i < -maxExactInt {
+				return v
+			}
+		}
+		if f, err := v.Float64(); err == nil {
+			return f
+		}
+		return v
+	case map[string]interface{}:
+		for key, value := range v {
+			v[key] = numbers(value)
+		}
+	case []interface{}:
+		for i, value := range v {
+			v[i] = numbers(value)
+		}
+	}
+	return v
+}
+
+func marshalCompact(v interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := json.NewEncoder(&b)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(b.Bytes(), "\n"), nil
+}
+
+// parseNode decodes JSON into a YAML node, which unlike a map keeps the
+// order of object keys.
+func parseNode(data []byte) (*yaml.Node, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to parse response: %v", err)
+	}
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) == 1 {
+		return doc.Content[0], nil
+	}
+	return &doc, nil
+}
+
+func encodeYAML(node *yaml.Node) (string, error) {
+	blockStyle(node)
+	var b strings.Builder
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(node); err != nil {
+		return "", fmt.Errorf("failed to encode response: %v", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+// blockStyle drops the flow style and quoting the nodes got from JSON, so
+// they are written as plain YAML.
+func blockStyle(node *yaml.Node) {
+	node.Style = 0
+	for _, child := range node.Content {
+		blockStyle(child)
+	}
+}
+
+// table lays out an array of objects with a column per key, an object as
+// key/value rows and an array of scalars as one value per line. Nested
+// values are shown as compact JSON.
+func table(node *yaml.Node) (string, error) {
+	var b strings.Builder
+	writer := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	switch {
+	case node.Kind == yaml.SequenceNode && allMappings(node.Content):
+		var columns []string
+		index := map[string]int{}
+		for _, row := range node.Content {
+			for i := 0; i+1 < len(row.Content); i += 2 {
+				key := row.Content[i].Value
+				if _, ok := index[key]; !ok {
+					index[key] = len(columns)
+					columns = append(columns, key)
+				}
+			}
+		}
+		fmt.Fprintln(writer, strings.Join(columns, "\t"))
+		for _, row := range node.Content {
+			cells := make([]string, len(columns))
+			for i := 0; i+1 < len(row.Content); i += 2 {
+				cells[index[row.Content[i].Value]] = cell(row.Content[i+1])
+			}
+			fmt.Fprintln(writer, strings.Join(cells, "\t"))
+		}
+	case node.Kind == yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			fmt.Fprintf(writer, "%s:\t%s\n", node.Content[i].Value, cell(node.Content[i+1]))
+		}
+	case node.Kind == yaml.SequenceNode:
+		for _, item := range node.Content {
+			fmt.Fprintln(writer, cell(item))
+		}
+	default:
+		fmt.Fprintln(writer, cell(node))
+	}
+	if err := writer.Flush(); err != nil {
+		return "", err
+	}
+	return strings.TrimRight(b.String(), "\n"), nil
+}
+
+func allMappings(nodes []*yaml.Node) bool {
+	for _, node := range nodes {
+		if node.Kind != yaml.MappingNode {
+			return false
+		}
+	}
+	return len(nodes) > 0
+}
+
+func cell(node *yaml.Node) string {
+	if node.Kind == yaml.ScalarNode {
+		if node.Tag == "!!null" {
+			return ""
+		}
+		return strings.ReplaceAll(node.Value, "\n", `\n`)
+	}
+	var v interface{}
+	if err := node.Decode(&v); err != nil {
+		return node.Value
+	}
+	data, err := marshalCompact(v)
+	if err != nil {
+		return node.Value
+	}
+	return string(data)
+}
+
+type TokenKind int
+
+const (
+	TokenPlain TokenKind = iota
+	TokenKey
+	TokenString
+	TokenNumber
+	// TokenLiteral is true, false or null.
+	TokenLiteral
+)
+
+type Token struct {
+	Kind TokenKind
+	Text string
+}
+
+// JSONTokens splits JSON text, such as a FormatResponse result, into
+// tokens for highlighting. Text that is not JSON comes back as plain
+// tokens and concatenating the tokens always gives the text back.
+func JSONTokens(text string) []Token {
+	var tokens []Token
+	add := func(kind TokenKind, s string) {
+		if s == "" {
+			return
+		}
+		if n := len(tokens); n > 0 && tokens[n-1].Kind == kind {
+			tokens[n-1].Text += s
+			return
+		}
+		tokens = append(tokens, Token{Kind: kind, Text: s})
+	}
+
+	for i := 0; i < len(text); {
+		c := text[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(text) && text[end] != '"' {
+				if text[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end = min(end+1, len(text))
+			kind := TokenString
+			if strings.HasPrefix(strings.TrimLeft(text[end:], " \t\r\n"), ":") {
+				kind = TokenKey
+			}
+			add(kind, text[i:end])
+			i = end
+		case c == '-' || (c >= '0' && c <= '9'):
+			end := i + 1
+			for end < len(text) && strings.IndexByte("0123456789.eE+-", text[end]) >= 0 {
+				end++
+			}
+			add(TokenNumber, text[i:end])
+			i = end
+		case c >= 'a' && c <= 'z':
+			end := i + 1
+			for end < len(text) && text[end] >= 'a' && text[end] <= 'z' {
+				end++
+			}
+			switch text[i:end] {
+			case "true", "false", "null":
+				add(TokenLiteral, text[i:end])
+			default:
+				add(TokenPlain, text[i:end])
+			}
+			i = end
+		default:
+			add(TokenPlain, text[i:i+1])
+			i++
+		}
+	}
+	return tokens
+}
+
+var tokenStyles = map[TokenKind]lipgloss.Style{
+	TokenKey:     lipgloss.NewStyle().Foreground(lipgloss.Color("12")),
+	TokenString:  lipgloss.NewStyle().Foreground(lipgloss.Color("10")),
+	TokenNumber:  lipgloss.NewStyle().Foreground(lipgloss.Color("11")),
+	TokenLiteral: lipgloss.NewStyle().Foreground(lipgloss.Color("13")),
+}
+
+// Colorize highlights JSON text for the terminal. Colors are left out when
+// stdout is not a terminal or NO_COLOR is set.
+func Colorize(text string) string {
+	if !json.Valid([]byte(text)) {
+		return text
+	}
+	var b strings.Builder
+	for _, token := range JSONTokens(text) {
+		if style, ok := tokenStyles[token.Kind]; ok {
+			b.WriteString(style.Render(token.Text))
+		} else {
+			b.WriteString(token.Text)
+		}
+	}
+	return b.String()
+}
@@ -0,0 +1,135 @@
+package output
+
+import (
+	"strings"
+	"testing"
+)
+
+// orders is what an order-processing function might return.
+const orders = `{"batch":"b-1","items":[{"id":"o-1","status":"shipped","total":12.5},{"id":"o-2","status":"failed","error":"out of stock"}],"retry":null}`
+
+func TestFormatResponseCyclesThroughFormats(t *testing.T) {
+	want := map[string]string{
+		FormatJSON: `{
+  "batch": "b-1",
+  "items": [
+    {
+      "id": "o-1",
+      "status": "shipped",
+      "total": 12.5
+    },
+    {
+      "id": "o-2",
+      "status": "failed",
+      "error": "out of stock"
+    }
+  ],
+  "retry": null
+}`,
+		FormatYAML: `batch: b-1
+items:
+  - id: o-1
+    status: shipped
+    total: 12.5
+  - id: o-2
+    status: failed
+    error: out of stock
+retry: null`,
+		// Cells are padded to the widest, so an empty last one leaves spaces.
+		FormatTable: "batch:  b-1\n" +
+			`items:  [{"id":"o-1","status":"shipped","total":12.5},{"error":"out of stock","id":"o-2","status":"failed"}]` + "\n" +
+			"retry:  ",
+		FormatRaw: orders,
+	}
+	for _, format := range ResponseFormats {
+		got, err := FormatResponse([]byte(orders), ResponseOptions{Format: format})
+		if err != nil {
+			t.Fatalf("FormatResponse(%s) error = %v", format, err)
+		}
+		if got != want[format] {
+			t.Errorf("FormatResponse(%s) =\n%s\nwant\n%s", format, got, want[format])
+		}
+	}
+}
+
+func TestFormatResponseQuery(t *testing.T) {
+	for query, want := range map[string]string{
+		"items[?status=='failed'].id": `["o-2"]`,
+		"items[0].status":             "shipped",
+		"items[0].total":              "12.5",
+	} {
+		got, err := FormatResponse([]byte(orders), ResponseOptions{Query: query})
+		if err != nil || got != want {
+			t.Errorf("FormatResponse(query %s) = %q, %v, want %q", query, got, err, want)
+		}
+	}
+
+	got, err := FormatResponse([]byte(orders), ResponseOptions{Format: FormatTable, Query: "items"})
+	wantTable := "id   status   total  error\no-1  shipped  12.5   \no-2  failed          out of stock"
+	if err != nil || got != wantTable {
+		t.Errorf("FormatResponse(table of items) =\n%s\nwant\n%s", got, wantTable)
+	}
+
+	if _, err := FormatResponse([]byte(orders), ResponseOptions{Query: "items[?"}); err == nil || !strings.Contains(err.Error(), "invalid query") {
+		t.Errorf("FormatResponse(bad query) error = %v", err)
+	}
+	if _, err := FormatResponse([]byte("plain text"), ResponseOptions{Query: "id"}); err == nil || !strings.Contains(err.Error(), "not JSON") {
+		t.Errorf("FormatResponse(query of text) error = %v", err)
+	}
+}
+
+func TestFormatResponseQueryKeepsLargeNumbers(t *testing.T) {
+	payload := []byte(`{"orders": [{"id": 12345678901234567890, "total": 12.50}, {"id": 9007199254740993, "total": 3}]}`)
+	for query, want := range map[string]string{
+		"orders[0].id":            "12345678901234567890",
+		"orders[?total > `5`].id": "[12345678901234567890]",
+		"orders[1]":               `{"id":9007199254740993,"total":3}`,
+		"sum(orders[].total)":     "15.5",
+	} {
+		got, err := FormatResponse(payload, ResponseOptions{Format: FormatRaw, Query: query})
+		if err != nil || got != want {
+			t.Errorf("FormatResponse(query %s) = %s, %v, want %s", query, got, err, want)
+		}
+	}
+
+	if _, err := FormatResponse([]byte(`{"id": 1} trailing`), ResponseOptions{Query: "id"}); err == nil || !strings.Contains(err.Error(), "not JSON") {
+		t.Errorf("FormatResponse(query of JSON with trailing data) error = %v", err)
+	}
+}
+
+func TestFormatResponseLeavesTextAlone(t *testing.T) {
+	for _, format := range ResponseFormats {
+		got, err := FormatResponse([]byte("OK\n"), ResponseOptions{Format: format})
+		if err != nil || got != "OK\n" {
+			t.Errorf("FormatResponse(%s of text) = %q, %v", format, got, err)
+		}
+	}
+	if err := CheckResponseFormat("xml"); err == nil {
+		t.Error("CheckResponseFormat(xml) error = nil, want an error")
+	}
+}
+
+func TestJSONTokens(t *testing.T) {
+	text, err := FormatResponse([]byte(`{"ok":true,"n":-1.5e3,"s":"a \"b\"","k":null}`), ResponseOptions{Format: FormatJSON})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var joined strings.Builder
+	kinds := map[string]TokenKind{}
+	for _, token := range JSONTokens(text) {
+		joined.WriteString(token.Text)
+		kinds[strings.TrimSpace(token.Text)] = token.Kind
+	}
+	if joined.String() != text {
+		t.Errorf("JSONTokens() joined = %q, want %q", joined.String(), text)
+	}
+	for text, want := range map[string]TokenKind{`"ok"`: TokenKey, "true": TokenLiteral, "-1.5e3": TokenNumber, `"a \"b\""`: TokenString, "null": TokenLiteral} {
+		if kinds[text] != want {
+			t.Errorf("JSONTokens() kind of %s = %d, want %d", text, kinds[text], want)
+		}
+	}
+
+	if got := Colorize("not json"); got != "not json" {
+		t.Errorf("Colorize(text) = %q", got)
+	}
+}
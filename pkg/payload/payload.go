@@ -0,0 +1,239 @@
+package payload
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Assignments collects repeated key=value flags such as --set. It implements
+// flag.Value so values containing commas are not split.
+type Assignments []string
+
+func (a *Assignments) Set(value string) error {
+	if !strings.Contains(value, "=") {
+		return fmt.Errorf("invalid assignment %q, expected key=value", value)
+	}
+	*a = append(*a, value)
+	return nil
+}
+
+func (a *Assignments) String() string {
+	if a == nil {
+		return ""
+	}
+	return strings.Join(*a, ", ")
+}
+
+type Options struct {
+	Inline string
+	File   string
+	Stdin  io.Reader
+	Sets   []string
+	Base   interface{}
+}
+
+// Build assembles a payload from, in order, the base document, the inline
+// JSON or payload file ("-" reads stdin for either), and --set assignments.
+// A payload given on its own goes out as written; once it is combined with a
+// base or assignments it is re-encoded, with object keys in sorted order.
+// The base is copied, never modified.
+func Build(opts Options) ([]byte, error) {
+	if opts.Inline != "" && opts.File != "" {
+		return nil, fmt.Errorf("--payload and --payload-file are mutually exclusive")
+	}
+
+	doc := clone(opts.Base)
+
+	raw, err := opts.read()
+	if err != nil {
+		return nil, err
+	}
+	if raw != nil && doc == nil && len(opts.Sets) == 0 {
+		return Validate(string(raw))
+	}
+	if raw != nil {
+		parsed, err := Parse(raw)
+		if err != nil {
+			return nil, err
+		}
+		doc = merge(doc, parsed)
+	}
+
+	for _, assignment := range opts.Sets {
+		key, value, _ := strings.Cut(assignment, "=")
+		doc, err = SetPath(doc, key, ParseValue(value))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if doc == nil {
+		doc = map[string]interface{}{}
+	}
+
+	payloadJson, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	return payloadJson, nil
+}
+
+func (opts Options) read() ([]byte, error) {
+	stdin := opts.Stdin
+	if stdin == nil {
+		stdin = os.Stdin
+	}
+
+	switch {
+	case opts.Inline == "-" || opts.File == "-":
+		raw, err := io.ReadAll(stdin)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payload from stdin: %v", err)
+		}
+		return raw, nil
+	case opts.Inline != "":
+		return []byte(opts.Inline), nil
+	case opts.File != "":
+		raw, err := os.ReadFile(opts.File)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read payload file: %v", err)
+		}
+		return raw, nil
+	}
+	return nil, nil
+}
+
+// Parse decodes a JSON payload. Numbers are kept as json.Number, so that
+// integers too large for a float64, such as IDs, are encoded back as given.
+func Parse(raw []byte) (interface{}, error) {
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %v", err)
+	}
+	if _, err := decoder.Token(); err != io.EOF {
+		return nil, fmt.Errorf("invalid JSON payload: unexpected data after the document")
+	}
+	return doc, nil
+}
+
+// Validate checks that raw is well-formed JSON and returns it compacted.
+func Validate(raw string) ([]byte, error) {
+	if _, err := Parse([]byte(raw)); err != nil {
+		return nil, err
+	}
+	var compacted bytes.Buffer
+	if err := json.Compact(&compacted, []byte(raw)); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %v", err)
+	}
+	return compacted.Bytes(), nil
+}
+
+func Indent(payloadJson []byte) string {
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, bytes.TrimSpace(payloadJson), "", "  "); err != nil {
+		return string(payloadJson)
+	}
+	return indented.String()
+}
+
+// ParseValue interprets the right-hand side of a --set assignment. Anything
+// that parses as JSON (numbers, booleans, null, objects, arrays, quoted
+// strings) keeps its type; everything else is taken as a plain string.
+func ParseValue(value string) interface{} {
+	if typed, err := Parse([]byte(value)); err == nil {
+		return typed
+	}
+	return value
+}
+
+// SetPath sets value at a dotted path such as "service.env.0.name",
+// creating intermediate objects as needed. Numeric segments index into
+// existing arrays.
+func SetPath(doc interface{}, path string, value interface{}) (interface{}, error) {
+	if path == "" {
+		return nil, fmt.Errorf("empty key in assignment")
+	}
+	return setPath(doc, strings.Split(path, "."), value, path)
+}
+
+func setPath(node interface{}, segments []string, value interface{}, path string) (interface{}, error) {
+	if len(segments) == 0 {
+		return value, nil
+	}
+	segment := segments[0]
+	if segment == "" {
+		return nil, fmt.Errorf("invalid key %q: empty path segment", path)
+	}
+
+	switch current := node.(type) {
+	case nil:
+		child, err := setPath(nil, segments[1:], value, path)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]interface{}{segment: child}, nil
+	case map[string]interface{}:
+		child, err := setPath(current[segment], segments[1:], value, path)
+		if err != nil {
+			return nil, err
+		}
+		current[segment] = child
+		return current, nil
+	case []interface{}:
+		index, err := strconv.Atoi(segment)
+		if err != nil || index < 0 || index > len(current) {
+			return nil, fmt.Errorf("invalid key %q: %q is not a valid index into an array of length %d", path, segment, len(current))
+		}
+		if index == len(current) {
+			current = append(current, nil)
+		}
+		child, err := setPath(current[index], segments[1:], value, path)
+		if err != nil {
+			return nil, err
+		}
+		current[index] = child
+		return current, nil
+	default:
+		return nil, fmt.Errorf("invalid key %q: cannot set %q on a %T value", path, segment, node)
+	}
+}
+
+func merge(base, overlay interface{}) interface{} {
+	baseMap, baseOk := base.(map[string]interface{})
+	overlayMap, overlayOk := overlay.(map[string]interface{})
+	if !baseOk || !overlayOk {
+		return overlay
+	}
+	for key, value := range overlayMap {
+		baseMap[key] = merge(baseMap[key], value)
+	}
+	return baseMap
+}
+
+// clone deep-copies a decoded JSON document so that SetPath and merge can
+// change it in place.
+func clone(doc interface{}) interface{} {
+	switch value := doc.(type) {
+	case map[string]interface{}:
+		copied := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			copied[key] = clone(child)
+		}
+		return copied
+	case []interface{}:
+		copied := make([]interface{}, len(value))
+		for i, child := range value {
+			copied[i] = clone(child)
+		}
+		return copied
+	default:
+		return doc
+	}
+}
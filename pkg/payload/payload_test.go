@@ -0,0 +1,136 @@
+package payload
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestBuildLayersBaseInlineAndSets(t *testing.T) {
+	base := map[string]interface{}{
+		"service": map[string]interface{}{"name": "api", "replicas": 1.0},
+		"dry":     false,
+	}
+	got, err := Build(Options{
+		Base:   base,
+		Inline: `{"service": {"replicas": 2}, "env": [{"name": "A"}]}`,
+		Sets:   []string{"dry=true", "env.0.name=B", "env.1.name=C", "service.tag=v1.2"},
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	want := `{"dry":true,"env":[{"name":"B"},{"name":"C"}],"service":{"name":"api","replicas":2,"tag":"v1.2"}}`
+	if string(got) != want {
+		t.Errorf("Build() = %s, want %s", got, want)
+	}
+}
+
+func TestBuildReadsFileAndStdin(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "payload.json")
+	if err := os.WriteFile(file, []byte("{\"from\": \"file\"}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := Build(Options{File: file})
+	if err != nil || string(got) != `{"from":"file"}` {
+		t.Errorf("Build(file) = %s, %v", got, err)
+	}
+	got, err = Build(Options{File: "-", Stdin: strings.NewReader(`{"from": "stdin"}`)})
+	if err != nil || string(got) != `{"from":"stdin"}` {
+		t.Errorf("Build(stdin) = %s, %v", got, err)
+	}
+	got, err = Build(Options{})
+	if err != nil || string(got) != `{}` {
+		t.Errorf("Build() without a payload = %s, %v, want {}", got, err)
+	}
+}
+
+func TestBuildKeepsLargeNumbers(t *testing.T) {
+	got, err := Build(Options{
+		Inline: `{"order_id": 9007199254740993, "amount": 1.10}`,
+		Sets:   []string{"customer_id=12345678901234567890"},
+	})
+	if err != nil {
+		t.Fatalf("Build() error = %v", err)
+	}
+	want := `{"amount":1.10,"customer_id":12345678901234567890,"order_id":9007199254740993}`
+	if string(got) != want {
+		t.Errorf("Build() = %s, want %s", got, want)
+	}
+	if indented := Indent(got); !strings.Contains(indented, `"order_id": 9007199254740993`) {
+		t.Errorf("Indent() = %s", indented)
+	}
+}
+
+func TestBuildSendsUneditedPayloadsAsWritten(t *testing.T) {
+	got, err := Build(Options{Inline: "{\"z\": 1,\n \"a\": {\"y\": 2, \"b\": 3}}"})
+	if err != nil || string(got) != `{"z":1,"a":{"y":2,"b":3}}` {
+		t.Errorf("Build() = %s, %v, want the payload compacted in its own key order", got, err)
+	}
+}
+
+func TestBuildLeavesTheBaseAlone(t *testing.T) {
+	base := map[string]interface{}{"order": map[string]interface{}{"id": "o-1", "lines": []interface{}{"a"}}}
+	opts := Options{Base: base, Inline: `{"order": {"id": "o-2"}}`, Sets: []string{"order.lines.1=b"}}
+	for i := 0; i < 2; i++ {
+		got, err := Build(opts)
+		if err != nil || string(got) != `{"order":{"id":"o-2","lines":["a","b"]}}` {
+			t.Errorf("Build() call %d = %s, %v", i+1, got, err)
+		}
+	}
+	if !reflect.DeepEqual(base, map[string]interface{}{"order": map[string]interface{}{"id": "o-1", "lines": []interface{}{"a"}}}) {
+		t.Errorf("Build() changed the base to %v", base)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.json")
+	for opts, want := range map[*Options]string{
+		{Inline: `{}`, File: missing}:                  "mutually exclusive",
+		{Inline: `{"a":`}:                              "invalid JSON payload",
+		{Inline: `{"a": 1} {"b": 2}`}:                  "unexpected data after the document",
+		{File: missing}:                                "failed to read payload file",
+		{Inline: `{"a": 1}`, Sets: []string{"a.b=2"}}:  "cannot set",
+		{Inline: `{"a": []}`, Sets: []string{"a.3=1"}}: "not a valid index",
+		{Sets: []string{"a..b=1"}}:                     "empty path segment",
+	} {
+		if _, err := Build(*opts); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Build(%+v) error = %v, want %q", *opts, err, want)
+		}
+	}
+}
+
+func TestParseValue(t *testing.T) {
+	tests := map[string]interface{}{
+		"3":          json.Number("3"),
+		"true":       true,
+		"null":       nil,
+		`"quoted"`:   "quoted",
+		`[1,"a"]`:    []interface{}{json.Number("1"), "a"},
+		"plain text": "plain text",
+		"":           "",
+	}
+	for value, want := range tests {
+		if got := ParseValue(value); !reflect.DeepEqual(got, want) {
+			t.Errorf("ParseValue(%q) = %#v, want %#v", value, got, want)
+		}
+	}
+}
+
+func TestAssignments(t *testing.T) {
+	var assignments Assignments
+	for _, value := range []string{"a=1", "b=x,y"} {
+		if err := assignments.Set(value); err != nil {
+			t.Fatalf("Set(%q) error = %v", value, err)
+		}
+	}
+	if err := assignments.Set("novalue"); err == nil {
+		t.Error("Set(\"novalue\") error = nil, want an error")
+	}
+	if got := assignments.String(); got != "a=1, b=x,y" {
+		t.Errorf("String() = %q", got)
+	}
+}
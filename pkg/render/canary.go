@@ -0,0 +1,222 @@
+package render
+
+import (
+	"aws_utility/pkg/canary"
+	"aws_utility/pkg/logger"
+	"aws_utility/pkg/payload"
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ShowCanaryDialog starts, or resumes, a weighted canary release of one of
+// the function's aliases and shows its progress live.
+func (r *FyneRenderer) ShowCanaryDialog(functionName string) {
+	aliases, err := r.awsInterface.ListAliases(functionName)
+	if err != nil {
+		dialog.ShowError(err, r.window)
+		return
+	}
+	versions, err := r.awsInterface.ListVersions(functionName)
+	if err != nil {
+		dialog.ShowError(err, r.window)
+		return
+	}
+
+	aliasVersions := map[string]string{}
+	aliasNames := make([]string, len(aliases))
+	for i, alias := range aliases {
+		aliasNames[i] = alias.Name
+		aliasVersions[alias.Name] = alias.FunctionVersion
+	}
+	var versionNames []string
+	for _, version := range versions {
+		if version.Version != "$LATEST" {
+			versionNames = append(versionNames, version.Version)
+		}
+	}
+
+	fromLabel := widget.NewLabel("")
+	aliasSelect := widget.NewSelect(aliasNames, func(name string) {
+		fromLabel.SetText(aliasVersions[name])
+	})
+	toSelect := widget.NewSelect(versionNames, nil)
+	stepsEntry := widget.NewEntry()
+	stepsEntry.SetText("10,25,50,100")
+	intervalEntry := widget.NewEntry()
+	intervalEntry.SetText("1m")
+	checksEntry := widget.NewEntry()
+	checksEntry.SetText("3")
+	errorRateEntry := widget.NewEntry()
+	errorRateEntry.SetText("0")
+	payloadEditor := widget.NewMultiLineEntry()
+	payloadEditor.SetPlaceHolder("{}")
+	payloadEditor.SetMinRowsVisible(4)
+
+	trafficBar := widget.NewProgressBar()
+	stepsLabel := widget.NewLabel("")
+	stepsLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	logLabel := widget.NewLabel("")
+	logLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+
+	var startButton, resumeButton *widget.Button
+	var lines []string
+	follow := func(state *canary.State, statePath string) {
+		startButton.Disable()
+		resumeButton.Disable()
+		statusLabel.Importance = widget.MediumImportance
+		statusLabel.SetText(fmt.Sprintf("Running, state saved to %s", statePath))
+		go func() {
+			err := canary.Run(context.Background(), r.awsInterface, state, statePath, func(event canary.Event) {
+				lines = append(lines, fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), event.Message))
+				if len(lines) > 12 {
+					lines = lines[len(lines)-12:]
+				}
+				logLabel.SetText(strings.Join(lines, "\n"))
+				showCanaryState(event.State, trafficBar, stepsLabel)
+			})
+			startButton.Enable()
+			if err != nil {
+				logger.Error("Canary release failed:", err)
+				statusLabel.Importance = widget.DangerImportance
+				statusLabel.SetText(err.Error())
+				resumeButton.Enable()
+				return
+			}
+			statusLabel.Importance = widget.SuccessImportance
+			statusLabel.SetText("Release complete")
+		}()
+	}
+
+	startButton = widget.NewButton("Start", func() {
+		state, statePath, err := newCanaryState(functionName, aliasSelect.Selected, fromLabel.Text, toSelect.Selected,
+			stepsEntry.Text, intervalEntry.Text, checksEntry.Text, errorRateEntry.Text, payloadEditor.Text)
+		if err != nil {
+			statusLabel.Importance = widget.DangerImportance
+			statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+			return
+		}
+		lines = nil
+		follow(state, statePath)
+	})
+
+	resumeButton = widget.NewButton("Resume", func() {
+		statePath, err := canary.DefaultStatePath(functionName, aliasSelect.Selected)
+		if err == nil {
+			var state *canary.State
+			state, err = canary.LoadState(statePath)
+			if err == nil {
+				showCanaryState(*state, trafficBar, stepsLabel)
+				follow(state, statePath)
+				return
+			}
+		}
+		statusLabel.Importance = widget.DangerImportance
+		statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+	})
+
+	content := container.NewVBox(
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Alias:"), aliasSelect,
+			widget.NewLabel("From version:"), fromLabel,
+			widget.NewLabel("To version:"), toSelect,
+			widget.NewLabel("Steps (%):"), stepsEntry,
+			widget.NewLabel("Interval:"), intervalEntry,
+			widget.NewLabel("Checks per step:"), checksEntry,
+			widget.NewLabel("Max error rate:"), errorRateEntry,
+		),
+		widget.NewLabel("Health-check payload:"),
+		payloadEditor,
+		container.NewHBox(startButton, resumeButton),
+		widget.NewLabel("Traffic to the new version:"),
+		trafficBar,
+		stepsLabel,
+		logLabel,
+		statusLabel,
+	)
+	canaryDialog := dialog.NewCustom(fmt.Sprintf("Canary release of %s", functionName), "Close", container.NewVScroll(content), r.window)
+	canaryDialog.Resize(fyne.NewSize(680, 720))
+	canaryDialog.Show()
+}
+
+func newCanaryState(functionName, alias, fromVersion, toVersion, stepsText, intervalText, checksText, errorRateText, payloadText string) (*canary.State, string, error) {
+	steps, err := canary.ParseSteps(stepsText)
+	if err != nil {
+		return nil, "", err
+	}
+	interval, err := time.ParseDuration(intervalText)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid interval: %v", err)
+	}
+	checks, err := strconv.Atoi(checksText)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid number of checks: %v", err)
+	}
+	maxErrorRate, err := strconv.ParseFloat(errorRateText, 64)
+	if err != nil {
+		return nil, "", fmt.Errorf("invalid error rate: %v", err)
+	}
+	if payloadText == "" {
+		payloadText = "{}"
+	}
+	payloadJson, err := payload.Validate(payloadText)
+	if err != nil {
+		return nil, "", err
+	}
+
+	state, err := canary.NewState(canary.Plan{
+		Function:      functionName,
+		Alias:         alias,
+		FromVersion:   fromVersion,
+		ToVersion:     toVersion,
+		Steps:         steps,
+		Interval:      canary.Duration(interval),
+		Payload:       payloadJson,
+		ChecksPerStep: checks,
+		MaxErrorRate:  maxErrorRate,
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	statePath, err := canary.DefaultStatePath(functionName, alias)
+	if err != nil {
+		return nil, "", err
+	}
+	if existing, err := canary.LoadState(statePath); err == nil && existing.Status == canary.StatusRunning {
+		return nil, "", fmt.Errorf("a release of %s:%s is already in progress, resume it instead", functionName, alias)
+	}
+	return state, statePath, state.Save(statePath)
+}
+
+func showCanaryState(state canary.State, trafficBar *widget.ProgressBar, stepsLabel *widget.Label) {
+	weight := 0.0
+	if state.CurrentStep > 0 && state.Status != canary.StatusRolledBack {
+		weight = state.Plan.Steps[state.CurrentStep-1]
+	}
+	trafficBar.SetValue(weight)
+
+	lines := make([]string, len(state.Plan.Steps))
+	for i, step := range state.Plan.Steps {
+		marker := " "
+		switch {
+		case i < state.CurrentStep:
+			marker = "✓"
+		case i == state.CurrentStep && state.Status == canary.StatusRunning:
+			marker = "▶"
+		}
+		lines[i] = fmt.Sprintf("%s %5g%%", marker, step*100)
+		if i < len(state.Results) {
+			lines[i] += fmt.Sprintf("  %d/%d failed", state.Results[i].Errors, state.Results[i].Invocations)
+		}
+	}
+	stepsLabel.SetText(strings.Join(lines, "\n"))
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"aws_utility/pkg/logger"
+	"aws_utility/pkg/output"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"strconv"
+)
+
+// ShowConcurrencyDialog shows the function's reserved and provisioned
+// concurrency next to the account's unreserved pool, and lets the user
+// throttle the function or pre-warm a version or alias.
+func (r *FyneRenderer) ShowConcurrencyDialog(functionName string) {
+	allocation := container.NewVBox()
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+
+	showError := func(err error) {
+		logger.Error("Concurrency update failed:", err)
+		statusLabel.Importance = widget.DangerImportance
+		statusLabel.SetText(err.Error())
+	}
+	refresh := func() {
+		concurrency, err := r.awsInterface.GetConcurrency(functionName)
+		if err != nil {
+			showError(err)
+			return
+		}
+		allocation.Objects = []fyne.CanvasObject{newRowsGrid(output.ConcurrencyRows(concurrency))}
+		allocation.Refresh()
+	}
+	done := func(message string) {
+		refresh()
+		statusLabel.Importance = widget.SuccessImportance
+		statusLabel.SetText(message)
+	}
+	parse := func(text string) (int32, bool) {
+		count, err := strconv.ParseInt(text, 10, 32)
+		if err != nil || count < 0 {
+			showError(fmt.Errorf("invalid number of executions %q", text))
+			return 0, false
+		}
+		return int32(count), true
+	}
+
+	reservedEntry := widget.NewEntry()
+	reservedEntry.SetPlaceHolder("executions, 0 throttles the function")
+	reserveButton := widget.NewButton("Reserve", func() {
+		count, ok := parse(reservedEntry.Text)
+		if !ok {
+			return
+		}
+		if err := r.awsInterface.SetReservedConcurrency(functionName, count); err != nil {
+			showError(err)
+			return
+		}
+		done(fmt.Sprintf("Reserved %d concurrent executions", count))
+	})
+	unreserveButton := widget.NewButton("Remove", func() {
+		if err := r.awsInterface.RemoveReservedConcurrency(functionName); err != nil {
+			showError(err)
+			return
+		}
+		done("The function now uses the unreserved pool")
+	})
+
+	var qualifierNames []string
+	if qualifiers, err := r.awsInterface.ListQualifiers(functionName); err != nil {
+		logger.Warn("Failed to list versions and aliases:", err)
+	} else {
+		for _, qualifier := range qualifiers {
+			if qualifier.Name != "$LATEST" {
+				qualifierNames = append(qualifierNames, qualifier.Name)
+			}
+		}
+	}
+	qualifierSelect := widget.NewSelect(qualifierNames, nil)
+	qualifierSelect.PlaceHolder = "Version or alias"
+	provisionedEntry := widget.NewEntry()
+	provisionedEntry.SetPlaceHolder("executions")
+	provisionButton := widget.NewButton("Provision", func() {
+		count, ok := parse(provisionedEntry.Text)
+		if !ok {
+			return
+		}
+		provisioned, err := r.awsInterface.SetProvisionedConcurrency(functionName, qualifierSelect.Selected, count)
+		if err != nil {
+			showError(err)
+			return
+		}
+		done(fmt.Sprintf("Requested %d executions for %s, status %s", provisioned.Requested, provisioned.Qualifier, provisioned.Status))
+	})
+	unprovisionButton := widget.NewButton("Remove", func() {
+		if qualifierSelect.Selected == "" {
+			return
+		}
+		if err := r.awsInterface.RemoveProvisionedConcurrency(functionName, qualifierSelect.Selected); err != nil {
+			showError(err)
+			return
+		}
+		done(fmt.Sprintf("Removed provisioned concurrency from %s", qualifierSelect.Selected))
+	})
+
+	refresh()
+	content := container.NewVBox(
+		allocation,
+		widget.NewButton("Refresh", refresh),
+		widget.NewSeparator(),
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Reserved:"), container.NewBorder(nil, nil, nil, container.NewHBox(reserveButton, unreserveButton), reservedEntry),
+			widget.NewLabel("Provisioned:"), container.NewBorder(nil, nil, qualifierSelect, container.NewHBox(provisionButton, unprovisionButton), provisionedEntry),
+		),
+		statusLabel,
+	)
+	concurrencyDialog := dialog.NewCustom(fmt.Sprintf("Concurrency of %s", functionName), "Close", content, r.window)
+	concurrencyDialog.Resize(fyne.NewSize(640, 460))
+	concurrencyDialog.Show()
+}
@@ -0,0 +1,110 @@
+package render
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/logger"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+	"time"
+)
+
+// ShowDeployDialog lets the user upload a zip or point the function at a new
+// image, then follows the deployment step by step.
+func (r *FyneRenderer) ShowDeployDialog(functionName string) {
+	zipEntry := widget.NewEntry()
+	zipEntry.SetPlaceHolder("path/to/function.zip")
+	browseButton := widget.NewButton("Browse...", func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			zipEntry.SetText(reader.URI().Path())
+		}, r.window)
+	})
+	imageEntry := widget.NewEntry()
+	imageEntry.SetPlaceHolder("123456789012.dkr.ecr.us-east-1.amazonaws.com/app:tag")
+	publishCheck := widget.NewCheck("Publish a version", nil)
+	aliasEntry := widget.NewEntry()
+	aliasEntry.SetPlaceHolder("optional, implies publishing")
+	descriptionEntry := widget.NewEntry()
+
+	progressLabel := widget.NewLabel("")
+	progressLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	progressBar := widget.NewProgressBarInfinite()
+	progressBar.Stop()
+	progressBar.Hide()
+	resultLabel := widget.NewLabel("")
+	resultLabel.Wrapping = fyne.TextWrapWord
+
+	var deployButton *widget.Button
+	deployButton = widget.NewButton("Deploy", func() {
+		opts := awsinterface.DeployOptions{
+			ZipFile:     strings.TrimSpace(zipEntry.Text),
+			ImageURI:    strings.TrimSpace(imageEntry.Text),
+			Publish:     publishCheck.Checked,
+			Alias:       strings.TrimSpace(aliasEntry.Text),
+			Description: descriptionEntry.Text,
+		}
+		var lines []string
+		opts.Progress = func(message string) {
+			lines = append(lines, fmt.Sprintf("%s %s", time.Now().Format("15:04:05"), message))
+			progressLabel.SetText(strings.Join(lines, "\n"))
+		}
+
+		deployButton.Disable()
+		resultLabel.Importance = widget.MediumImportance
+		resultLabel.SetText("")
+		progressBar.Show()
+		progressBar.Start()
+		go func() {
+			defer func() {
+				progressBar.Stop()
+				progressBar.Hide()
+				deployButton.Enable()
+			}()
+
+			result, err := r.awsInterface.Deploy(functionName, opts)
+			if err != nil {
+				logger.Error("Failed to deploy Lambda function:", err)
+				resultLabel.Importance = widget.DangerImportance
+				resultLabel.SetText(fmt.Sprintf("Error: %v", err))
+				return
+			}
+			summary := fmt.Sprintf("Deployed %s", result.CodeSha256)
+			if result.Version != "" {
+				summary += ", version " + result.Version
+			}
+			if result.Alias != "" {
+				summary += ", alias " + result.Alias
+			}
+			resultLabel.Importance = widget.SuccessImportance
+			resultLabel.SetText(summary)
+		}()
+	})
+
+	progressScroll := container.NewVScroll(progressLabel)
+	progressScroll.SetMinSize(fyne.NewSize(500, 150))
+
+	content := container.NewVBox(
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Zip file:"), container.NewBorder(nil, nil, nil, browseButton, zipEntry),
+			widget.NewLabel("or image URI:"), imageEntry,
+			widget.NewLabel(""), publishCheck,
+			widget.NewLabel("Alias:"), aliasEntry,
+			widget.NewLabel("Description:"), descriptionEntry,
+		),
+		deployButton,
+		progressBar,
+		progressScroll,
+		resultLabel,
+	)
+	deployDialog := dialog.NewCustom(fmt.Sprintf("Deploy %s", functionName), "Close", content, r.window)
+	deployDialog.Resize(fyne.NewSize(640, 520))
+	deployDialog.Show()
+}
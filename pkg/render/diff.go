@@ -0,0 +1,134 @@
+package render
+
+import (
+	"aws_utility/pkg/configdiff"
+	"aws_utility/pkg/logger"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+)
+
+// ShowDiffDialog compares the function's configuration with another
+// function, in this account or, through the SSO session, in another one.
+func (r *FyneRenderer) ShowDiffDialog(functionName, qualifier string) {
+	left := configdiff.Target{Function: functionName, Qualifier: qualifier}
+
+	accountEntry := widget.NewEntry()
+	accountEntry.SetPlaceHolder("this account")
+	roleEntry := widget.NewEntry()
+	roleEntry.SetPlaceHolder("role to assume in that account")
+	functionEntry := widget.NewEntry()
+	functionEntry.SetText(functionName)
+	qualifierEntry := widget.NewEntry()
+	qualifierEntry.SetText(qualifier)
+	valuesCheck := widget.NewCheck("Compare environment variable values", nil)
+	secretsCheck := widget.NewCheck("Show secret values", nil)
+	ignoreEntry := widget.NewEntry()
+	ignoreEntry.SetPlaceHolder("key prefixes to skip, e.g. tag.,code_sha256")
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	results := container.NewVBox()
+
+	var compareButton *widget.Button
+	compareButton = widget.NewButton("Compare", func() {
+		right := configdiff.Target{
+			AccountID: strings.TrimSpace(accountEntry.Text),
+			RoleName:  strings.TrimSpace(roleEntry.Text),
+			Function:  strings.TrimSpace(functionEntry.Text),
+			Qualifier: strings.TrimSpace(qualifierEntry.Text),
+		}
+		var ignore []string
+		for _, prefix := range strings.Split(ignoreEntry.Text, ",") {
+			if prefix = strings.TrimSpace(prefix); prefix != "" {
+				ignore = append(ignore, prefix)
+			}
+		}
+
+		compareButton.Disable()
+		statusLabel.Importance = widget.MediumImportance
+		statusLabel.SetText("Fetching both configurations...")
+		results.RemoveAll()
+		go func() {
+			defer compareButton.Enable()
+			rightInterface := r.awsInterface
+			if right.AccountID != "" {
+				var err error
+				rightInterface, err = r.awsInterface.ForRole(right.AccountID, right.RoleName)
+				if err != nil {
+					showDiffError(statusLabel, err)
+					return
+				}
+			}
+			report, err := configdiff.Diff(r.awsInterface, left, rightInterface, right, configdiff.Options{Values: valuesCheck.Checked, Ignore: ignore})
+			if err != nil {
+				showDiffError(statusLabel, err)
+				return
+			}
+			differences := report.Differences
+			if !secretsCheck.Checked {
+				differences = configdiff.Masked(differences)
+			}
+
+			if len(differences) == 0 {
+				statusLabel.Importance = widget.SuccessImportance
+				statusLabel.SetText("The configurations are the same")
+				return
+			}
+			statusLabel.Importance = widget.WarningImportance
+			statusLabel.SetText(fmt.Sprintf("%d difference(s)", len(differences)))
+
+			grid := container.NewGridWithColumns(3,
+				widget.NewLabelWithStyle("Key", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle(left.String(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle(right.String(), fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+			)
+			for _, difference := range differences {
+				leftValue, rightValue := difference.Left, difference.Right
+				switch difference.Kind {
+				case configdiff.KindOnlyLeft:
+					rightValue = "(missing)"
+				case configdiff.KindOnlyRight:
+					leftValue = "(missing)"
+				}
+				for _, text := range []string{difference.Key, leftValue, rightValue} {
+					label := widget.NewLabel(text)
+					label.Wrapping = fyne.TextWrapBreak
+					grid.Add(label)
+				}
+			}
+			results.Add(grid)
+		}()
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(
+			widget.NewLabel(fmt.Sprintf("Compare %s with:", left)),
+			container.New(layout.NewFormLayout(),
+				widget.NewLabel("Account ID:"), accountEntry,
+				widget.NewLabel("Role:"), roleEntry,
+				widget.NewLabel("Function:"), functionEntry,
+				widget.NewLabel("Qualifier:"), qualifierEntry,
+				widget.NewLabel("Ignore:"), ignoreEntry,
+			),
+			container.NewHBox(valuesCheck, secretsCheck),
+			compareButton,
+			statusLabel,
+		),
+		nil, nil, nil,
+		container.NewVScroll(results),
+	)
+	diffDialog := dialog.NewCustom(fmt.Sprintf("Compare %s", functionName), "Close", content, r.window)
+	diffDialog.Resize(fyne.NewSize(820, 680))
+	diffDialog.Show()
+}
+
+func showDiffError(statusLabel *widget.Label, err error) {
+	logger.Error("Configuration diff failed:", err)
+	statusLabel.Importance = widget.DangerImportance
+	statusLabel.SetText(err.Error())
+}
@@ -0,0 +1,181 @@
+package render
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/envvars"
+	"aws_utility/pkg/logger"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"sort"
+	"strings"
+)
+
+type environmentRow struct {
+	key   *widget.Entry
+	value *widget.Entry
+}
+
+// ShowEnvironmentDialog shows the function's environment variables as an
+// editable table. Saving previews the changes and only applies them if the
+// function has not been modified since the variables were loaded.
+func (r *FyneRenderer) ShowEnvironmentDialog(functionName string) {
+	var environment *awsinterface.Environment
+	var rows []*environmentRow
+	table := container.NewVBox()
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+
+	var render func()
+	addRow := func(key, value string) {
+		row := &environmentRow{key: widget.NewEntry(), value: widget.NewEntry()}
+		if envvars.IsSecret(key) {
+			row.value = widget.NewPasswordEntry()
+		}
+		row.key.SetPlaceHolder("KEY")
+		row.key.SetText(key)
+		row.value.SetText(value)
+		rows = append(rows, row)
+	}
+	render = func() {
+		table.Objects = []fyne.CanvasObject{
+			container.NewGridWithColumns(2, widget.NewLabelWithStyle("Key", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+				widget.NewLabelWithStyle("Value", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})),
+		}
+		for _, row := range rows {
+			row := row
+			removeButton := widget.NewButtonWithIcon("", theme.DeleteIcon(), func() {
+				for i := range rows {
+					if rows[i] == row {
+						rows = append(rows[:i], rows[i+1:]...)
+						break
+					}
+				}
+				render()
+			})
+			table.Add(container.NewBorder(nil, nil, nil, removeButton, container.NewGridWithColumns(2, row.key, row.value)))
+		}
+		table.Refresh()
+	}
+
+	load := func() {
+		var err error
+		environment, err = r.awsInterface.GetEnvironment(functionName)
+		if err != nil {
+			logger.Error("Failed to load environment variables:", err)
+			statusLabel.Importance = widget.DangerImportance
+			statusLabel.SetText(err.Error())
+			return
+		}
+		keys := make([]string, 0, len(environment.Variables))
+		for key := range environment.Variables {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		rows = nil
+		for _, key := range keys {
+			addRow(key, environment.Variables[key])
+		}
+		render()
+		statusLabel.Importance = widget.MediumImportance
+		statusLabel.SetText(fmt.Sprintf("%d variable(s), revision %s", len(keys), environment.RevisionID))
+	}
+
+	addButton := widget.NewButtonWithIcon("Add variable", theme.ContentAddIcon(), func() {
+		addRow("", "")
+		render()
+	})
+	importButton := widget.NewButton("Import .env...", func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			imported, err := envvars.ParseDotenv(reader)
+			if err != nil {
+				dialog.ShowError(fmt.Errorf("failed to parse %s: %v", reader.URI().Name(), err), r.window)
+				return
+			}
+			for _, row := range rows {
+				if value, ok := imported[strings.TrimSpace(row.key.Text)]; ok {
+					row.value.SetText(value)
+					delete(imported, strings.TrimSpace(row.key.Text))
+				}
+			}
+			keys := make([]string, 0, len(imported))
+			for key := range imported {
+				keys = append(keys, key)
+			}
+			sort.Strings(keys)
+			for _, key := range keys {
+				addRow(key, imported[key])
+			}
+			render()
+		}, r.window)
+	})
+	reloadButton := widget.NewButtonWithIcon("Reload", theme.ViewRefreshIcon(), load)
+
+	saveButton := widget.NewButton("Review and save...", func() {
+		if environment == nil {
+			return
+		}
+		updated := map[string]string{}
+		for _, row := range rows {
+			key := strings.TrimSpace(row.key.Text)
+			if key == "" {
+				continue
+			}
+			if err := envvars.CheckKey(key); err != nil {
+				dialog.ShowError(err, r.window)
+				return
+			}
+			if _, ok := updated[key]; ok {
+				dialog.ShowError(fmt.Errorf("%s is defined twice", key), r.window)
+				return
+			}
+			updated[key] = row.value.Text
+		}
+
+		changes := envvars.Diff(environment.Variables, updated)
+		if len(changes) == 0 {
+			statusLabel.Importance = widget.MediumImportance
+			statusLabel.SetText("No changes")
+			return
+		}
+		lines := make([]string, len(changes))
+		for i, change := range changes {
+			lines[i] = change.Format(false)
+		}
+		preview := widget.NewLabel(strings.Join(lines, "\n"))
+		preview.TextStyle = fyne.TextStyle{Monospace: true}
+		dialog.ShowCustomConfirm("Apply these changes?", "Apply", "Cancel", container.NewVScroll(preview), func(apply bool) {
+			if !apply {
+				return
+			}
+			if err := r.awsInterface.UpdateEnvironment(functionName, updated, environment.RevisionID); err != nil {
+				logger.Error("Failed to update environment variables:", err)
+				statusLabel.Importance = widget.DangerImportance
+				statusLabel.SetText(err.Error())
+				return
+			}
+			load()
+			statusLabel.Importance = widget.SuccessImportance
+			statusLabel.SetText(fmt.Sprintf("Updated %d variable(s)", len(changes)))
+		}, r.window)
+	})
+
+	load()
+	content := container.NewBorder(
+		nil,
+		container.NewVBox(container.New(layout.NewHBoxLayout(), addButton, importButton, reloadButton, layout.NewSpacer(), saveButton), statusLabel),
+		nil, nil,
+		container.NewVScroll(table),
+	)
+	environmentDialog := dialog.NewCustom(fmt.Sprintf("Environment of %s", functionName), "Close", content, r.window)
+	environmentDialog.Resize(fyne.NewSize(760, 560))
+	environmentDialog.Show()
+}
@@ -0,0 +1,169 @@
+package render
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/fleet"
+	"aws_utility/pkg/history"
+	"aws_utility/pkg/logger"
+	"aws_utility/pkg/payload"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"strconv"
+)
+
+// GenerateFleetContent shows the accessible accounts as a multi-select and
+// invokes one function in every checked account under a common role. The
+// account menu stays intact underneath so "Back" returns to it.
+func (r *FyneRenderer) GenerateFleetContent(accounts []awsinterface.Account) {
+	r.clearContent()
+
+	accountIDs := make(map[string]string, len(accounts))
+	options := make([]string, len(accounts))
+	for i, account := range accounts {
+		options[i] = fmt.Sprintf("%s (%s)", account.AccountName, account.AccountID)
+		accountIDs[options[i]] = account.AccountID
+	}
+	accountChecks := widget.NewCheckGroup(options, nil)
+	selectAll := widget.NewCheck("Select all", func(checked bool) {
+		if checked {
+			accountChecks.SetSelected(options)
+		} else {
+			accountChecks.SetSelected(nil)
+		}
+	})
+	accountScroll := container.NewVScroll(accountChecks)
+	accountScroll.SetMinSize(fyne.NewSize(500, 200))
+
+	roleEntry := widget.NewEntry()
+	roleEntry.SetPlaceHolder("Role name, e.g. AdministratorAccess")
+	functionEntry := widget.NewEntry()
+	functionEntry.SetPlaceHolder("function or function:alias")
+	concurrencyEntry := widget.NewEntry()
+	concurrencyEntry.SetText("4")
+
+	payloadEditor := widget.NewMultiLineEntry()
+	payloadEditor.SetPlaceHolder("{\n  \"key\": \"value\"\n}")
+	payloadEditor.SetMinRowsVisible(6)
+	payloadEditor.Validator = func(text string) error {
+		_, err := payload.Validate(text)
+		return err
+	}
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	results := container.New(layout.NewGridLayout(4))
+
+	var invokeButton *widget.Button
+	invokeButton = widget.NewButton("Invoke in selected accounts", func() {
+		var selected []string
+		for _, option := range accountChecks.Selected {
+			selected = append(selected, accountIDs[option])
+		}
+		targets, err := fleet.Selector{AccountIDs: selected}.Select(accounts)
+		if err != nil {
+			statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+			return
+		}
+		if roleEntry.Text == "" || functionEntry.Text == "" {
+			statusLabel.SetText("Error: a role name and a function are required")
+			return
+		}
+		concurrency, err := strconv.Atoi(concurrencyEntry.Text)
+		if err != nil || concurrency < 1 {
+			statusLabel.SetText("Error: concurrency must be a positive number")
+			return
+		}
+		payloadText := payloadEditor.Text
+		if payloadText == "" {
+			payloadText = "{}"
+		}
+		payloadJson, err := payload.Validate(payloadText)
+		if err != nil {
+			statusLabel.SetText(fmt.Sprintf("Error: %v", err))
+			return
+		}
+
+		functionName, qualifier := awsinterface.SplitQualifier(functionEntry.Text)
+
+		results.RemoveAll()
+		statusLabels := make(map[string]*widget.Label, len(targets))
+		detailLabels := make(map[string]*widget.Label, len(targets))
+		durationLabels := make(map[string]*widget.Label, len(targets))
+		for _, header := range []string{"Account", "Status", "Duration", "Response or error"} {
+			results.Add(widget.NewLabelWithStyle(header, fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		}
+		for _, account := range targets {
+			statusLabels[account.AccountID] = widget.NewLabel("pending")
+			durationLabels[account.AccountID] = widget.NewLabel("")
+			detailLabels[account.AccountID] = widget.NewLabel("")
+			detailLabels[account.AccountID].Wrapping = fyne.TextWrapWord
+			results.Add(widget.NewLabel(fmt.Sprintf("%s (%s)", account.AccountName, account.AccountID)))
+			results.Add(statusLabels[account.AccountID])
+			results.Add(durationLabels[account.AccountID])
+			results.Add(detailLabels[account.AccountID])
+		}
+		results.Refresh()
+
+		invokeButton.Disable()
+		statusLabel.SetText(fmt.Sprintf("Invoking %s in %d accounts...", functionEntry.Text, len(targets)))
+		go func() {
+			finished := 0
+			fleetResults := fleet.Run(r.awsInterface, targets, functionName, payloadJson, fleet.Options{
+				RoleName:    roleEntry.Text,
+				Concurrency: concurrency,
+				Invoke:      awsinterface.InvokeOptions{Qualifier: qualifier},
+				OnResult: func(result fleet.Result) {
+					finished++
+					statusLabels[result.AccountID].SetText(result.Status)
+					if !result.Succeeded() {
+						statusLabels[result.AccountID].Importance = widget.DangerImportance
+						statusLabels[result.AccountID].Refresh()
+					}
+					durationLabels[result.AccountID].SetText(result.Duration().String())
+					detail := string(result.Response)
+					if result.FunctionError != "" {
+						detail = result.FunctionError + ": " + detail
+					}
+					if result.Error != "" {
+						detail = result.Error
+					}
+					detailLabels[result.AccountID].SetText(detail)
+					statusLabel.SetText(fmt.Sprintf("Invoking %s: %d of %d accounts finished", functionEntry.Text, finished, len(targets)))
+				},
+			})
+
+			counts := fleet.Summarize(fleetResults)
+			logger.Info("Fleet invocation finished:", counts)
+			statusLabel.SetText(fmt.Sprintf("Finished %s in %d accounts: %d succeeded, %d function errors, %d failed",
+				functionEntry.Text, len(fleetResults), counts[history.StatusSuccess], counts[history.StatusFunctionError], counts[history.StatusFailed]))
+			invokeButton.Enable()
+		}()
+	})
+
+	backButton := widget.NewButton("Back", func() {
+		r.clearContent()
+		r.contentContainer.Hide()
+		r.menuContainer.Show()
+	})
+
+	r.contentContainer.Add(container.NewVBox(
+		widget.NewLabel("Accounts:"),
+		selectAll,
+		accountScroll,
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Role:"), roleEntry,
+			widget.NewLabel("Function:"), functionEntry,
+			widget.NewLabel("Concurrency:"), concurrencyEntry,
+		),
+		widget.NewLabel("Payload:"),
+		payloadEditor,
+		container.NewHBox(backButton, invokeButton),
+		statusLabel,
+		results,
+	))
+	r.menuContainer.Hide()
+	r.contentContainer.Show()
+}
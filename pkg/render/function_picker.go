@@ -0,0 +1,182 @@
+package render
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/canvas"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"sort"
+	"strings"
+)
+
+var functionSortOrders = []string{"Name", "Runtime", "Memory", "Last modified"}
+
+// functionPicker is a searchable, sortable list of Lambda functions. The
+// search box matches name, runtime and description case-insensitively.
+type functionPicker struct {
+	functions  []awsinterface.LambdaFunction
+	filtered   []awsinterface.LambdaFunction
+	search     *widget.Entry
+	sortSelect *widget.Select
+	list       *widget.List
+	countLabel *widget.Label
+	content    *fyne.Container
+	Selected   string
+	// selectedARN tells apart functions of the same name in other regions.
+	selectedARN string
+	OnSelected  func(awsinterface.LambdaFunction)
+}
+
+func newFunctionPicker(functions []awsinterface.LambdaFunction, onSelected func(awsinterface.LambdaFunction)) *functionPicker {
+	p := &functionPicker{
+		functions:  functions,
+		OnSelected: onSelected,
+		countLabel: widget.NewLabel(""),
+	}
+
+	p.search = widget.NewEntry()
+	p.search.SetPlaceHolder("Search functions")
+	p.search.OnChanged = func(string) { p.apply() }
+
+	p.sortSelect = widget.NewSelect(functionSortOrders, func(string) { p.apply() })
+	p.sortSelect.PlaceHolder = "Sort by"
+
+	p.list = widget.NewList(
+		func() int { return len(p.filtered) },
+		func() fyne.CanvasObject {
+			name := widget.NewLabelWithStyle("", fyne.TextAlignLeading, fyne.TextStyle{Bold: true})
+			summary := widget.NewLabel("")
+			summary.Importance = widget.LowImportance
+			summary.Truncation = fyne.TextTruncateEllipsis
+			return container.NewVBox(name, summary)
+		},
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			function := p.filtered[id]
+			labels := object.(*fyne.Container).Objects
+			labels[0].(*widget.Label).SetText(function.Name)
+			labels[1].(*widget.Label).SetText(function.Summary())
+		},
+	)
+	p.list.OnSelected = func(id widget.ListItemID) {
+		function := p.filtered[id]
+		if function.ARN == p.selectedARN {
+			return
+		}
+		p.Selected = function.Name
+		p.selectedARN = function.ARN
+		if p.OnSelected != nil {
+			p.OnSelected(function)
+		}
+	}
+
+	listArea := canvas.NewRectangle(nil)
+	listArea.SetMinSize(fyne.NewSize(500, 240))
+
+	p.content = container.NewVBox(
+		container.NewBorder(nil, nil, nil, container.NewHBox(p.sortSelect, p.countLabel), p.search),
+		container.NewStack(listArea, p.list),
+	)
+	p.sortSelect.SetSelected(functionSortOrders[0])
+	return p
+}
+
+func (p *functionPicker) apply() {
+	query := strings.ToLower(strings.TrimSpace(p.search.Text))
+
+	p.filtered = p.filtered[:0]
+	for _, function := range p.functions {
+		if query == "" ||
+			strings.Contains(strings.ToLower(function.Name), query) ||
+			strings.Contains(strings.ToLower(function.Runtime), query) ||
+			strings.Contains(function.Region, query) ||
+			strings.Contains(strings.ToLower(function.Description), query) {
+			p.filtered = append(p.filtered, function)
+		}
+	}
+
+	sort.SliceStable(p.filtered, func(i, j int) bool {
+		a, b := p.filtered[i], p.filtered[j]
+		switch p.sortSelect.Selected {
+		case "Runtime":
+			if a.Runtime != b.Runtime {
+				return a.Runtime < b.Runtime
+			}
+		case "Memory":
+			if a.MemorySize != b.MemorySize {
+				return a.MemorySize > b.MemorySize
+			}
+		case "Last modified":
+			if a.LastModified != b.LastModified {
+				return a.LastModified > b.LastModified
+			}
+		}
+		return a.Name < b.Name
+	})
+
+	p.countLabel.SetText(fmt.Sprintf("%d of %d", len(p.filtered), len(p.functions)))
+	p.list.UnselectAll()
+	for i, function := range p.filtered {
+		if function.ARN == p.selectedARN {
+			p.list.Select(i)
+			break
+		}
+	}
+	p.list.Refresh()
+}
+
+// SetSelected selects the function with the given ARN, clearing the search
+// if it is currently filtered out. Names are not unique once several
+// regions are listed, so functions are picked by ARN.
+func (p *functionPicker) SetSelected(arn string) {
+	for _, function := range p.functions {
+		if function.ARN != arn {
+			continue
+		}
+		changed := p.selectedARN != function.ARN
+		p.Selected = function.Name
+		p.selectedARN = function.ARN
+		if !p.visible(arn) {
+			p.search.SetText("")
+		}
+		for i, candidate := range p.filtered {
+			if candidate.ARN == function.ARN {
+				p.list.Select(i)
+				p.list.ScrollTo(i)
+			}
+		}
+		if changed && p.OnSelected != nil {
+			p.OnSelected(function)
+		}
+		return
+	}
+}
+
+// Lookup returns the ARN of the function given by ARN or by name, in which
+// case the one in region is preferred, or any one of that name when region
+// is empty.
+func (p *functionPicker) Lookup(function, region string) (string, bool) {
+	var match string
+	for _, candidate := range p.functions {
+		switch {
+		case candidate.ARN == function:
+			return candidate.ARN, true
+		case candidate.Name != function:
+		case candidate.Region == region:
+			return candidate.ARN, true
+		case region == "" && match == "":
+			match = candidate.ARN
+		}
+	}
+	return match, match != ""
+}
+
+func (p *functionPicker) visible(arn string) bool {
+	for _, function := range p.filtered {
+		if function.ARN == arn {
+			return true
+		}
+	}
+	return false
+}
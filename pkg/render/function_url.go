@@ -0,0 +1,119 @@
+package render
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/logger"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+var urlMethods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodHead, http.MethodOptions}
+
+// ShowFunctionURLDialog sends HTTP requests to the function's URL, signed
+// with the role's credentials when the URL uses AWS_IAM auth.
+func (r *FyneRenderer) ShowFunctionURLDialog(functionName, qualifier string) {
+	configLabel := widget.NewLabel("")
+	configLabel.Wrapping = fyne.TextWrapBreak
+	config, err := r.awsInterface.GetFunctionURLConfig(functionName, qualifier)
+	if err != nil {
+		logger.Warn("Failed to get function URL:", err)
+		configLabel.SetText(fmt.Sprintf("No function URL found (%v); set an endpoint to call one directly.", err))
+	} else {
+		configLabel.SetText(fmt.Sprintf("%s\nAuth: %s, invoke mode: %s", config.URL, config.AuthType, config.InvokeMode))
+	}
+
+	methodSelect := widget.NewSelect(urlMethods, nil)
+	methodSelect.SetSelected(http.MethodGet)
+	pathEntry := widget.NewEntry()
+	pathEntry.SetText("/")
+	endpointEntry := widget.NewEntry()
+	endpointEntry.SetPlaceHolder("optional, e.g. http://localhost:9000")
+	unsignedCheck := widget.NewCheck("Do not sign", nil)
+	headersEditor := widget.NewMultiLineEntry()
+	headersEditor.SetPlaceHolder("Content-Type: application/json")
+	headersEditor.SetMinRowsVisible(3)
+	bodyEditor := widget.NewMultiLineEntry()
+	bodyEditor.SetMinRowsVisible(5)
+
+	statusLabel := widget.NewLabel("")
+	responseHeaders := widget.NewLabel("")
+	responseHeaders.TextStyle = fyne.TextStyle{Monospace: true}
+	responseBody := widget.NewMultiLineEntry()
+	responseBody.TextStyle = fyne.TextStyle{Monospace: true}
+	responseBody.SetMinRowsVisible(8)
+
+	var sendButton *widget.Button
+	sendButton = widget.NewButton("Send", func() {
+		headers, err := awsinterface.ParseHeaders(strings.Split(headersEditor.Text, "\n"))
+		if err != nil {
+			statusLabel.Importance = widget.DangerImportance
+			statusLabel.SetText(err.Error())
+			return
+		}
+		sendButton.Disable()
+		statusLabel.Importance = widget.MediumImportance
+		statusLabel.SetText("Sending...")
+		go func() {
+			defer sendButton.Enable()
+			response, err := r.awsInterface.InvokeFunctionURL(functionName, qualifier, awsinterface.URLRequest{
+				Method:   methodSelect.Selected,
+				Path:     pathEntry.Text,
+				Headers:  headers,
+				Body:     []byte(bodyEditor.Text),
+				Endpoint: strings.TrimSpace(endpointEntry.Text),
+				Unsigned: unsignedCheck.Checked,
+			})
+			if err != nil {
+				logger.Error("Function URL request failed:", err)
+				statusLabel.Importance = widget.DangerImportance
+				statusLabel.SetText(err.Error())
+				return
+			}
+
+			statusLabel.Importance = widget.SuccessImportance
+			if response.StatusCode >= 400 {
+				statusLabel.Importance = widget.DangerImportance
+			}
+			statusLabel.SetText(fmt.Sprintf("%s in %d ms", response.Status, response.DurationMs))
+			names := make([]string, 0, len(response.Headers))
+			for name := range response.Headers {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			lines := make([]string, len(names))
+			for i, name := range names {
+				lines[i] = fmt.Sprintf("%s: %s", name, strings.Join(response.Headers[name], ", "))
+			}
+			responseHeaders.SetText(strings.Join(lines, "\n"))
+			responseBody.SetText(response.Body)
+		}()
+	})
+
+	content := container.NewVBox(
+		configLabel,
+		container.New(layout.NewFormLayout(),
+			widget.NewLabel("Request:"), container.NewBorder(nil, nil, methodSelect, nil, pathEntry),
+			widget.NewLabel("Endpoint:"), container.NewBorder(nil, nil, nil, unsignedCheck, endpointEntry),
+			widget.NewLabel("Headers:"), headersEditor,
+			widget.NewLabel("Body:"), bodyEditor,
+		),
+		sendButton,
+		statusLabel,
+		widget.NewAccordion(widget.NewAccordionItem("Response headers", responseHeaders)),
+		responseBody,
+	)
+	title := functionName
+	if qualifier != "" {
+		title += ":" + qualifier
+	}
+	urlDialog := dialog.NewCustom(fmt.Sprintf("Function URL of %s", title), "Close", container.NewVScroll(content), r.window)
+	urlDialog.Resize(fyne.NewSize(720, 700))
+	urlDialog.Show()
+}
@@ -2,15 +2,24 @@ package render
 
 import (
 	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/emulator"
+	"aws_utility/pkg/history"
 	"aws_utility/pkg/logger"
-	"encoding/json"
+	"aws_utility/pkg/output"
+	"aws_utility/pkg/payload"
+	"aws_utility/pkg/schema"
+	"aws_utility/pkg/templates"
+	"context"
+	"errors"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/canvas"
 	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/layout"
 	"fyne.io/fyne/v2/widget"
 	"image/color"
+	"os"
 	"sort"
 	"strings"
 	"time"
@@ -21,6 +30,9 @@ type FyneRenderer struct {
 	menuContainer    *fyne.Container
 	contentContainer *fyne.Container
 	awsInterface     *awsinterface.AWSInterface
+	retryStatus      *retryStatus
+	// cancelStream stops the response stream being shown, if any.
+	cancelStream context.CancelFunc
 }
 
 func NewFyneRenderer(window fyne.Window, menuContainer *fyne.Container, contentContainer *fyne.Container) (*FyneRenderer, error) {
@@ -28,6 +40,7 @@ func NewFyneRenderer(window fyne.Window, menuContainer *fyne.Container, contentC
 		window:           window,
 		menuContainer:    menuContainer,
 		contentContainer: contentContainer,
+		retryStatus:      newRetryStatus(),
 	}, nil
 }
 
@@ -43,6 +56,12 @@ func (r *FyneRenderer) GenerateMenu() {
 
 	var accountSelect *widget.Select
 	var roleSelect *widget.Select
+	var accessibleAccounts []awsinterface.Account
+	fleetButton := widget.NewButton("Invoke across accounts...", func() {
+		r.GenerateFleetContent(accessibleAccounts)
+	})
+	fleetButton.Hide()
+	allRegionsCheck := widget.NewCheck("Search all enabled regions", nil)
 
 	loginButton := widget.NewButton("Login", func() {
 		portalURL := portalEntry.Text
@@ -115,6 +134,8 @@ func (r *FyneRenderer) GenerateMenu() {
 				accountSelect.Options = accountOptions
 				accountSelect.Refresh()
 				accountSelect.Show()
+				accessibleAccounts = accounts
+				fleetButton.Show()
 
 				r.contentContainer.Hide()
 				r.menuContainer.Show()
@@ -164,7 +185,17 @@ func (r *FyneRenderer) GenerateMenu() {
 
 		statusLabel.SetText("Role assumed successfully. Loading Lambda functions...")
 
-		lambdaFunctions, err := r.awsInterface.ListLambdaFunctions()
+		var lambdaFunctions []awsinterface.LambdaFunction
+		if allRegionsCheck.Checked {
+			lambdaFunctions, err = r.awsInterface.ListLambdaFunctionsInRegions(awsinterface.FunctionFilter{}, r.awsInterface.Regions(nil))
+			var regionErrors awsinterface.RegionErrors
+			if errors.As(err, &regionErrors) {
+				logger.Warn("Some regions could not be listed:", regionErrors)
+				err = nil
+			}
+		} else {
+			lambdaFunctions, err = r.awsInterface.ListLambdaFunctions(awsinterface.FunctionFilter{})
+		}
 		if err != nil {
 			logger.Error("Failed to list Lambda functions:", err)
 			statusLabel.SetText(fmt.Sprintf("Error: Failed to list Lambda functions: %v", err))
@@ -181,6 +212,8 @@ func (r *FyneRenderer) GenerateMenu() {
 		loginButton,
 		accountSelect,
 		roleSelect,
+		allRegionsCheck,
+		fleetButton,
 		statusLabel,
 	)
 
@@ -360,74 +393,492 @@ func (r *FyneRenderer) logSelectedTimes(selectedTimes map[time.Time]bool) {
 	}
 }
 
-func (r *FyneRenderer) GenerateLambdaContent(lambdaFunctions []string) {
+func (r *FyneRenderer) GenerateLambdaContent(lambdaFunctions []awsinterface.LambdaFunction) {
 	r.ClearScreen()
 
-	functionDropdown := widget.NewSelect(lambdaFunctions, func(value string) {
+	resultLabel := widget.NewLabel("")
+
+	payloadTemplates, err := templates.LoadDefault()
+	if err != nil {
+		logger.Error("Failed to load payload templates:", err)
+		resultLabel.SetText(fmt.Sprintf("Error: %v", err))
+		payloadTemplates = templates.Builtin
+	}
+
+	var payloadSchema *schema.Schema
+	schemaLabel := widget.NewLabel("")
+
+	payloadEditor := widget.NewMultiLineEntry()
+	payloadEditor.SetPlaceHolder("{\n  \"key\": \"value\"\n}")
+	payloadEditor.SetMinRowsVisible(8)
+	payloadEditor.Validator = func(text string) error {
+		payloadJson, err := payload.Validate(text)
+		if err != nil || payloadSchema == nil {
+			return err
+		}
+		return payloadSchema.Check("payload", payloadJson)
+	}
+	payloadEditor.Hide()
+
+	var keySelect *widget.Select
+	keySelect = widget.NewSelect([]string{}, func(key string) {
+		if key == "" {
+			return
+		}
+		segments := strings.Split(key, ".")
+		insertAtCursor(payloadEditor, fmt.Sprintf("%q: ", segments[len(segments)-1]))
+		keySelect.ClearSelected()
+	})
+	keySelect.PlaceHolder = "Insert schema key"
+	keySelect.Hide()
+
+	qualifierNames := map[string]string{}
+	qualifierSelect := widget.NewSelect([]string{}, nil)
+	qualifierSelect.PlaceHolder = "$LATEST"
+
+	functionDetails := widget.NewCard("", "", nil)
+	functionDetails.Hide()
+
+	var functionPicker *functionPicker
+	detailsButton := widget.NewButton("Show full configuration", func() {
+		if functionPicker.Selected != "" {
+			r.ShowFunctionDetails(functionPicker.Selected, qualifierNames[qualifierSelect.Selected])
+		}
+	})
+	deployButton := widget.NewButton("Deploy code...", func() {
+		if functionPicker.Selected != "" {
+			r.ShowDeployDialog(functionPicker.Selected)
+		}
+	})
+	canaryButton := widget.NewButton("Canary release...", func() {
+		if functionPicker.Selected != "" {
+			r.ShowCanaryDialog(functionPicker.Selected)
+		}
+	})
+	environmentButton := widget.NewButton("Environment...", func() {
+		if functionPicker.Selected != "" {
+			r.ShowEnvironmentDialog(functionPicker.Selected)
+		}
+	})
+	concurrencyButton := widget.NewButton("Concurrency...", func() {
+		if functionPicker.Selected != "" {
+			r.ShowConcurrencyDialog(functionPicker.Selected)
+		}
+	})
+	urlButton := widget.NewButton("Function URL...", func() {
+		if functionPicker.Selected != "" {
+			r.ShowFunctionURLDialog(functionPicker.Selected, qualifierNames[qualifierSelect.Selected])
+		}
+	})
+	logsButton := widget.NewButton("Logs...", func() {
+		if functionPicker.Selected != "" {
+			r.ShowLogsDialog(functionPicker.Selected, awsinterface.LogQuery{})
+		}
+	})
+	diffButton := widget.NewButton("Compare...", func() {
+		if functionPicker.Selected != "" {
+			r.ShowDiffDialog(functionPicker.Selected, qualifierNames[qualifierSelect.Selected])
+		}
+	})
+
+	functionPicker = newFunctionPicker(lambdaFunctions, func(function awsinterface.LambdaFunction) {
+		value := function.Name
 		logger.Info("Lambda function selected:", value)
+		// Lambda calls must go to the region the function lives in.
+		r.awsInterface = r.awsInterface.ForRegion(function.Region)
+
+		functionDetails.SetTitle(function.Name)
+		functionDetails.SetSubTitle(function.Description)
+		functionDetails.SetContent(container.NewVBox(newFunctionDetailsGrid(function), container.NewGridWithColumns(4, detailsButton, deployButton, canaryButton, environmentButton, concurrencyButton, urlButton, logsButton, diffButton)))
+		functionDetails.Show()
+
+		qualifierNames = map[string]string{}
+		qualifierSelect.Options = nil
+		qualifierSelect.ClearSelected()
+		qualifiers, err := r.awsInterface.ListQualifiers(value)
+		if err != nil {
+			logger.Error("Failed to list versions and aliases:", err)
+			resultLabel.SetText(fmt.Sprintf("Error: Failed to list versions and aliases: %v", err))
+		}
+		for _, qualifier := range qualifiers {
+			label := qualifier.Name
+			if qualifier.Description != "" {
+				label += " — " + qualifier.Description
+			}
+			qualifierNames[label] = qualifier.Name
+			qualifierSelect.Options = append(qualifierSelect.Options, label)
+		}
+		qualifierSelect.Refresh()
+
+		payloadSchema, err = r.loadSchema(value)
+		if err != nil {
+			logger.Error("Failed to load payload schema:", err)
+			schemaLabel.SetText(fmt.Sprintf("Error: Failed to load payload schema: %v", err))
+		} else if payloadSchema != nil {
+			schemaLabel.SetText(fmt.Sprintf("Payload schema: %s", payloadSchema.Location))
+		} else {
+			schemaLabel.SetText("")
+		}
+
+		if payloadSchema != nil && payloadEditor.Visible() {
+			keySelect.Options = payloadSchema.Keys()
+			keySelect.Refresh()
+			keySelect.Show()
+		} else {
+			keySelect.Hide()
+		}
+		payloadEditor.Validate()
 	})
 
-	clusterEntry := widget.NewEntry()
-	clusterEntry.SetPlaceHolder("Enter Cluster Name")
+	templateFields := container.NewVBox()
+	var variableEntries map[string]*widget.Entry
+	var selectedTemplate *templates.Template
 
-	serviceEntry := widget.NewEntry()
-	serviceEntry.SetPlaceHolder("Enter Service Name")
+	const jsonEditorMode = "JSON editor"
+	templateNames := make([]string, 0, len(payloadTemplates)+1)
+	for _, template := range payloadTemplates {
+		templateNames = append(templateNames, template.Name)
+	}
+	templateNames = append(templateNames, jsonEditorMode)
+
+	templateSelect := widget.NewSelect(templateNames, func(value string) {
+		templateFields.RemoveAll()
+		variableEntries = map[string]*widget.Entry{}
+		selectedTemplate = nil
+
+		if value == jsonEditorMode {
+			templateFields.Hide()
+			payloadEditor.Show()
+			if payloadSchema != nil {
+				keySelect.Options = payloadSchema.Keys()
+				keySelect.Refresh()
+				keySelect.Show()
+			}
+			return
+		}
+		payloadEditor.Hide()
+		keySelect.Hide()
 
-	tagEntry := widget.NewEntry()
-	tagEntry.SetPlaceHolder("Enter Tag Name")
+		template, err := templates.Find(payloadTemplates, value)
+		if err != nil {
+			resultLabel.SetText(fmt.Sprintf("Error: %v", err))
+			return
+		}
+		selectedTemplate = template
+		if template.Function != "" {
+			if arn, ok := functionPicker.Lookup(template.Function, ""); ok {
+				functionPicker.SetSelected(arn)
+			}
+		}
 
-	resultLabel := widget.NewLabel("")
+		for _, variable := range template.Variables {
+			entry := widget.NewEntry()
+			entry.SetPlaceHolder(variable.Default)
+			variableEntries[variable.Name] = entry
+			templateFields.Add(widget.NewLabel(variable.Label() + ":"))
+			templateFields.Add(entry)
+		}
+		templateFields.Show()
+		templateFields.Refresh()
+	})
+	templateSelect.SetSelected(templates.ECSDeploy.Name)
+
+	invocationTypeSelect := widget.NewSelect([]string{"RequestResponse", "Event", "DryRun"}, nil)
+	invocationTypeSelect.SetSelected("RequestResponse")
+	tailLogsCheck := widget.NewCheck("Tail logs", nil)
+	tailLogsCheck.SetChecked(true)
+	streamCheck := widget.NewCheck("Stream response", nil)
+	localEntry := widget.NewEntry()
+	localEntry.SetPlaceHolder("path to a locally built bootstrap")
+	localEntry.SetText(os.Getenv(emulator.BootstrapEnvVariable))
+	localEntry.Hide()
+	localCheck := widget.NewCheck("Run locally", func(checked bool) {
+		if checked {
+			localEntry.Show()
+		} else {
+			localEntry.Hide()
+		}
+	})
 
-	invokeButton := widget.NewButton("Invoke Lambda", func() {
-		selectedFunction := functionDropdown.Selected
-		payload := map[string]string{
-			"cluster": clusterEntry.Text,
-			"service": serviceEntry.Text,
-			"ecr_tag": tagEntry.Text,
+	resultView := newInvokeResultView()
+	resultView.OnLogs = func(entry history.Entry) {
+		r.ShowLogsDialog(entry.Function, entry.LogQuery())
+	}
+	historyView := newHistoryView()
+
+	var invokeButton *widget.Button
+	invokeButton = widget.NewButton("Invoke Lambda", func() {
+		r.stopStream()
+		selectedFunction := functionPicker.Selected
+
+		var payloadJson []byte
+		var err error
+		if selectedTemplate == nil {
+			payloadJson, err = payload.Validate(payloadEditor.Text)
+		} else {
+			vars := make(map[string]string, len(variableEntries))
+			for name, entry := range variableEntries {
+				vars[name] = entry.Text
+			}
+			payloadJson, err = selectedTemplate.Render(vars)
+		}
+		if err == nil && payloadSchema != nil {
+			err = payloadSchema.Check(selectedFunction, payloadJson)
+		}
+		if err != nil {
+			logger.Error("Failed to build payload:", err)
+			resultLabel.SetText(fmt.Sprintf("Error: %v", err))
+			return
 		}
 
-		payloadJson, err := json.Marshal(payload)
+		invocationType, err := awsinterface.ParseInvocationType(invocationTypeSelect.Selected)
 		if err != nil {
-			logger.Error("Failed to marshal payload:", err)
 			resultLabel.SetText(fmt.Sprintf("Error: %v", err))
 			return
 		}
 
-		result, err := r.awsInterface.InvokeLambda(selectedFunction, payloadJson)
+		resultLabel.SetText("")
+		if localCheck.Checked {
+			// Local runs are not recorded in the invocation history.
+			invokeButton.Disable()
+			resultView.ShowStatus("Running local handler...")
+			go func() {
+				defer invokeButton.Enable()
+				result, err := emulator.Invoke(selectedFunction, payloadJson, emulator.Options{
+					Bootstrap:      strings.TrimSpace(localEntry.Text),
+					InvocationType: invocationType,
+				})
+				if err != nil {
+					logger.Error("Failed to invoke local handler:", err)
+					resultView.ShowError(err)
+					return
+				}
+				logger.Info("Local handler invoked. Result:", string(result.Payload))
+				resultView.ShowResult(selectedFunction+" (local)", result)
+			}()
+			return
+		}
+		invokeOptions := awsinterface.InvokeOptions{
+			InvocationType: invocationType,
+			TailLogs:       tailLogsCheck.Checked,
+			Qualifier:      qualifierNames[qualifierSelect.Selected],
+		}
+		if streamCheck.Checked {
+			ctx, cancel := context.WithCancel(context.Background())
+			stream, entry, err := history.InvokeStream(ctx, r.awsInterface, selectedFunction, payloadJson, invokeOptions)
+			if err != nil {
+				cancel()
+				logger.Error("Failed to invoke Lambda with response streaming:", err)
+				historyView.Reload()
+				resultView.ShowError(err)
+				return
+			}
+			r.cancelStream = cancel
+			resultView.StartStream(selectedFunction, stream)
+			go func() {
+				defer cancel()
+				for event := range stream.Events {
+					resultView.ShowStreamEvent(event)
+				}
+				historyView.Reload()
+				resultView.EnableLogs(entry)
+			}()
+			return
+		}
+
+		result, entry, err := history.Invoke(r.awsInterface, selectedFunction, payloadJson, invokeOptions)
+		historyView.Reload()
 		if err != nil {
 			logger.Error("Failed to invoke Lambda:", err)
-			resultLabel.SetText(fmt.Sprintf("Error: %v", err))
+			resultView.ShowError(err)
 			return
 		}
 
-		logger.Info("Lambda invoked successfully. Result:", string(result))
-		resultLabel.SetText(fmt.Sprintf("Result: %s", string(result)))
+		logger.Info("Lambda invoked. Result:", string(result.Payload))
+		resultView.ShowResult(selectedFunction, result)
+		resultView.EnableLogs(entry)
 	})
 
-	menuContent := container.NewVBox(
+	invokeContent := container.NewVBox(
 		widget.NewLabel("Select Lambda Function:"),
-		functionDropdown,
-		widget.NewLabel("Cluster:"),
-		clusterEntry,
-		widget.NewLabel("Service:"),
-		serviceEntry,
-		widget.NewLabel("Tag:"),
-		tagEntry,
+		functionPicker.content,
+		functionDetails,
+		widget.NewLabel("Version or alias:"),
+		qualifierSelect,
+		schemaLabel,
+		widget.NewLabel("Payload template:"),
+		templateSelect,
+		templateFields,
+		payloadEditor,
+		keySelect,
+		container.NewHBox(widget.NewLabel("Invocation type:"), invocationTypeSelect, tailLogsCheck, streamCheck, localCheck),
+		localEntry,
 		invokeButton,
 		resultLabel,
+		resultView.content,
 	)
 
-	r.contentContainer.Add(menuContent)
+	invokeTab := container.NewTabItem("Invoke", invokeContent)
+	tabs := container.NewAppTabs(invokeTab, container.NewTabItem("History", historyView.content))
+	tabs.OnSelected = func(tab *container.TabItem) {
+		if tab != invokeTab {
+			historyView.Reload()
+		}
+	}
+
+	historyView.OnLoad = func(entry history.Entry, invokeNow bool) {
+		tabs.Select(invokeTab)
+		arn, ok := functionPicker.Lookup(entry.Function, entry.Region)
+		if !ok {
+			resultLabel.SetText(fmt.Sprintf("Error: function '%s' is not available in this account", entry.Function))
+			return
+		}
+		functionPicker.SetSelected(arn)
+		qualifierSelect.ClearSelected()
+		for label, name := range qualifierNames {
+			if entry.Qualifier != "" && name == entry.Qualifier {
+				qualifierSelect.SetSelected(label)
+			}
+		}
+		invocationTypeSelect.SetSelected(entry.InvocationType)
+		streamCheck.SetChecked(entry.Streamed)
+		templateSelect.SetSelected(jsonEditorMode)
+		payloadEditor.SetText(payload.Indent([]byte(entry.Payload)))
+		resultLabel.SetText("")
+		if invokeNow {
+			invokeButton.OnTapped()
+		}
+	}
+
+	r.contentContainer.Add(tabs)
 	r.menuContainer.Hide()
 	r.contentContainer.Show()
 }
 
+func newFunctionDetailsGrid(function awsinterface.LambdaFunction) *fyne.Container {
+	return newRowsGrid([]output.Row{
+		{Label: "ARN", Value: function.ARN},
+		{Label: "Runtime", Value: function.Runtime},
+		{Label: "Architecture", Value: function.Architecture},
+		{Label: "Memory", Value: fmt.Sprintf("%d MB", function.MemorySize)},
+		{Label: "Timeout", Value: fmt.Sprintf("%d s", function.Timeout)},
+		{Label: "Package type", Value: function.PackageType},
+		{Label: "Handler", Value: function.Handler},
+		{Label: "Last modified", Value: function.LastModified},
+	})
+}
+
+func newRowsGrid(rows []output.Row) *fyne.Container {
+	grid := container.New(layout.NewFormLayout())
+	for _, row := range rows {
+		if row.Value == "" {
+			continue
+		}
+		grid.Add(widget.NewLabelWithStyle(row.Label, fyne.TextAlignTrailing, fyne.TextStyle{Bold: true}))
+		value := widget.NewLabel(row.Value)
+		value.Wrapping = fyne.TextWrapBreak
+		grid.Add(value)
+	}
+	return grid
+}
+
+// ShowFunctionDetails opens a dialog with the full configuration of the
+// function, as returned by DescribeFunction.
+func (r *FyneRenderer) ShowFunctionDetails(functionName, qualifier string) {
+	details, err := r.awsInterface.DescribeFunction(functionName, qualifier)
+	if err != nil {
+		logger.Error("Failed to describe Lambda function:", err)
+		dialog.ShowError(err, r.window)
+		return
+	}
+
+	sections := container.NewVBox(newRowsGrid(output.FunctionDetailRows(details)))
+	mappings, err := r.awsInterface.ListEventSourceMappings(functionName)
+	if err != nil {
+		logger.Warn("Failed to list event source mappings:", err)
+	} else if len(mappings) > 0 {
+		sections.Add(widget.NewSeparator())
+		sections.Add(widget.NewLabelWithStyle("Event source mappings", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}))
+		for _, mapping := range mappings {
+			sections.Add(r.newEventSourceMappingRow(mapping))
+		}
+	}
+
+	content := container.NewVScroll(sections)
+	content.SetMinSize(fyne.NewSize(600, 400))
+	dialog.ShowCustom(details.Name, "Close", content, r.window)
+}
+
+// newEventSourceMappingRow shows a mapping with a button that pauses or
+// resumes it.
+func (r *FyneRenderer) newEventSourceMappingRow(mapping awsinterface.EventSourceMapping) fyne.CanvasObject {
+	summary := widget.NewLabel(mapping.Summary())
+	summary.Wrapping = fyne.TextWrapWord
+	var toggleButton *widget.Button
+	update := func() {
+		summary.SetText(mapping.Summary())
+		if mapping.Enabled() {
+			toggleButton.SetText("Disable")
+		} else {
+			toggleButton.SetText("Enable")
+		}
+	}
+	toggleButton = widget.NewButton("", func() {
+		updated, err := r.awsInterface.SetEventSourceMappingEnabled(mapping.UUID, !mapping.Enabled())
+		if err != nil {
+			logger.Error("Failed to update event source mapping:", err)
+			dialog.ShowError(err, r.window)
+			return
+		}
+		mapping = *updated
+		update()
+	})
+	update()
+	return container.NewBorder(nil, nil, nil, toggleButton, summary)
+}
+
+func (r *FyneRenderer) loadSchema(functionName string) (*schema.Schema, error) {
+	tags, err := r.awsInterface.GetFunctionTags(functionName)
+	if err != nil {
+		logger.Warn("Failed to read function tags, checking local schemas only:", err)
+	}
+	return schema.ForFunction(functionName, tags)
+}
+
+func insertAtCursor(entry *widget.Entry, text string) {
+	lines := strings.Split(entry.Text, "\n")
+	row := entry.CursorRow
+	if row >= len(lines) {
+		entry.SetText(entry.Text + text)
+		return
+	}
+	line := []rune(lines[row])
+	column := entry.CursorColumn
+	if column > len(line) {
+		column = len(line)
+	}
+	lines[row] = string(line[:column]) + text + string(line[column:])
+	entry.SetText(strings.Join(lines, "\n"))
+	entry.CursorColumn = column + len([]rune(text))
+	entry.Refresh()
+}
+
 func (r *FyneRenderer) clearMenu() {
 	r.menuContainer.RemoveAll()
 	r.menuContainer.Refresh()
 }
 
+// stopStream cancels the response stream being shown, which nothing will
+// read once the user has moved on.
+func (r *FyneRenderer) stopStream() {
+	if r.cancelStream != nil {
+		r.cancelStream()
+		r.cancelStream = nil
+	}
+}
+
 func (r *FyneRenderer) clearContent() {
+	r.stopStream()
 	r.contentContainer.RemoveAll()
 	r.contentContainer.Refresh()
 }
@@ -0,0 +1,122 @@
+package render
+
+import (
+	"aws_utility/pkg/history"
+	"aws_utility/pkg/logger"
+	"aws_utility/pkg/payload"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+)
+
+// historyView lists recorded invocations, newest first, and hands the
+// selected one back through OnLoad to be edited or re-invoked.
+type historyView struct {
+	entries       []history.Entry
+	selected      *history.Entry
+	list          *widget.List
+	detailsLabel  *widget.Label
+	payloadLabel  *widget.Label
+	responseLabel *widget.Label
+	errorLabel    *widget.Label
+	content       fyne.CanvasObject
+
+	// OnLoad receives the selected entry; invokeNow is false for "Edit".
+	OnLoad func(entry history.Entry, invokeNow bool)
+}
+
+func newHistoryView() *historyView {
+	v := &historyView{
+		detailsLabel:  widget.NewLabel(""),
+		payloadLabel:  widget.NewLabel(""),
+		responseLabel: widget.NewLabel(""),
+		errorLabel:    widget.NewLabel(""),
+	}
+	v.detailsLabel.Wrapping = fyne.TextWrapWord
+	v.payloadLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	v.responseLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	v.errorLabel.Importance = widget.DangerImportance
+	v.errorLabel.Wrapping = fyne.TextWrapWord
+	v.errorLabel.Hide()
+
+	v.list = widget.NewList(
+		func() int { return len(v.entries) },
+		func() fyne.CanvasObject { return widget.NewLabel("") },
+		func(id widget.ListItemID, object fyne.CanvasObject) {
+			entry := v.entries[len(v.entries)-1-id]
+			object.(*widget.Label).SetText(fmt.Sprintf("#%d  %s  %s  %s  %s",
+				entry.ID, entry.Timestamp.Local().Format("2006-01-02 15:04:05"), entry.QualifiedFunction(), entry.Status, entry.Duration()))
+		},
+	)
+	v.list.OnSelected = func(id widget.ListItemID) {
+		entry := v.entries[len(v.entries)-1-id]
+		v.selected = &entry
+		v.showEntry(entry)
+	}
+
+	load := func(invokeNow bool) func() {
+		return func() {
+			if v.selected != nil && v.OnLoad != nil {
+				v.OnLoad(*v.selected, invokeNow)
+			}
+		}
+	}
+	buttons := container.NewHBox(
+		widget.NewButton("Refresh", v.Reload),
+		widget.NewButton("Edit and invoke", load(false)),
+		widget.NewButton("Re-invoke", load(true)),
+	)
+
+	listScroll := container.NewVScroll(v.list)
+	listScroll.SetMinSize(fyne.NewSize(600, 250))
+	v.content = container.NewVBox(
+		buttons,
+		v.errorLabel,
+		listScroll,
+		v.detailsLabel,
+		widget.NewLabelWithStyle("Payload", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		v.payloadLabel,
+		widget.NewLabelWithStyle("Response", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}),
+		v.responseLabel,
+	)
+	v.Reload()
+	return v
+}
+
+func (v *historyView) Reload() {
+	entries, err := history.Load()
+	if err != nil {
+		logger.Error("Failed to load invocation history:", err)
+		v.errorLabel.SetText(fmt.Sprintf("Error: %v", err))
+		v.errorLabel.Show()
+		return
+	}
+	v.errorLabel.Hide()
+	v.entries = entries
+	v.selected = nil
+	v.list.UnselectAll()
+	v.list.Refresh()
+	v.detailsLabel.SetText("")
+	v.payloadLabel.SetText("")
+	v.responseLabel.SetText("")
+}
+
+func (v *historyView) showEntry(entry history.Entry) {
+	details := []string{
+		fmt.Sprintf("%s, %s, status %d, %s", entry.InvocationType, entry.Status, entry.StatusCode, entry.Duration()),
+	}
+	if entry.AccountID != "" {
+		details = append(details, fmt.Sprintf("Account %s, role %s, region %s", entry.AccountID, entry.RoleName, entry.Region))
+	}
+	if entry.FunctionError != "" {
+		details = append(details, "Function error: "+entry.FunctionError)
+	}
+	if entry.Error != "" {
+		details = append(details, "Error: "+entry.Error)
+	}
+	v.detailsLabel.SetText(strings.Join(details, "\n"))
+	v.payloadLabel.SetText(payload.Indent([]byte(entry.Payload)))
+	v.responseLabel.SetText(payload.Indent([]byte(entry.Response)))
+}
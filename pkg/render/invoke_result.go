@@ -0,0 +1,214 @@
+package render
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/history"
+	"aws_utility/pkg/output"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/theme"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+)
+
+// maxHighlightedResponse is the size above which responses are shown
+// without highlighting, which takes a segment per token.
+const maxHighlightedResponse = 64 * 1024
+
+var tokenColors = map[output.TokenKind]fyne.ThemeColorName{
+	output.TokenKey:     theme.ColorNamePrimary,
+	output.TokenString:  theme.ColorNameSuccess,
+	output.TokenNumber:  theme.ColorNameWarning,
+	output.TokenLiteral: theme.ColorNameHyperlink,
+}
+
+// invokeResultView shows an invocation's status, function error, tail logs
+// and response payload as separate, distinctly styled sections. The
+// response can be shown in any output.ResponseFormats and narrowed with a
+// JMESPath query.
+type invokeResultView struct {
+	statusLabel      *widget.Label
+	errorLabel       *widget.Label
+	logsLabel        *widget.Label
+	responseText     *widget.RichText
+	formatSelect     *widget.Select
+	queryEntry       *widget.Entry
+	responseControls *fyne.Container
+	logsSection      *fyne.Container
+	logsButton       *widget.Button
+	content          *fyne.Container
+	entry            history.Entry
+	payload          []byte
+	failed           bool
+	// OnLogs opens the CloudWatch logs of the invocation shown.
+	OnLogs func(history.Entry)
+}
+
+func newInvokeResultView() *invokeResultView {
+	v := &invokeResultView{
+		statusLabel:  widget.NewLabel(""),
+		errorLabel:   widget.NewLabel(""),
+		logsLabel:    widget.NewLabel(""),
+		responseText: widget.NewRichText(),
+		queryEntry:   widget.NewEntry(),
+	}
+	v.responseText.Wrapping = fyne.TextWrapBreak
+	v.statusLabel.Wrapping = fyne.TextWrapWord
+	v.errorLabel.Importance = widget.DangerImportance
+	v.errorLabel.Wrapping = fyne.TextWrapWord
+	v.logsLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	v.logsLabel.Importance = widget.LowImportance
+	v.formatSelect = widget.NewSelect(output.ResponseFormats, nil)
+	v.formatSelect.SetSelected(output.FormatJSON)
+	v.formatSelect.OnChanged = func(string) { v.showResponse() }
+	v.queryEntry.SetPlaceHolder("JMESPath query, e.g. items[].id")
+	v.queryEntry.OnChanged = func(string) { v.showResponse() }
+	v.responseControls = container.NewBorder(nil, nil,
+		container.NewHBox(widget.NewLabelWithStyle("Response", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), v.formatSelect),
+		nil, v.queryEntry)
+
+	v.logsButton = widget.NewButton("CloudWatch logs of this invocation...", func() {
+		if v.OnLogs != nil {
+			v.OnLogs(v.entry)
+		}
+	})
+
+	v.logsSection = container.NewVBox(widget.NewLabelWithStyle("Logs", fyne.TextAlignLeading, fyne.TextStyle{Bold: true}), v.logsLabel)
+	v.content = container.NewVBox(v.statusLabel, v.errorLabel, v.logsSection, v.responseControls, v.responseText, container.NewHBox(v.logsButton))
+	v.Clear()
+	return v
+}
+
+func (v *invokeResultView) Clear() {
+	v.statusLabel.SetText("")
+	v.errorLabel.Hide()
+	v.logsSection.Hide()
+	v.logsButton.Hide()
+	v.payload = nil
+	v.failed = false
+	v.showResponse()
+}
+
+// EnableLogs offers the recorded invocation's logs once its request ID is
+// known.
+func (v *invokeResultView) EnableLogs(entry history.Entry) {
+	v.entry = entry
+	if entry.RequestID != "" {
+		v.logsButton.Show()
+	}
+}
+
+// ShowStatus replaces the result with a progress message.
+func (v *invokeResultView) ShowStatus(text string) {
+	v.Clear()
+	v.statusLabel.SetText(text)
+}
+
+func (v *invokeResultView) ShowError(err error) {
+	v.Clear()
+	v.errorLabel.SetText(fmt.Sprintf("Error: %v", err))
+	v.errorLabel.Show()
+}
+
+func (v *invokeResultView) ShowResult(functionName string, result *awsinterface.InvokeResult) {
+	v.Clear()
+
+	status := fmt.Sprintf("%s: %s, status %d", functionName, result.InvocationType, result.StatusCode)
+	if result.ExecutedVersion != "" {
+		status += ", version " + result.ExecutedVersion
+	}
+	if result.RequestID != "" {
+		status += ", request " + result.RequestID
+	}
+	v.statusLabel.SetText(status)
+
+	if result.Failed() {
+		v.errorLabel.SetText(fmt.Sprintf("Function error: %s", result.FunctionError))
+		v.errorLabel.Show()
+	}
+
+	if result.LogResult != "" {
+		v.logsLabel.SetText(strings.TrimRight(result.LogResult, "\n"))
+		v.logsSection.Show()
+	}
+
+	v.payload = result.Payload
+	v.failed = result.Failed()
+	v.showResponse()
+}
+
+// showResponse renders the payload in the selected format, highlighting
+// JSON, or the query's error.
+func (v *invokeResultView) showResponse() {
+	if len(v.payload) == 0 {
+		v.responseControls.Hide()
+		v.responseText.Segments = nil
+		v.responseText.Refresh()
+		return
+	}
+	v.responseControls.Show()
+
+	opts := output.ResponseOptions{Format: v.formatSelect.Selected, Query: v.queryEntry.Text}
+	formatted, err := output.FormatResponse(v.payload, opts)
+	switch {
+	case err != nil:
+		v.responseText.Segments = []widget.RichTextSegment{responseSegment(err.Error(), theme.ColorNameError)}
+	case v.failed:
+		v.responseText.Segments = []widget.RichTextSegment{responseSegment(formatted, theme.ColorNameError)}
+	case opts.Format == output.FormatJSON && len(formatted) <= maxHighlightedResponse:
+		tokens := output.JSONTokens(formatted)
+		segments := make([]widget.RichTextSegment, len(tokens))
+		for i, token := range tokens {
+			segments[i] = responseSegment(token.Text, tokenColors[token.Kind])
+		}
+		v.responseText.Segments = segments
+	default:
+		v.responseText.Segments = []widget.RichTextSegment{responseSegment(formatted, "")}
+	}
+	v.responseText.Refresh()
+}
+
+func responseSegment(text string, color fyne.ThemeColorName) widget.RichTextSegment {
+	style := widget.RichTextStyleCodeInline
+	style.ColorName = color
+	if color == "" {
+		style.ColorName = theme.ColorNameForeground
+	}
+	return &widget.TextSegment{Text: text, Style: style}
+}
+
+func (v *invokeResultView) StartStream(functionName string, stream *awsinterface.InvokeStream) {
+	v.Clear()
+
+	status := fmt.Sprintf("%s: streaming, status %d", functionName, stream.StatusCode)
+	if stream.ExecutedVersion != "" {
+		status += ", version " + stream.ExecutedVersion
+	}
+	v.statusLabel.SetText(status)
+}
+
+// ShowStreamEvent appends a response chunk, or shows the outcome and logs
+// once the stream completes or fails.
+func (v *invokeResultView) ShowStreamEvent(event awsinterface.StreamEvent) {
+	switch {
+	case event.Err != nil:
+		v.errorLabel.SetText(fmt.Sprintf("Error: %v", event.Err))
+		v.errorLabel.Show()
+	case event.Complete != nil:
+		v.statusLabel.SetText(strings.Replace(v.statusLabel.Text, "streaming", "stream complete", 1))
+		if event.Complete.Failed() {
+			v.errorLabel.SetText(fmt.Sprintf("Function error: %s: %s", event.Complete.ErrorCode, event.Complete.ErrorDetails))
+			v.errorLabel.Show()
+			v.failed = true
+			v.showResponse()
+		}
+		if event.Complete.LogResult != "" {
+			v.logsLabel.SetText(strings.TrimRight(event.Complete.LogResult, "\n"))
+			v.logsSection.Show()
+		}
+	default:
+		v.payload = append(v.payload, event.Chunk...)
+		v.showResponse()
+	}
+}
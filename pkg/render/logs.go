@@ -0,0 +1,158 @@
+package render
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"aws_utility/pkg/logger"
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	sinceInvocation = "the invocation"
+	// maxLogLines keeps the panel responsive when following a busy function.
+	maxLogLines = 2000
+)
+
+var logWindows = []string{"5m", "15m", "1h", "3h", "12h", "24h"}
+
+// ShowLogsDialog reads the function's CloudWatch logs and can keep
+// following them. A query with a request ID and start, from an invocation,
+// shows only that invocation's events.
+func (r *FyneRenderer) ShowLogsDialog(functionName string, query awsinterface.LogQuery) {
+	awsInterface := r.awsInterface
+
+	patternEntry := widget.NewEntry()
+	patternEntry.SetPlaceHolder("CloudWatch Logs filter pattern")
+	requestEntry := widget.NewEntry()
+	requestEntry.SetPlaceHolder("all invocations")
+	requestEntry.SetText(query.RequestID)
+	windows := logWindows
+	if !query.Start.IsZero() {
+		windows = append([]string{sinceInvocation}, logWindows...)
+	}
+	sinceSelect := widget.NewSelect(windows, nil)
+	sinceSelect.SetSelected(windows[0])
+	if query.Start.IsZero() {
+		sinceSelect.SetSelected("15m")
+	}
+	followCheck := widget.NewCheck("Follow", nil)
+	followCheck.SetChecked(true)
+
+	statusLabel := widget.NewLabel("")
+	statusLabel.Wrapping = fyne.TextWrapWord
+	logsLabel := widget.NewLabel("")
+	logsLabel.TextStyle = fyne.TextStyle{Monospace: true}
+	logsLabel.Wrapping = fyne.TextWrapBreak
+	logsScroll := container.NewVScroll(logsLabel)
+
+	var mu sync.Mutex
+	var lines []string
+	cancel := func() {}
+	showEvents := func(events []awsinterface.LogEvent) {
+		mu.Lock()
+		defer mu.Unlock()
+		for _, event := range events {
+			lines = append(lines, event.Line())
+		}
+		if len(lines) > maxLogLines {
+			lines = lines[len(lines)-maxLogLines:]
+		}
+		logsLabel.SetText(strings.Join(lines, "\n"))
+		logsScroll.ScrollToBottom()
+	}
+	showStatus := func(importance widget.Importance, text string) {
+		statusLabel.Importance = importance
+		statusLabel.SetText(text)
+	}
+
+	var showButton *widget.Button
+	showButton = widget.NewButton("Show", func() {
+		cancel()
+		ctx, stop := context.WithCancel(context.Background())
+		cancel = stop
+		mu.Lock()
+		lines = nil
+		mu.Unlock()
+		logsLabel.SetText("")
+
+		search := awsinterface.LogQuery{
+			FilterPattern: strings.TrimSpace(patternEntry.Text),
+			RequestID:     strings.TrimSpace(requestEntry.Text),
+			Start:         query.Start,
+		}
+		if sinceSelect.Selected != sinceInvocation {
+			window, _ := time.ParseDuration(sinceSelect.Selected)
+			search.Start = time.Now().Add(-window)
+		}
+		follow := followCheck.Checked
+
+		showStatus(widget.MediumImportance, "Finding the log group...")
+		go func() {
+			group, err := awsInterface.FunctionLogGroup(functionName)
+			if err != nil {
+				logger.Error("Failed to find the function's log group:", err)
+				showStatus(widget.DangerImportance, err.Error())
+				return
+			}
+			search.LogGroup = group
+
+			if !follow {
+				showButton.Disable()
+				defer showButton.Enable()
+				events, err := awsInterface.FilterLogs(search, maxLogLines)
+				if err != nil {
+					logger.Error("Failed to read logs:", err)
+					showStatus(widget.DangerImportance, err.Error())
+					return
+				}
+				showEvents(events)
+				showStatus(widget.MediumImportance, fmt.Sprintf("%d event(s) from %s", len(events), group))
+				return
+			}
+
+			showStatus(widget.MediumImportance, fmt.Sprintf("Following %s...", group))
+			count := 0
+			for batch := range awsInterface.FollowLogs(ctx, search, 2*time.Second) {
+				if batch.Err != nil {
+					logger.Warn("Failed to read logs:", batch.Err)
+					showStatus(widget.WarningImportance, batch.Err.Error())
+					continue
+				}
+				count += len(batch.Events)
+				showEvents(batch.Events)
+				showStatus(widget.MediumImportance, fmt.Sprintf("%d event(s) from %s, following", count, group))
+			}
+		}()
+	})
+	stopButton := widget.NewButton("Stop", func() {
+		cancel()
+		showStatus(widget.MediumImportance, "Stopped")
+	})
+
+	content := container.NewBorder(
+		container.NewVBox(
+			container.New(layout.NewFormLayout(),
+				widget.NewLabel("Filter:"), patternEntry,
+				widget.NewLabel("Request ID:"), requestEntry,
+				widget.NewLabel("Since:"), container.NewHBox(sinceSelect, followCheck),
+			),
+			container.NewHBox(showButton, stopButton),
+			statusLabel,
+		),
+		nil, nil, nil,
+		logsScroll,
+	)
+	logsDialog := dialog.NewCustom(fmt.Sprintf("Logs of %s", functionName), "Close", content, r.window)
+	logsDialog.SetOnClosed(func() { cancel() })
+	logsDialog.Resize(fyne.NewSize(900, 680))
+	logsDialog.Show()
+	showButton.OnTapped()
+}
@@ -0,0 +1,52 @@
+package render
+
+import (
+	awsinterface "aws_utility/pkg/awsInterface"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/widget"
+	"sync"
+	"time"
+)
+
+// retryNoticeDuration is how long a retry stays shown after the last one.
+const retryNoticeDuration = 10 * time.Second
+
+// retryStatus shows the latest retry of an AWS call below the content.
+type retryStatus struct {
+	label *widget.Label
+	mu    sync.Mutex
+	last  time.Time
+}
+
+func newRetryStatus() *retryStatus {
+	label := widget.NewLabel("")
+	label.Importance = widget.WarningImportance
+	label.Wrapping = fyne.TextWrapWord
+	label.Hide()
+	status := &retryStatus{label: label}
+	awsinterface.OnRetry(status.show)
+	return status
+}
+
+func (s *retryStatus) show(retry awsinterface.Retry) {
+	s.mu.Lock()
+	now := time.Now()
+	s.last = now
+	s.mu.Unlock()
+	s.label.SetText(retry.String())
+	s.label.Show()
+
+	time.AfterFunc(retryNoticeDuration, func() {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.last.Equal(now) {
+			s.label.Hide()
+		}
+	})
+}
+
+// RetryStatus is the label showing retries of AWS calls, to be placed in
+// the window.
+func (r *FyneRenderer) RetryStatus() fyne.CanvasObject {
+	return r.retryStatus.label
+}
@@ -0,0 +1,270 @@
+package schema
+
+import (
+	"aws_utility/pkg/configdir"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	_ "github.com/santhosh-tekuri/jsonschema/v5/httploader"
+)
+
+const (
+	DirEnvVariable = "AWS_UTILITY_SCHEMA_DIR"
+	// TagKey names the function tag whose value is a path or URL of the
+	// function's payload schema. Paths are relative to the schema directory.
+	TagKey = "aws_utility:payload-schema"
+	// HostsEnvVariable lists, comma-separated, the hosts a schema tag may
+	// point to over https.
+	HostsEnvVariable = "AWS_UTILITY_SCHEMA_HOSTS"
+)
+
+type Schema struct {
+	Location string
+	compiled *jsonschema.Schema
+}
+
+type FieldError struct {
+	Path    string
+	Message string
+}
+
+func (e FieldError) String() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+type ValidationError struct {
+	Function string
+	Errors   []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	lines := make([]string, len(e.Errors))
+	for i, fieldError := range e.Errors {
+		lines[i] = "  " + fieldError.String()
+	}
+	return fmt.Sprintf("payload for '%s' does not match its schema:\n%s", e.Function, strings.Join(lines, "\n"))
+}
+
+func Dir() (string, error) {
+	return configdir.Path(DirEnvVariable, "schemas")
+}
+
+// Locate returns where the schema for functionName lives: the function's
+// schema tag if present, otherwise <schema dir>/<function>.json if that file
+// exists. An empty string means the function has no schema.
+func Locate(functionName string, tags map[string]string) (string, error) {
+	dir, err := Dir()
+	if err != nil {
+		return "", err
+	}
+	if location := tags[TagKey]; location != "" {
+		if !strings.Contains(location, "://") && !filepath.IsAbs(location) {
+			location = filepath.Join(dir, location)
+		}
+		return location, nil
+	}
+
+	path := filepath.Join(dir, functionName+".json")
+	if _, err := os.Stat(path); err != nil {
+		if os.IsNotExist(err) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read schema %s: %v", path, err)
+	}
+	return path, nil
+}
+
+func Load(location string) (*Schema, error) {
+	compiled, err := jsonschema.Compile(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema %s: %v", location, err)
+	}
+	return &Schema{Location: location, compiled: compiled}, nil
+}
+
+// ForFunction loads the schema associated with functionName, or returns nil
+// if there is none.
+func ForFunction(functionName string, tags map[string]string) (*Schema, error) {
+	location, err := Locate(functionName, tags)
+	if err != nil || location == "" {
+		return nil, err
+	}
+	if tags[TagKey] == "" {
+		return Load(location)
+	}
+	return loadTagged(location)
+}
+
+// loadTagged loads a schema named by a function's tag. Anyone allowed to tag
+// the function can set it, so the schema and every document it refers to
+// must be in the schema directory or on an https host in HostsEnvVariable.
+func loadTagged(location string) (*Schema, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	compiler := jsonschema.NewCompiler()
+	compiler.LoadURL = func(s string) (io.ReadCloser, error) {
+		if err := checkTagged(s, dir); err != nil {
+			return nil, err
+		}
+		return jsonschema.LoadURL(s)
+	}
+	compiled, err := compiler.Compile(location)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load schema %s from tag %s: %v", location, TagKey, err)
+	}
+	return &Schema{Location: location, compiled: compiled}, nil
+}
+
+func checkTagged(location, dir string) error {
+	u, err := url.Parse(location)
+	if err != nil {
+		return err
+	}
+	switch u.Scheme {
+	case "https":
+		for _, host := range strings.Split(os.Getenv(HostsEnvVariable), ",") {
+			if host = strings.TrimSpace(host); host != "" && strings.EqualFold(host, u.Hostname()) {
+				return nil
+			}
+		}
+		return fmt.Errorf("host %s is not listed in $%s", u.Hostname(), HostsEnvVariable)
+	case "file":
+		path := realPath(filepath.FromSlash(u.Path))
+		rel, err := filepath.Rel(realPath(dir), path)
+		if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return fmt.Errorf("%s is outside the schema directory %s", path, dir)
+		}
+		return nil
+	}
+	return fmt.Errorf("%s is neither an https URL nor a file in the schema directory", location)
+}
+
+// realPath resolves symbolic links so they cannot point out of the schema
+// directory, keeping the path as is when it does not exist.
+func realPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return filepath.Clean(path)
+}
+
+// Validate checks payloadJson against the schema and returns one FieldError
+// per failing leaf keyword, addressed by JSON pointer.
+func (s *Schema) Validate(payloadJson []byte) ([]FieldError, error) {
+	decoder := json.NewDecoder(bytes.NewReader(payloadJson))
+	decoder.UseNumber()
+	var doc interface{}
+	if err := decoder.Decode(&doc); err != nil {
+		return nil, fmt.Errorf("invalid JSON payload: %v", err)
+	}
+
+	err := s.compiled.Validate(doc)
+	if err == nil {
+		return nil, nil
+	}
+	validationErr, ok := err.(*jsonschema.ValidationError)
+	if !ok {
+		return nil, err
+	}
+
+	var fieldErrors []FieldError
+	collect(validationErr, &fieldErrors)
+	return fieldErrors, nil
+}
+
+// Check validates payloadJson for functionName and returns a
+// *ValidationError listing every problem, or nil if the payload is valid.
+func (s *Schema) Check(functionName string, payloadJson []byte) error {
+	fieldErrors, err := s.Validate(payloadJson)
+	if err != nil {
+		return err
+	}
+	if len(fieldErrors) > 0 {
+		return &ValidationError{Function: functionName, Errors: fieldErrors}
+	}
+	return nil
+}
+
+func collect(validationErr *jsonschema.ValidationError, fieldErrors *[]FieldError) {
+	if len(validationErr.Causes) == 0 {
+		path := validationErr.InstanceLocation
+		if path == "" {
+			path = "/"
+		}
+		*fieldErrors = append(*fieldErrors, FieldError{Path: path, Message: validationErr.Message})
+		return
+	}
+	for _, cause := range validationErr.Causes {
+		collect(cause, fieldErrors)
+	}
+}
+
+// Keys lists the property paths the schema declares, in dotted form, for
+// autocompletion in the payload editors.
+func (s *Schema) Keys() []string {
+	var keys []string
+	walkKeys(s.compiled, "", &keys, map[*jsonschema.Schema]bool{})
+	sort.Strings(keys)
+	return keys
+}
+
+// MissingKeys returns the top-level properties the schema declares that
+// payloadText does not set yet, required ones first.
+func (s *Schema) MissingKeys(payloadText string) []string {
+	var doc map[string]interface{}
+	_ = json.Unmarshal([]byte(payloadText), &doc)
+
+	root := resolve(s.compiled)
+	required := map[string]bool{}
+	var missing []string
+	for _, key := range root.Required {
+		required[key] = true
+		if _, ok := doc[key]; !ok {
+			missing = append(missing, key)
+		}
+	}
+
+	var optional []string
+	for key := range root.Properties {
+		if _, ok := doc[key]; !ok && !required[key] {
+			optional = append(optional, key)
+		}
+	}
+	sort.Strings(optional)
+	return append(missing, optional...)
+}
+
+func walkKeys(node *jsonschema.Schema, prefix string, keys *[]string, seen map[*jsonschema.Schema]bool) {
+	node = resolve(node)
+	if node == nil || seen[node] {
+		return
+	}
+	seen[node] = true
+	defer delete(seen, node)
+
+	for name, property := range node.Properties {
+		key := name
+		if prefix != "" {
+			key = prefix + "." + name
+		}
+		*keys = append(*keys, key)
+		walkKeys(property, key, keys, seen)
+	}
+}
+
+func resolve(node *jsonschema.Schema) *jsonschema.Schema {
+	for node != nil && node.Ref != nil && len(node.Properties) == 0 {
+		node = node.Ref
+	}
+	return node
+}
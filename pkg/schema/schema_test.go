@@ -0,0 +1,150 @@
+package schema
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+const deploySchema = `{
+	"type": "object",
+	"required": ["service", "replicas"],
+	"properties": {
+		"service": {"type": "string"},
+		"replicas": {"type": "integer", "minimum": 1},
+		"env": {"type": "object", "properties": {"name": {"type": "string"}}}
+	}
+}`
+
+// schemaDir points the schema directory at a temporary one holding files.
+func schemaDir(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(DirEnvVariable, dir)
+	return dir
+}
+
+func TestForFunction(t *testing.T) {
+	dir := schemaDir(t, map[string]string{
+		"deploy.json":       deploySchema,
+		"shared/named.json": deploySchema,
+	})
+
+	loaded, err := ForFunction("deploy", nil)
+	if err != nil || loaded == nil || loaded.Location != filepath.Join(dir, "deploy.json") {
+		t.Errorf("ForFunction(deploy) = %+v, %v, want the schema named after the function", loaded, err)
+	}
+
+	loaded, err = ForFunction("other", nil)
+	if err != nil || loaded != nil {
+		t.Errorf("ForFunction(other) = %+v, %v, want no schema", loaded, err)
+	}
+
+	tagged := filepath.Join(dir, "shared", "named.json")
+	loaded, err = ForFunction("other", map[string]string{TagKey: tagged})
+	if err != nil || loaded == nil || loaded.Location != tagged {
+		t.Errorf("ForFunction(other) with a tag = %+v, %v, want %s", loaded, err, tagged)
+	}
+}
+
+// TestForFunctionRestrictsTagLocations covers tags set by someone who may
+// not be trusted with files outside the schema directory or arbitrary URLs.
+func TestForFunctionRestrictsTagLocations(t *testing.T) {
+	outside := filepath.Join(t.TempDir(), "secret.json")
+	if err := os.WriteFile(outside, []byte(deploySchema), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	dir := schemaDir(t, map[string]string{
+		"shared/named.json": deploySchema,
+		"escape.json":       `{"$ref": "file://` + filepath.ToSlash(outside) + `"}`,
+	})
+	t.Setenv(HostsEnvVariable, "schemas.example.com")
+
+	loaded, err := ForFunction("deploy", map[string]string{TagKey: "shared/named.json"})
+	if err != nil || loaded == nil || loaded.Location != filepath.Join(dir, "shared", "named.json") {
+		t.Errorf("ForFunction() with a relative tag = %+v, %v, want it read from the schema directory", loaded, err)
+	}
+
+	for location, want := range map[string]string{
+		outside: "outside the schema directory",
+		filepath.Join("..", filepath.Base(filepath.Dir(outside)), "secret.json"): "outside the schema directory",
+		"escape.json":                            "outside the schema directory",
+		"https://evil.example.com/deploy.json":   "not listed in $" + HostsEnvVariable,
+		"http://schemas.example.com/deploy.json": "neither an https URL",
+	} {
+		if _, err := ForFunction("deploy", map[string]string{TagKey: location}); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("ForFunction() with tag %s error = %v, want %q", location, err, want)
+		}
+	}
+}
+
+func TestCheckReportsEveryField(t *testing.T) {
+	dir := schemaDir(t, map[string]string{"deploy.json": deploySchema})
+	loaded, err := Load(filepath.Join(dir, "deploy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := loaded.Check("deploy", []byte(`{"service": "api", "replicas": 2}`)); err != nil {
+		t.Errorf("Check() of a valid payload error = %v", err)
+	}
+
+	err = loaded.Check("deploy", []byte(`{"service": 1, "replicas": 1.5, "env": {"name": 1}}`))
+	validationErr, ok := err.(*ValidationError)
+	if !ok {
+		t.Fatalf("Check() error = %v, want a *ValidationError", err)
+	}
+	var paths []string
+	for _, fieldError := range validationErr.Errors {
+		paths = append(paths, fieldError.Path)
+	}
+	sort.Strings(paths)
+	if want := []string{"/env/name", "/replicas", "/service"}; !reflect.DeepEqual(paths, want) {
+		t.Errorf("Check() paths = %v, want %v", paths, want)
+	}
+	if message := err.Error(); !strings.HasPrefix(message, "payload for 'deploy' does not match its schema:\n  /") {
+		t.Errorf("Check() message = %q", message)
+	}
+
+	if _, err := loaded.Validate([]byte(`{`)); err == nil {
+		t.Error("Validate(invalid JSON) error = nil, want an error")
+	}
+}
+
+func TestCheckKeepsLargeIntegers(t *testing.T) {
+	dir := schemaDir(t, map[string]string{"deploy.json": deploySchema})
+	loaded, err := Load(filepath.Join(dir, "deploy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := loaded.Check("deploy", []byte(`{"service": "api", "replicas": 12345678901234567890}`)); err != nil {
+		t.Errorf("Check() error = %v, want a large integer to stay an integer", err)
+	}
+}
+
+func TestKeysForEditors(t *testing.T) {
+	dir := schemaDir(t, map[string]string{"deploy.json": deploySchema})
+	loaded, err := Load(filepath.Join(dir, "deploy.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if got, want := loaded.Keys(), []string{"env", "env.name", "replicas", "service"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Keys() = %v, want %v", got, want)
+	}
+	if got, want := loaded.MissingKeys(`{"service": "api"}`), []string{"replicas", "env"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("MissingKeys() = %v, want %v", got, want)
+	}
+}
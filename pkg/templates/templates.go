@@ -0,0 +1,335 @@
+package templates
+
+import (
+	"aws_utility/pkg/configdir"
+	"aws_utility/pkg/payload"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+const DirEnvVariable = "AWS_UTILITY_TEMPLATE_DIR"
+
+type Variable struct {
+	Name     string `yaml:"name" json:"name"`
+	Prompt   string `yaml:"prompt" json:"prompt"`
+	Default  string `yaml:"default" json:"default"`
+	Required bool   `yaml:"required" json:"required"`
+}
+
+type Template struct {
+	Name        string      `yaml:"name" json:"name"`
+	Description string      `yaml:"description" json:"description"`
+	Function    string      `yaml:"function" json:"function"`
+	Payload     interface{} `yaml:"payload" json:"payload"`
+	Variables   []Variable  `yaml:"variables" json:"variables"`
+	Path        string      `yaml:"-" json:"-"`
+}
+
+// ECSDeploy is the built-in template for the original cluster/service/tag
+// deploy payload.
+var ECSDeploy = Template{
+	Name:        "ecs-deploy",
+	Description: "Deploy an ECR image tag to an ECS service",
+	Payload: map[string]interface{}{
+		"cluster": "{{cluster}}",
+		"service": "{{service}}",
+		"ecr_tag": "{{ecr_tag}}",
+	},
+	Variables: []Variable{
+		{Name: "cluster", Prompt: "Cluster"},
+		{Name: "service", Prompt: "Service"},
+		{Name: "ecr_tag", Prompt: "Tag"},
+	},
+}
+
+var Builtin = []Template{ECSDeploy}
+
+var variablePattern = regexp.MustCompile(`{{\s*([A-Za-z0-9_.-]+)\s*}}`)
+
+// Dir returns the template directory: $AWS_UTILITY_TEMPLATE_DIR if set,
+// otherwise aws_utility/templates under the user config directory.
+func Dir() (string, error) {
+	return configdir.Path(DirEnvVariable, "templates")
+}
+
+// Load returns the built-in templates followed by every .yaml, .yml and
+// .json template in dir, sorted by name. A missing directory is not an error.
+func Load(dir string) ([]Template, error) {
+	templates := append([]Template{}, Builtin...)
+
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return templates, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read template directory: %v", err)
+	}
+
+	var loaded []Template
+	for _, entry := range entries {
+		if entry.IsDir() {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		switch strings.ToLower(filepath.Ext(entry.Name())) {
+		case ".yaml", ".yml", ".json":
+			template, err := LoadFile(path)
+			if err != nil {
+				return nil, err
+			}
+			loaded = append(loaded, template)
+		}
+	}
+	sort.Slice(loaded, func(i, j int) bool { return loaded[i].Name < loaded[j].Name })
+
+	for _, template := range loaded {
+		templates = replace(templates, template)
+	}
+	return templates, nil
+}
+
+func LoadDefault() ([]Template, error) {
+	dir, err := Dir()
+	if err != nil {
+		return nil, err
+	}
+	return Load(dir)
+}
+
+func LoadFile(path string) (Template, error) {
+	var template Template
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return template, fmt.Errorf("failed to read template %s: %v", path, err)
+	}
+
+	if strings.EqualFold(filepath.Ext(path), ".json") {
+		err = json.Unmarshal(raw, &template)
+	} else {
+		err = yaml.Unmarshal(raw, &template)
+	}
+	if err != nil {
+		return template, fmt.Errorf("failed to parse template %s: %v", path, err)
+	}
+
+	if template.Name == "" {
+		template.Name = strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	}
+	template.Path = path
+	return template, nil
+}
+
+func Find(templates []Template, name string) (*Template, error) {
+	for i := range templates {
+		if templates[i].Name == name {
+			return &templates[i], nil
+		}
+	}
+	return nil, fmt.Errorf("template %q not found", name)
+}
+
+// ForFunction returns the templates that either target functionName or do
+// not name a function at all.
+func ForFunction(templates []Template, functionName string) []Template {
+	var matching []Template
+	for _, template := range templates {
+		if template.Function == "" || template.Function == functionName {
+			matching = append(matching, template)
+		}
+	}
+	return matching
+}
+
+func (v Variable) Label() string {
+	if v.Prompt != "" {
+		return v.Prompt
+	}
+	return v.Name
+}
+
+// Resolve fills in defaults and checks that every variable the payload
+// references has a value.
+func (t Template) Resolve(vars map[string]string) (map[string]string, error) {
+	resolved := make(map[string]string, len(t.Variables))
+	for key, value := range vars {
+		resolved[key] = value
+	}
+
+	for _, variable := range t.Variables {
+		if value, ok := resolved[variable.Name]; ok && value != "" {
+			continue
+		}
+		if variable.Default != "" {
+			resolved[variable.Name] = variable.Default
+			continue
+		}
+		if variable.Required {
+			return nil, fmt.Errorf("template %q: missing value for variable %q", t.Name, variable.Name)
+		}
+		resolved[variable.Name] = ""
+	}
+
+	for _, name := range t.References() {
+		if _, ok := resolved[name]; !ok {
+			return nil, fmt.Errorf("template %q: undefined variable %q", t.Name, name)
+		}
+	}
+	return resolved, nil
+}
+
+// References lists the distinct variable names used in the payload body.
+func (t Template) References() []string {
+	seen := map[string]bool{}
+	var names []string
+	walkStrings(t.Payload, func(s string) {
+		for _, match := range variablePattern.FindAllStringSubmatch(s, -1) {
+			if !seen[match[1]] {
+				seen[match[1]] = true
+				names = append(names, match[1])
+			}
+		}
+	})
+	return names
+}
+
+// Document renders the payload body with vars substituted. A string payload
+// is treated as JSON text and parsed after substitution, with values
+// escaped inside string literals so they cannot break out of them.
+func (t Template) Document(vars map[string]string) (interface{}, error) {
+	resolved, err := t.Resolve(vars)
+	if err != nil {
+		return nil, err
+	}
+
+	if raw, ok := t.Payload.(string); ok {
+		doc, err := payload.Parse([]byte(substituteJSON(raw, resolved)))
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %v", t.Name, err)
+		}
+		return doc, nil
+	}
+	return render(t.Payload, resolved), nil
+}
+
+func (t Template) Render(vars map[string]string) ([]byte, error) {
+	doc, err := t.Document(vars)
+	if err != nil {
+		return nil, err
+	}
+	payloadJson, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal payload: %v", err)
+	}
+	return payloadJson, nil
+}
+
+func render(node interface{}, vars map[string]string) interface{} {
+	switch value := node.(type) {
+	case string:
+		return substitute(value, vars)
+	case map[string]interface{}:
+		rendered := make(map[string]interface{}, len(value))
+		for key, child := range value {
+			rendered[substitute(key, vars)] = render(child, vars)
+		}
+		return rendered
+	case []interface{}:
+		rendered := make([]interface{}, len(value))
+		for i, child := range value {
+			rendered[i] = render(child, vars)
+		}
+		return rendered
+	default:
+		return value
+	}
+}
+
+func substitute(s string, vars map[string]string) string {
+	return variablePattern.ReplaceAllStringFunc(s, func(match string) string {
+		name := variablePattern.FindStringSubmatch(match)[1]
+		return vars[name]
+	})
+}
+
+// substituteJSON replaces the variables in JSON text. Inside a string
+// literal a value is escaped as JSON; elsewhere it is inserted as is when it
+// is a JSON value, such as a number, and as a quoted string otherwise.
+func substituteJSON(raw string, vars map[string]string) string {
+	var b strings.Builder
+	inString, escaped := false, false
+	last := 0
+	for _, match := range variablePattern.FindAllStringSubmatchIndex(raw, -1) {
+		for _, c := range []byte(raw[last:match[0]]) {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\' && inString:
+				escaped = true
+			case c == '"':
+				inString = !inString
+			}
+		}
+		b.WriteString(raw[last:match[0]])
+
+		value := vars[raw[match[2]:match[3]]]
+		quoted, _ := json.Marshal(value)
+		switch {
+		case inString:
+			b.Write(quoted[1 : len(quoted)-1])
+		case json.Valid([]byte(value)):
+			b.WriteString(value)
+		default:
+			b.Write(quoted)
+		}
+		last = match[1]
+	}
+	b.WriteString(raw[last:])
+	return b.String()
+}
+
+func walkStrings(node interface{}, visit func(string)) {
+	switch value := node.(type) {
+	case string:
+		visit(value)
+	case map[string]interface{}:
+		for key, child := range value {
+			visit(key)
+			walkStrings(child, visit)
+		}
+	case []interface{}:
+		for _, child := range value {
+			walkStrings(child, visit)
+		}
+	}
+}
+
+func replace(templates []Template, template Template) []Template {
+	for i := range templates {
+		if templates[i].Name == template.Name {
+			templates[i] = template
+			return templates
+		}
+	}
+	return append(templates, template)
+}
+
+// ParseVars turns repeated name=value flags into a map.
+func ParseVars(assignments []string) (map[string]string, error) {
+	vars := make(map[string]string, len(assignments))
+	for _, assignment := range assignments {
+		name, value, ok := strings.Cut(assignment, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid variable %q, expected name=value", assignment)
+		}
+		vars[name] = value
+	}
+	return vars, nil
+}
@@ -0,0 +1,144 @@
+package templates
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func writeTemplates(t *testing.T, files map[string]string) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	t.Setenv(DirEnvVariable, dir)
+	return dir
+}
+
+func TestLoadDefault(t *testing.T) {
+	writeTemplates(t, map[string]string{
+		"b.yaml":    "description: from yaml\nfunction: fn-b\npayload:\n  a: 1\n",
+		"a.json":    `{"name": "named", "payload": {"x": "{{x}}"}}`,
+		"ecs.yml":   "name: ecs-deploy\ndescription: overridden\npayload: {}\n",
+		"notes.txt": "ignored",
+	})
+
+	loaded, err := LoadDefault()
+	if err != nil {
+		t.Fatalf("LoadDefault() error = %v", err)
+	}
+	var names []string
+	for _, template := range loaded {
+		names = append(names, template.Name)
+	}
+	if got, want := strings.Join(names, ","), "ecs-deploy,b,named"; got != want {
+		t.Errorf("LoadDefault() names = %s, want %s", got, want)
+	}
+	if loaded[0].Description != "overridden" {
+		t.Errorf("a template file did not replace the built-in template: %+v", loaded[0])
+	}
+	if got := ForFunction(loaded, "fn-a"); len(got) != 2 {
+		t.Errorf("ForFunction(fn-a) = %d templates, want the 2 without a function", len(got))
+	}
+	if _, err := Find(loaded, "missing"); err == nil {
+		t.Error("Find(missing) error = nil, want an error")
+	}
+}
+
+func TestLoadDefaultWithoutDirectory(t *testing.T) {
+	t.Setenv(DirEnvVariable, filepath.Join(t.TempDir(), "missing"))
+	loaded, err := LoadDefault()
+	if err != nil || len(loaded) != len(Builtin) {
+		t.Errorf("LoadDefault() = %d templates, %v, want only the built-in ones", len(loaded), err)
+	}
+}
+
+func TestLoadDefaultNamesBrokenFile(t *testing.T) {
+	writeTemplates(t, map[string]string{"broken.yaml": "payload: [\n"})
+	if _, err := LoadDefault(); err == nil || !strings.Contains(err.Error(), "broken.yaml") {
+		t.Errorf("LoadDefault() error = %v, want a parse error naming the file", err)
+	}
+}
+
+func TestRenderWithVars(t *testing.T) {
+	vars, err := ParseVars([]string{"cluster=prod", "service=api", "ecr_tag=1.2.3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := ECSDeploy.Render(vars)
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := `{"cluster":"prod","ecr_tag":"1.2.3","service":"api"}`; string(got) != want {
+		t.Errorf("Render() = %s, want %s", got, want)
+	}
+
+	template := Template{
+		Payload:   map[string]interface{}{"{{key}}": []interface{}{"v{{ version }}", 3.0}},
+		Variables: []Variable{{Name: "key"}, {Name: "version", Default: "2"}},
+	}
+	got, err = template.Render(map[string]string{"key": "release"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := `{"release":["v2",3]}`; string(got) != want {
+		t.Errorf("Render() with a default = %s, want %s", got, want)
+	}
+}
+
+func TestRenderJSONText(t *testing.T) {
+	template := Template{Payload: `{"count": {{count}}, "tags": {{tags}}, "name": "{{name}}"}`}
+	got, err := template.Render(map[string]string{"count": "3", "tags": `["a"]`, "name": "plain"})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	if want := `{"count":3,"name":"plain","tags":["a"]}`; string(got) != want {
+		t.Errorf("Render() = %s, want %s", got, want)
+	}
+}
+
+func TestRenderJSONTextEscapesValues(t *testing.T) {
+	template := Template{Payload: `{"note": "say \"{{greeting}}\"", "path": "{{path}}", "owner": {{owner}}}`}
+	got, err := template.Render(map[string]string{
+		"greeting": `hi", "admin": true, "x": "`,
+		"path":     `C:\temp`,
+		"owner":    "ops team",
+	})
+	if err != nil {
+		t.Fatalf("Render() error = %v", err)
+	}
+	want := `{"note":"say \"hi\", \"admin\": true, \"x\": \"\"","owner":"ops team","path":"C:\\temp"}`
+	if string(got) != want {
+		t.Errorf("Render() = %s, want %s", got, want)
+	}
+}
+
+func TestRenderMissingVariables(t *testing.T) {
+	required := Template{Name: "t", Payload: "{{a}}", Variables: []Variable{{Name: "a", Required: true}}}
+	if _, err := required.Render(nil); err == nil || !strings.Contains(err.Error(), `missing value for variable "a"`) {
+		t.Errorf("Render() error = %v, want a missing value", err)
+	}
+	undefined := Template{Name: "t", Payload: map[string]interface{}{"a": "{{b}}"}}
+	if _, err := undefined.Render(nil); err == nil || !strings.Contains(err.Error(), `undefined variable "b"`) {
+		t.Errorf("Render() error = %v, want an undefined variable", err)
+	}
+}
+
+func TestParseVars(t *testing.T) {
+	vars, err := ParseVars([]string{"a=1", "b=x=y", "c="})
+	if err != nil {
+		t.Fatalf("ParseVars() error = %v", err)
+	}
+	if vars["a"] != "1" || vars["b"] != "x=y" || vars["c"] != "" {
+		t.Errorf("ParseVars() = %v", vars)
+	}
+	for _, assignment := range []string{"novalue", "=1"} {
+		if _, err := ParseVars([]string{assignment}); err == nil {
+			t.Errorf("ParseVars(%q) error = nil, want an error", assignment)
+		}
+	}
+}